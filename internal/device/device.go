@@ -6,55 +6,87 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"golang.org/x/sys/unix"
 )
 
-// ErrPartitionSize indicates more than one int was passed.
-var ErrPartitionSize = errors.New("partion size may only contain one item")
+// ErrNotBlockDevice indicates a path does not reside on a block device, e.g.
+// it resides on a tmpfs or overlay filesystem.
+var ErrNotBlockDevice = errors.New("not a block device")
 
-// ReadDeviceMinors retrieves the device minors of the specified major.
-// Specify a paritonSize if partion minor numbers should be returned.
-func ReadDeviceMinors(major uint32, partitionSize ...int) ([]uint32, error) {
-	if len(partitionSize) > 1 {
-		return nil, ErrPartitionSize
+// Device is a device identified by its major and minor numbers.
+type Device struct {
+	Major uint32
+	Minor uint32
+}
+
+// String formats the Device as "major:minor", e.g. "259:0".
+func (d Device) String() string {
+	return fmt.Sprintf("%d:%d", d.Major, d.Minor)
+}
+
+// BlockDevice retrieves the disk backing the filesystem path resides on. If
+// the filesystem resides on a partition, the disk containing the partition is
+// retrieved. If path does not reside on a block device, ErrNotBlockDevice is
+// returned.
+func BlockDevice(path string) (Device, error) {
+	var stats unix.Stat_t
+	if err := unix.Stat(path, &stats); err != nil {
+		return Device{}, fmt.Errorf("stat %s: %w", path, err)
 	}
 
-	var minors []uint32
-	if err := filepath.WalkDir(devices, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-
-		if d.Type() != fs.ModeDevice {
-			return nil
-		}
-
-		var stats unix.Stat_t
-		if err := unix.Stat(path, &stats); err != nil {
-			return nil
-		}
-
-		if unix.Major(stats.Rdev) != major {
-			return nil
-		}
-
-		minor := unix.Minor(stats.Rdev)
-		if len(partitionSize) == 1 && minor%uint32(partitionSize[0]) != 0 {
-			return nil
-		}
-
-		minors = append(minors, minor)
-		return nil
-	}); err != nil {
-		return nil, fmt.Errorf("read disk device minors: %w", err)
+	dev := Device{Major: unix.Major(stats.Dev), Minor: unix.Minor(stats.Dev)}
+	return resolveDisk(sysBlock, dev)
+}
+
+// resolveDisk retrieves the disk of dev using the sysfs block device links
+// within root. If dev is a partition, its parent disk is returned, otherwise
+// dev is returned.
+func resolveDisk(root string, dev Device) (Device, error) {
+	// e.g. /sys/dev/block/259:1 -> ../../devices/.../nvme0n1/nvme0n1p1
+	path, err := filepath.EvalSymlinks(filepath.Join(root, dev.String()))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Device{}, fmt.Errorf("%w; device: %s", ErrNotBlockDevice, dev)
+	}
+	if err != nil {
+		return Device{}, fmt.Errorf("resolve device %s: %w", dev, err)
 	}
 
-	return minors, nil
+	_, err = os.Stat(filepath.Join(path, partition))
+	if errors.Is(err, fs.ErrNotExist) {
+		return dev, nil
+	}
+	if err != nil {
+		return Device{}, fmt.Errorf("stat device %s partition: %w", dev, err)
+	}
+
+	// Partitions are nested within the directory of their disk.
+	return readDevice(filepath.Join(filepath.Dir(path), devFile))
+}
+
+// readDevice reads the "major:minor" formatted sysfs dev file.
+func readDevice(file string) (Device, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return Device{}, fmt.Errorf("read %s: %w", file, err)
+	}
+
+	var dev Device
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(b)), "%d:%d", &dev.Major, &dev.Minor); err != nil {
+		return Device{}, fmt.Errorf("parse %s: %w", file, err)
+	}
+	return dev, nil
 }
 
 const (
-	// devices is the dev filesystem.
-	devices = "/dev"
+	// sysBlock is the sysfs directory of links to block devices, named by
+	// their "major:minor" numbers.
+	sysBlock = "/sys/dev/block"
+	// partition is the sysfs file present within partition directories.
+	partition = "partition"
+	// devFile is the sysfs file containing a device's "major:minor" numbers.
+	devFile = "dev"
 )
@@ -0,0 +1,80 @@
+package device
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveDisk(t *testing.T) {
+	// Fake sysfs layout of an NVMe disk with a partition, and a virtio disk
+	// without partitions.
+	sysfs := t.TempDir()
+	disks := map[string]string{
+		"devices/pci0000:00/nvme/nvme0/nvme0n1":           "259:0",
+		"devices/pci0000:00/nvme/nvme0/nvme0n1/nvme0n1p1": "259:1",
+		"devices/pci0000:00/virtio1/block/vda":            "252:0",
+	}
+	for dir, dev := range disks {
+		writeFile(t, filepath.Join(sysfs, dir, devFile), dev+"\n")
+	}
+	writeFile(t, filepath.Join(sysfs, "devices/pci0000:00/nvme/nvme0/nvme0n1/nvme0n1p1", partition), "1\n")
+
+	root := filepath.Join(sysfs, "dev/block")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for dir, dev := range disks {
+		if err := os.Symlink(filepath.Join("../..", dir), filepath.Join(root, dev)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type expected struct {
+		dev Device
+		err error
+	}
+	tests := map[string]struct {
+		dev Device
+		exp expected
+	}{
+		"partition": {
+			dev: Device{Major: 259, Minor: 1},
+			exp: expected{dev: Device{Major: 259, Minor: 0}},
+		},
+		"disk": {
+			dev: Device{Major: 259, Minor: 0},
+			exp: expected{dev: Device{Major: 259, Minor: 0}},
+		},
+		"disk w/o partitions": {
+			dev: Device{Major: 252, Minor: 0},
+			exp: expected{dev: Device{Major: 252, Minor: 0}},
+		},
+		"not a block device": {
+			dev: Device{Major: 0, Minor: 50},
+			exp: expected{err: ErrNotBlockDevice},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dev, err := resolveDisk(root, test.dev)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if dev != test.exp.dev {
+				t.Fatalf("unexpected device; actual: %v, expected: %v", dev, test.exp.dev)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, file, content string) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
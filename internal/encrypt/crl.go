@@ -0,0 +1,151 @@
+package encrypt
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrCertRevoked indicates a peer certificate has been revoked by its CA.
+var ErrCertRevoked = errors.New("certificate revoked")
+
+// crlPEMType is the type of PEM blocks holding a certificate revocation list.
+const crlPEMType = "X509 CRL"
+
+// LoadCRL creates a CRL instance from the certificate revocation list at path,
+// either PEM or DER encoded.
+func LoadCRL(path string) (*CRL, error) {
+	crl := &CRL{path: path}
+	if err := crl.Reload(); err != nil {
+		return nil, err
+	}
+	return crl, nil
+}
+
+// CRL is a certificate revocation list used to reject revoked peer
+// certificates, see WithCRL. The CRL may be reloaded while in use, so
+// certificates may be revoked without restarting the server. The list is
+// reloaded automatically once its file is modified.
+type CRL struct {
+	path string
+
+	mutex sync.RWMutex
+	list  *x509.RevocationList
+	// modTime is the modification time of the list's file when it was last
+	// loaded.
+	modTime time.Time
+}
+
+// Reload reads the certificate revocation list from the CRL's path again. If
+// the list cannot be read, the previously loaded list remains in use.
+func (c *CRL) Reload() error {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return fmt.Errorf("stat CRL; path: %s, error: %w", c.path, err)
+	}
+	return c.load(info.ModTime())
+}
+
+// load reads the certificate revocation list, last modified at modTime. The
+// modification time is recorded even if the list fails to load, so a
+// malformed list is not read again until it is modified.
+func (c *CRL) load(modTime time.Time) error {
+	c.mutex.Lock()
+	c.modTime = modTime
+	c.mutex.Unlock()
+
+	b, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("read CRL; path: %s, error: %w", c.path, err)
+	}
+	if block, _ := pem.Decode(b); block != nil && block.Type == crlPEMType {
+		b = block.Bytes
+	}
+	list, err := x509.ParseRevocationList(b)
+	if err != nil {
+		return fmt.Errorf("parse CRL; path: %s, error: %w", c.path, err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.list = list
+	return nil
+}
+
+// refresh reloads the certificate revocation list if its file has been
+// modified since it was last loaded. If the list cannot be reloaded, the
+// previously loaded list remains in use.
+func (c *CRL) refresh() {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		logger.Errorf("stat CRL, using previous list; path: %s, error: %v", c.path, err)
+		return
+	}
+	c.mutex.RLock()
+	modified := !info.ModTime().Equal(c.modTime)
+	c.mutex.RUnlock()
+	if !modified {
+		return
+	}
+
+	if err := c.load(info.ModTime()); err != nil {
+		logger.Errorf("reload CRL, using previous list; error: %v", err)
+		return
+	}
+	logger.Infof("CRL reloaded; path: %s", c.path)
+}
+
+// Revoked checks if cert, issued by issuer, has been revoked. Only a list
+// signed by issuer may revoke cert, as certificate serial numbers are unique
+// per issuer. issuer must be permitted to sign CRLs.
+func (c *CRL) Revoked(cert, issuer *x509.Certificate) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if err := c.list.CheckSignatureFrom(issuer); err != nil {
+		return false
+	}
+	for _, revoked := range c.list.RevokedCertificateEntries {
+		if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired checks if the list is past its next update time, and should be
+// replaced by an updated list.
+func (c *CRL) Expired() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return time.Now().After(c.list.NextUpdate)
+}
+
+// verifyPeerCertificate rejects peer certificates that have been revoked,
+// reloading the list first if it has been modified. It is called once the
+// peer's certificate chains have been verified, see
+// tls.Config.VerifyPeerCertificate.
+func (c *CRL) verifyPeerCertificate(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+	c.refresh()
+	for _, chain := range verifiedChains {
+		if len(chain) < 2 {
+			continue
+		}
+		if leaf := chain[0]; c.Revoked(leaf, chain[1]) {
+			return fmt.Errorf("%w; subject: %s, serial: %s", ErrCertRevoked, leaf.Subject, leaf.SerialNumber)
+		}
+	}
+	return nil
+}
+
+// WithCRL configures a tls.Config to reject peer certificates revoked by crl.
+// The handshake with a revoked peer fails with a TLS alert.
+func WithCRL(crl *CRL) TLSOption {
+	return func(c *tls.Config) { c.VerifyPeerCertificate = crl.verifyPeerCertificate }
+}
@@ -0,0 +1,162 @@
+package encrypt
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCRL(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir, "ca")
+	other := newTestCA(t, dir, "other_ca")
+
+	serverCert, serverKey := ca.leaf(t, 2, "jobworker", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.leaf(t, 3, "alpha_user", x509.ExtKeyUsageClientAuth)
+	clientConfig, err := NewClientTLSConfig(clientCert, clientKey, ca.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type expected struct {
+		err error
+	}
+	tests := map[string]struct {
+		issuer  testCA
+		serials []int64
+		// der indicates the CRL is written DER rather than PEM encoded.
+		der bool
+		exp expected
+	}{
+		"not revoked": {
+			issuer:  ca,
+			serials: []int64{4},
+			exp:     expected{err: nil},
+		},
+		"revoked": {
+			issuer:  ca,
+			serials: []int64{4, 3},
+			exp:     expected{err: ErrCertRevoked},
+		},
+		"revoked der": {
+			issuer:  ca,
+			serials: []int64{3},
+			der:     true,
+			exp:     expected{err: ErrCertRevoked},
+		},
+		"revoked by other CA": {
+			issuer:  other,
+			serials: []int64{3},
+			exp:     expected{err: nil},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ca.crl")
+			if test.der {
+				if err := os.WriteFile(path, createTestCRL(t, test.issuer, test.serials...), 0600); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				writeTestCRL(t, path, test.issuer, test.serials...)
+			}
+
+			crl, err := LoadCRL(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			serverConfig, err := NewServermTLSConfig(serverCert, serverKey, ca.cert, WithCRL(crl))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = serverHandshake(t, serverConfig, clientConfig)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+		})
+	}
+}
+
+func TestCRLReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir, "ca")
+
+	serverCert, serverKey := ca.leaf(t, 2, "jobworker", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.leaf(t, 3, "alpha_user", x509.ExtKeyUsageClientAuth)
+	clientConfig, err := NewClientTLSConfig(clientCert, clientKey, ca.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "ca.crl")
+	writeTestCRL(t, path, ca)
+	crl, err := LoadCRL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig, err := NewServermTLSConfig(serverCert, serverKey, ca.cert, WithCRL(crl))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := serverHandshake(t, serverConfig, clientConfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The client certificate is revoked once the CRL is modified.
+	writeTestCRL(t, path, ca, 3)
+	touch(t, time.Minute, path)
+	if err := serverHandshake(t, serverConfig, clientConfig); !errors.Is(err, ErrCertRevoked) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, ErrCertRevoked)
+	}
+
+	// A malformed CRL is not loaded, and the previous CRL remains in use.
+	writePEM(t, path, crlPEMType, []byte("malformed"))
+	touch(t, 2*time.Minute, path)
+	if err := serverHandshake(t, serverConfig, clientConfig); !errors.Is(err, ErrCertRevoked) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, ErrCertRevoked)
+	}
+	if err := crl.Reload(); err == nil {
+		t.Fatal("expected reload error")
+	}
+	if err := serverHandshake(t, serverConfig, clientConfig); !errors.Is(err, ErrCertRevoked) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, ErrCertRevoked)
+	}
+}
+
+// writeTestCRL writes a PEM encoded CRL signed by ca to path, revoking the
+// certificates identified by serials.
+func writeTestCRL(t *testing.T, path string, ca testCA, serials ...int64) {
+	writePEM(t, path, crlPEMType, createTestCRL(t, ca, serials...))
+}
+
+// createTestCRL creates a DER encoded CRL signed by ca, revoking the
+// certificates identified by serials.
+func createTestCRL(t *testing.T, ca testCA, serials ...int64) []byte {
+	var revoked []x509.RevocationListEntry
+	for _, serial := range serials {
+		revoked = append(revoked, x509.RevocationListEntry{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: time.Now(),
+		})
+	}
+
+	template := &x509.RevocationList{
+		RevokedCertificateEntries: revoked,
+		Number:                    big.NewInt(time.Now().UnixNano()),
+		ThisUpdate:                time.Now(),
+		NextUpdate:                time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, ca.ca, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
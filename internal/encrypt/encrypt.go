@@ -4,57 +4,276 @@ package encrypt
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 var errInvalidCaCert = errors.New("invalid ca cert")
 
-// NewServerTLSConfig creates a tls.Config suited for a server using mTLS.
-func NewServermTLSConfig(serverCert, serverKey, caCert string) (*tls.Config, error) {
-	cert, err := tls.LoadX509KeyPair(serverCert, serverKey)
+// ErrUnsupportedTLSVersion indicates a TLS version is not supported.
+var ErrUnsupportedTLSVersion = errors.New("unsupported tls version")
+
+// ErrUnsupportedCipherSuite indicates a cipher suite is not supported.
+var ErrUnsupportedCipherSuite = errors.New("unsupported cipher suite")
+
+// ErrUnsupportedCurve indicates an elliptic curve is not supported.
+var ErrUnsupportedCurve = errors.New("unsupported curve")
+
+// TLSOption is a function that mutates tls.Config instances. Typically used
+// with NewServermTLSConfig and NewClientTLSConfig.
+type TLSOption func(*tls.Config)
+
+// WithMinVersion configures a tls.Config to accept TLS versions greater than
+// or equal to version. By default, the minimum version is TLS 1.3. Lowering
+// the minimum version should only be done for compatibility with legacy
+// clients; versions prior to TLS 1.2 are rejected.
+func WithMinVersion(version uint16) TLSOption {
+	return func(c *tls.Config) { c.MinVersion = version }
+}
+
+// WithCipherSuites configures a tls.Config to only negotiate cipher suites
+// within suites. Cipher suites are only configurable for TLS 1.2 connections;
+// TLS 1.3 cipher suites are always secure and not configurable.
+func WithCipherSuites(suites []uint16) TLSOption {
+	return func(c *tls.Config) { c.CipherSuites = suites }
+}
+
+// WithCurvePreferences configures a tls.Config to only use the elliptic curves
+// within curves for key exchange, in order of preference.
+func WithCurvePreferences(curves []tls.CurveID) TLSOption {
+	return func(c *tls.Config) { c.CurvePreferences = curves }
+}
+
+// WithServerName configures a client's tls.Config to verify the server's
+// certificate is issued for name. By default, the server name is "localhost".
+func WithServerName(name string) TLSOption {
+	return func(c *tls.Config) { c.ServerName = name }
+}
+
+// ParseTLSVersion parses a TLS version in the form "1.2" or "1.3". Versions
+// prior to TLS 1.2 are not supported.
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("%w; version: %s", ErrUnsupportedTLSVersion, version)
+	}
+}
+
+// ParseCipherSuites parses cipher suite names, e.g.
+// "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256". Only cipher suites without known
+// security issues are supported, see tls.CipherSuites.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	supported := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		supported[suite.Name] = suite.ID
+	}
+
+	suites := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := supported[name]
+		if !ok {
+			return nil, fmt.Errorf("%w; cipher suite: %s", ErrUnsupportedCipherSuite, name)
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
+
+// curves are the supported elliptic curves, keyed by name.
+var curves = map[string]tls.CurveID{
+	"X25519": tls.X25519,
+	"P256":   tls.CurveP256,
+	"P384":   tls.CurveP384,
+	"P521":   tls.CurveP521,
+}
+
+// ParseCurves parses elliptic curve names, one of "X25519", "P256", "P384",
+// or "P521".
+func ParseCurves(names []string) ([]tls.CurveID, error) {
+	ids := make([]tls.CurveID, 0, len(names))
+	for _, name := range names {
+		id, ok := curves[name]
+		if !ok {
+			return nil, fmt.Errorf("%w; curve: %s", ErrUnsupportedCurve, name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// validate checks config does not accept TLS versions prior to TLS 1.2.
+func validate(config *tls.Config) error {
+	if config.MinVersion < tls.VersionTLS12 {
+		return fmt.Errorf("%w; minimum version: %#04x", ErrUnsupportedTLSVersion, config.MinVersion)
+	}
+	return nil
+}
+
+// NewServermTLSConfig creates a tls.Config suited for a server using mTLS,
+// trusting the CA certs at caCert. See NewServermTLSConfigWithCAs.
+func NewServermTLSConfig(serverCert, serverKey, caCert string, options ...TLSOption) (*tls.Config, error) {
+	return NewServermTLSConfigWithCAs(serverCert, serverKey, []string{caCert}, options...)
+}
+
+// NewServermTLSConfigWithCAs creates a tls.Config suited for a server using
+// mTLS. Client certificates signed by any of the CA certs in caCerts, each a
+// file or a directory of files, are accepted. The server's certificate is
+// reloaded once serverCert or serverKey change, see CertReloader. TLSOptions
+// may be specified to configure the tls.Config.
+func NewServermTLSConfigWithCAs(serverCert, serverKey string, caCerts []string, options ...TLSOption) (*tls.Config, error) {
+	reloader, err := NewCertReloader(serverCert, serverKey)
 	if err != nil {
-		return nil, fmt.Errorf("load server cert & key; error: %w", err)
+		return nil, err
 	}
 
-	ca := x509.NewCertPool()
-	b, err := ioutil.ReadFile(caCert)
+	ca, err := newCertPool(caCerts...)
 	if err != nil {
-		return nil, fmt.Errorf("read CA cert; error: %w", err)
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS13,
+		ClientAuth:     tls.RequireAndVerifyClientCert,
+		GetCertificate: reloader.GetCertificate,
+		ClientCAs:      ca,
+	}
+	for _, option := range options {
+		option(config)
 	}
-	if ok := ca.AppendCertsFromPEM(b); !ok {
-		return nil, errInvalidCaCert
+	if err := validate(config); err != nil {
+		return nil, err
 	}
 
-	return &tls.Config{
-		MinVersion:   tls.VersionTLS13,
-		ClientAuth:   tls.RequireAndVerifyClientCert,
-		Certificates: []tls.Certificate{cert},
-		ClientCAs:    ca,
-	}, nil
+	return config, nil
+}
+
+// NewClientTLSConfig creates a tls.Config suited for a client using mTLS,
+// trusting the CA certs at caCert. See NewClientTLSConfigWithCAs.
+func NewClientTLSConfig(clientCert, clientKey, caCert string, options ...TLSOption) (*tls.Config, error) {
+	return NewClientTLSConfigWithCAs(clientCert, clientKey, []string{caCert}, options...)
 }
 
-// NewClientTLSConfig creates a tls.Config suited for a client using mTLS.
-func NewClientTLSConfig(clientCert, clientKey, caCert string) (*tls.Config, error) {
+// NewClientTLSConfigWithCAs creates a tls.Config suited for a client using
+// mTLS. Server certificates signed by any of the CA certs in caCerts, each a
+// file or a directory of files, are accepted. The server's certificate must
+// be issued for "localhost", unless configured by WithServerName. TLSOptions
+// may be specified to configure the tls.Config.
+func NewClientTLSConfigWithCAs(clientCert, clientKey string, caCerts []string, options ...TLSOption) (*tls.Config, error) {
 	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
 	if err != nil {
 		return nil, fmt.Errorf("load client cert & key; error: %w", err)
 	}
 
-	ca := x509.NewCertPool()
-	b, err := ioutil.ReadFile(caCert)
+	ca, err := newCertPool(caCerts...)
 	if err != nil {
-		return nil, fmt.Errorf("read CA cert; error: %w", err)
-	}
-	if ok := ca.AppendCertsFromPEM(b); !ok {
-		return nil, errInvalidCaCert
+		return nil, err
 	}
 
-	return &tls.Config{
+	config := &tls.Config{
 		MinVersion:   tls.VersionTLS13,
 		ServerName:   "localhost",
 		Certificates: []tls.Certificate{cert},
 		RootCAs:      ca,
-	}, nil
+	}
+	for _, option := range options {
+		option(config)
+	}
+	if err := validate(config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// newCertPool creates a x509.CertPool holding the PEM encoded certificates of
+// each of the caCerts, each either a file or a directory of files. Files
+// holding no valid certificates are skipped, so a directory may hold other
+// files; an error is returned only if no certificates were loaded.
+func newCertPool(caCerts ...string) (*x509.CertPool, error) {
+	files, err := caFiles(caCerts)
+	if err != nil {
+		return nil, err
+	}
+
+	ca := x509.NewCertPool()
+	var appended int
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read CA cert; path: %s, error: %w", file, err)
+		}
+		n := appendCerts(ca, b)
+		if n == 0 {
+			logger.Warnf("skipping CA cert file holding no valid certs; path: %s", file)
+			continue
+		}
+		appended += n
+	}
+	if appended == 0 {
+		return nil, fmt.Errorf("%w; paths: %s", errInvalidCaCert, strings.Join(caCerts, ", "))
+	}
+	logger.Infof("loaded CA certs; count: %d, files: %d", appended, len(files))
+
+	return ca, nil
+}
+
+// caFiles expands paths into the files holding CA certs. Each path is either
+// a file, or a directory whose regular files, excluding those within
+// subdirectories, are included in name order.
+func caFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("stat CA cert; path: %s, error: %w", path, err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("read CA cert dir; path: %s, error: %w", path, err)
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// appendCerts appends the PEM encoded certificates in b to pool, and returns
+// the number of certificates appended. Blocks that are not valid certificates
+// are skipped.
+func appendCerts(pool *x509.CertPool, b []byte) int {
+	var n int
+	for len(b) > 0 {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" || len(block.Headers) != 0 {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		pool.AddCert(cert)
+		n++
+	}
+	return n
 }
@@ -0,0 +1,605 @@
+package encrypt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMinVersion(t *testing.T) {
+	certs := newTestCerts(t)
+
+	type expected struct {
+		connect bool
+	}
+	tests := map[string]struct {
+		serverMin uint16
+		clientMax uint16
+		exp       expected
+	}{
+		"default server w/ TLS 1.3 client": {
+			clientMax: tls.VersionTLS13,
+			exp:       expected{connect: true},
+		},
+		"default server w/ TLS 1.2 client": {
+			clientMax: tls.VersionTLS12,
+			exp:       expected{connect: false},
+		},
+		"TLS 1.2 server w/ TLS 1.2 client": {
+			serverMin: tls.VersionTLS12,
+			clientMax: tls.VersionTLS12,
+			exp:       expected{connect: true},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var serverOptions []TLSOption
+			if test.serverMin != 0 {
+				serverOptions = append(serverOptions, WithMinVersion(test.serverMin))
+			}
+			serverConfig, err := NewServermTLSConfig(certs.serverCert, certs.serverKey, certs.caCert, serverOptions...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clientConfig, err := NewClientTLSConfig(
+				certs.clientCert,
+				certs.clientKey,
+				certs.caCert,
+				WithMinVersion(tls.VersionTLS12),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clientConfig.MaxVersion = test.clientMax
+
+			lis, err := tls.Listen("tcp", "localhost:0", serverConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer lis.Close()
+
+			go func() {
+				conn, err := lis.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+			}()
+
+			conn, err := tls.DialWithDialer(
+				&net.Dialer{Timeout: 5 * time.Second},
+				"tcp",
+				lis.Addr().String(),
+				clientConfig,
+			)
+			if connect := err == nil; connect != test.exp.connect {
+				t.Fatalf("unexpected connect; actual: %v, expected: %v, error: %v", connect, test.exp.connect, err)
+			}
+			if err == nil {
+				conn.Close()
+			}
+		})
+	}
+}
+
+func TestMultipleClientCAs(t *testing.T) {
+	dir := t.TempDir()
+	alpha := newTestCA(t, dir, "alpha_ca")
+	beta := newTestCA(t, dir, "beta_ca")
+	gamma := newTestCA(t, dir, "gamma_ca")
+
+	serverCert, serverKey := alpha.leaf(t, 2, "jobworker", x509.ExtKeyUsageServerAuth)
+	serverConfig, err := NewServermTLSConfigWithCAs(serverCert, serverKey, []string{alpha.cert, beta.cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type expected struct {
+		connect bool
+	}
+	tests := map[string]struct {
+		ca  testCA
+		exp expected
+	}{
+		"first CA":   {ca: alpha, exp: expected{connect: true}},
+		"second CA":  {ca: beta, exp: expected{connect: true}},
+		"unknown CA": {ca: gamma, exp: expected{connect: false}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			clientCert, clientKey := test.ca.leaf(t, 3, name+"_user", x509.ExtKeyUsageClientAuth)
+			clientConfig, err := NewClientTLSConfig(clientCert, clientKey, alpha.cert)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = serverHandshake(t, serverConfig, clientConfig)
+			if connect := err == nil; connect != test.exp.connect {
+				t.Fatalf("unexpected connect; actual: %v, expected: %v, error: %v", connect, test.exp.connect, err)
+			}
+		})
+	}
+}
+
+func TestServerName(t *testing.T) {
+	ca := newTestCA(t, t.TempDir(), "ca")
+	serverCert, serverKey := ca.leaf(t, 2, "jobworker", x509.ExtKeyUsageServerAuth, "jobworker.example.com")
+	clientCert, clientKey := ca.leaf(t, 3, "alpha_user", x509.ExtKeyUsageClientAuth)
+
+	serverConfig, err := NewServermTLSConfig(serverCert, serverKey, ca.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type expected struct {
+		connect bool
+	}
+	tests := map[string]struct {
+		options []TLSOption
+		exp     expected
+	}{
+		"default server name": {
+			exp: expected{connect: false},
+		},
+		"matching server name": {
+			options: []TLSOption{WithServerName("jobworker.example.com")},
+			exp:     expected{connect: true},
+		},
+		"mismatched server name": {
+			options: []TLSOption{WithServerName("other.example.com")},
+			exp:     expected{connect: false},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			clientConfig, err := NewClientTLSConfig(clientCert, clientKey, ca.cert, test.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lis, err := tls.Listen("tcp", "localhost:0", serverConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer lis.Close()
+
+			go func() {
+				conn, err := lis.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+			}()
+
+			conn, err := tls.DialWithDialer(
+				&net.Dialer{Timeout: 5 * time.Second},
+				"tcp",
+				lis.Addr().String(),
+				clientConfig,
+			)
+			if connect := err == nil; connect != test.exp.connect {
+				t.Fatalf("unexpected connect; actual: %v, expected: %v, error: %v", connect, test.exp.connect, err)
+			}
+			if err == nil {
+				conn.Close()
+			}
+		})
+	}
+}
+
+func TestInvalidClientCA(t *testing.T) {
+	certs := newTestCerts(t)
+
+	invalid := filepath.Join(t.TempDir(), "invalid.crt")
+	if err := os.WriteFile(invalid, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	type expected struct {
+		err error
+	}
+	tests := map[string]struct {
+		caCerts []string
+		exp     expected
+	}{
+		"valid and invalid": {
+			caCerts: []string{certs.caCert, invalid},
+			exp:     expected{err: nil},
+		},
+		"only invalid": {
+			caCerts: []string{invalid},
+			exp:     expected{err: errInvalidCaCert},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewServermTLSConfigWithCAs(certs.serverCert, certs.serverKey, test.caCerts)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if err != nil && !strings.Contains(err.Error(), invalid) {
+				t.Fatalf("expected error naming file; error: %v, file: %v", err, invalid)
+			}
+		})
+	}
+}
+
+func TestCADirectory(t *testing.T) {
+	dir := t.TempDir()
+	alpha := newTestCA(t, dir, "alpha_ca")
+	beta := newTestCA(t, dir, "beta_ca")
+	gamma := newTestCA(t, dir, "gamma_ca")
+
+	// The CA directory holds the old and new CA during a rotation, alongside
+	// files that are not certificates.
+	caDir := t.TempDir()
+	for _, ca := range []testCA{alpha, beta} {
+		b, err := os.ReadFile(ca.cert)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(caDir, filepath.Base(ca.cert)), b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(caDir, "README"), []byte("CA certificates"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	serverCert, serverKey := beta.leaf(t, 2, "jobworker", x509.ExtKeyUsageServerAuth)
+	serverConfig, err := NewServermTLSConfig(serverCert, serverKey, caDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type expected struct {
+		connect bool
+	}
+	tests := map[string]struct {
+		ca  testCA
+		exp expected
+	}{
+		"old CA":     {ca: alpha, exp: expected{connect: true}},
+		"new CA":     {ca: beta, exp: expected{connect: true}},
+		"unknown CA": {ca: gamma, exp: expected{connect: false}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			clientCert, clientKey := test.ca.leaf(t, 3, name+"_user", x509.ExtKeyUsageClientAuth)
+			clientConfig, err := NewClientTLSConfigWithCAs(clientCert, clientKey, []string{caDir})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = serverHandshake(t, serverConfig, clientConfig)
+			if connect := err == nil; connect != test.exp.connect {
+				t.Fatalf("unexpected connect; actual: %v, expected: %v, error: %v", connect, test.exp.connect, err)
+			}
+		})
+	}
+}
+
+func TestTLSOptions(t *testing.T) {
+	certs := newTestCerts(t)
+
+	type expected struct {
+		minVersion       uint16
+		cipherSuites     []uint16
+		curvePreferences []tls.CurveID
+		err              error
+	}
+	tests := map[string]struct {
+		options []TLSOption
+		exp     expected
+	}{
+		"default": {
+			exp: expected{minVersion: tls.VersionTLS13},
+		},
+		"TLS 1.2 w/ cipher suites and curves": {
+			options: []TLSOption{
+				WithMinVersion(tls.VersionTLS12),
+				WithCipherSuites([]uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384}),
+				WithCurvePreferences([]tls.CurveID{tls.X25519, tls.CurveP256}),
+			},
+			exp: expected{
+				minVersion:       tls.VersionTLS12,
+				cipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
+				curvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+			},
+		},
+		"TLS 1.1": {
+			options: []TLSOption{WithMinVersion(tls.VersionTLS11)},
+			exp:     expected{err: ErrUnsupportedTLSVersion},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			configs := map[string]func() (*tls.Config, error){
+				"server": func() (*tls.Config, error) {
+					return NewServermTLSConfig(certs.serverCert, certs.serverKey, certs.caCert, test.options...)
+				},
+				"client": func() (*tls.Config, error) {
+					return NewClientTLSConfig(certs.clientCert, certs.clientKey, certs.caCert, test.options...)
+				},
+			}
+			for side, newConfig := range configs {
+				config, err := newConfig()
+				if !errors.Is(err, test.exp.err) {
+					t.Fatalf("unexpected %s error; actual: %v, expected: %v", side, err, test.exp.err)
+				}
+				if err != nil {
+					continue
+				}
+				if config.MinVersion != test.exp.minVersion {
+					t.Fatalf("unexpected %s min version; actual: %#04x, expected: %#04x", side, config.MinVersion, test.exp.minVersion)
+				}
+				if !reflect.DeepEqual(config.CipherSuites, test.exp.cipherSuites) {
+					t.Fatalf("unexpected %s cipher suites; actual: %v, expected: %v", side, config.CipherSuites, test.exp.cipherSuites)
+				}
+				if !reflect.DeepEqual(config.CurvePreferences, test.exp.curvePreferences) {
+					t.Fatalf("unexpected %s curves; actual: %v, expected: %v", side, config.CurvePreferences, test.exp.curvePreferences)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	type expected struct {
+		suites []uint16
+		err    error
+	}
+	tests := map[string]struct {
+		names []string
+		exp   expected
+	}{
+		"supported": {
+			names: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"},
+			exp: expected{suites: []uint16{
+				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+			}},
+		},
+		"insecure": {
+			names: []string{"TLS_RSA_WITH_RC4_128_SHA"},
+			exp:   expected{err: ErrUnsupportedCipherSuite},
+		},
+		"unknown": {
+			names: []string{"TLS_NOT_A_SUITE"},
+			exp:   expected{err: ErrUnsupportedCipherSuite},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			suites, err := ParseCipherSuites(test.names)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if err == nil && !reflect.DeepEqual(suites, test.exp.suites) {
+				t.Fatalf("unexpected suites; actual: %v, expected: %v", suites, test.exp.suites)
+			}
+		})
+	}
+}
+
+func TestParseCurves(t *testing.T) {
+	type expected struct {
+		curves []tls.CurveID
+		err    error
+	}
+	tests := map[string]struct {
+		names []string
+		exp   expected
+	}{
+		"supported": {
+			names: []string{"X25519", "P384"},
+			exp:   expected{curves: []tls.CurveID{tls.X25519, tls.CurveP384}},
+		},
+		"unknown": {
+			names: []string{"P192"},
+			exp:   expected{err: ErrUnsupportedCurve},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			curves, err := ParseCurves(test.names)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if err == nil && !reflect.DeepEqual(curves, test.exp.curves) {
+				t.Fatalf("unexpected curves; actual: %v, expected: %v", curves, test.exp.curves)
+			}
+		})
+	}
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	type expected struct {
+		version uint16
+		err     error
+	}
+	tests := map[string]struct {
+		version string
+		exp     expected
+	}{
+		"1.2": {version: "1.2", exp: expected{version: tls.VersionTLS12}},
+		"1.3": {version: "1.3", exp: expected{version: tls.VersionTLS13}},
+		"1.1": {version: "1.1", exp: expected{err: ErrUnsupportedTLSVersion}},
+		"bad": {version: "tls13", exp: expected{err: ErrUnsupportedTLSVersion}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			version, err := ParseTLSVersion(test.version)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if version != test.exp.version {
+				t.Fatalf("unexpected version; actual: %v, expected: %v", version, test.exp.version)
+			}
+		})
+	}
+}
+
+// testCerts are paths to PEM encoded certificates and keys generated for a
+// test.
+type testCerts struct {
+	caCert                string
+	serverCert, serverKey string
+	clientCert, clientKey string
+}
+
+// newTestCerts generates a CA, and a server and client certificate signed by
+// the CA, within a temporary directory.
+func newTestCerts(t *testing.T) testCerts {
+	ca := newTestCA(t, t.TempDir(), "ca")
+
+	certs := testCerts{caCert: ca.cert}
+	certs.serverCert, certs.serverKey = ca.leaf(t, 2, "jobworker", x509.ExtKeyUsageServerAuth)
+	certs.clientCert, certs.clientKey = ca.leaf(t, 3, "alpha_user", x509.ExtKeyUsageClientAuth)
+
+	return certs
+}
+
+// testCA is a CA generated for a test, signing leaf certificates.
+type testCA struct {
+	// dir is the directory the CA's certificates and keys are written within.
+	dir string
+	// cert is the path to the PEM encoded CA certificate.
+	cert string
+	ca   *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCA generates a CA named name, writing its certificate within dir.
+func newTestCA(t *testing.T, dir, name string) testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert := filepath.Join(dir, name+".crt")
+	writePEM(t, cert, "CERTIFICATE", der)
+	return testCA{dir: dir, cert: cert, ca: ca, key: key}
+}
+
+// leaf generates a certificate and key named name signed by the CA, and
+// returns their paths. The certificate is issued for dnsNames, or "localhost"
+// if none are specified.
+func (c testCA) leaf(t *testing.T, serial int64, name string, usage x509.ExtKeyUsage, dnsNames ...string) (string, string) {
+	if len(dnsNames) == 0 {
+		dnsNames = []string{"localhost"}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, c.ca, &key.PublicKey, c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, keyFile := filepath.Join(c.dir, name+".crt"), filepath.Join(c.dir, name+".key")
+	writePEM(t, cert, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return cert, keyFile
+}
+
+// serverHandshake completes a TLS handshake between a server configured by
+// serverConfig and a client configured by clientConfig, and returns the
+// server's handshake error.
+func serverHandshake(t *testing.T, serverConfig, clientConfig *tls.Config) error {
+	lis, err := tls.Listen("tcp", "localhost:0", serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer conn.Close()
+		errc <- conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.DialWithDialer(
+		&net.Dialer{Timeout: 5 * time.Second},
+		"tcp",
+		lis.Addr().String(),
+		clientConfig,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// With TLS 1.3, the server verifies the client certificate after the
+	// client completes its handshake.
+	conn.Handshake()
+
+	return <-errc
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	b := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
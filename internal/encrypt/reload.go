@@ -0,0 +1,149 @@
+package encrypt
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tjper/teleport/internal/log"
+)
+
+// logger is an object for logging package events to stdout.
+var logger = log.New(os.Stdout, "encrypt")
+
+// reloadInterval is the minimum interval between checks of whether a
+// CertReloader's certificate and key files have been modified.
+const reloadInterval = time.Second
+
+// NewCertReloader creates a CertReloader instance serving the certificate and
+// key at certPath and keyPath.
+func NewCertReloader(certPath, keyPath string) (*CertReloader, error) {
+	r := &CertReloader{
+		certPath: certPath,
+		keyPath:  keyPath,
+		interval: reloadInterval,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// CertReloader serves a certificate and key pair from files, reloading them
+// once either file changes, so certificates may be rotated without restarting
+// the server. The files are checked for changes at most once per interval, so
+// handshakes are not serialized on filesystem I/O. If a changed pair fails to
+// load, e.g. as only one of the files has been replaced, the previously loaded
+// certificate remains in use.
+type CertReloader struct {
+	certPath, keyPath string
+	// interval is the minimum interval between checks of whether the files
+	// have been modified.
+	interval time.Duration
+
+	mutex sync.RWMutex
+	cert  *tls.Certificate
+	// certMod and keyMod are the modification times of the files when they
+	// were last loaded.
+	certMod, keyMod time.Time
+	// checked is the time the files were last checked for modifications.
+	checked time.Time
+}
+
+// Reload loads the certificate and key files again. If the pair cannot be
+// loaded, the previously loaded certificate remains in use.
+func (r *CertReloader) Reload() error {
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return err
+	}
+	return r.load(certMod, keyMod)
+}
+
+// GetCertificate retrieves the certificate to serve, reloading the
+// certificate and key files if either has been modified since they were last
+// loaded. It is suited for use as tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if !r.due() {
+		return r.current(), nil
+	}
+
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		logger.Errorf("%v", err)
+		return r.current(), nil
+	}
+	r.mutex.RLock()
+	modified := !certMod.Equal(r.certMod) || !keyMod.Equal(r.keyMod)
+	r.mutex.RUnlock()
+	if !modified {
+		return r.current(), nil
+	}
+
+	if err := r.load(certMod, keyMod); err != nil {
+		logger.Errorf("reload server cert & key, using previous cert; error: %v", err)
+		return r.current(), nil
+	}
+	logger.Infof("server cert & key reloaded; cert: %s, key: %s", r.certPath, r.keyPath)
+	return r.current(), nil
+}
+
+// due checks if the interval has elapsed since the files were last checked
+// for modifications. If so, the check is recorded as made now, so only the
+// caller checks the files, and other handshakes are served the current
+// certificate meanwhile.
+func (r *CertReloader) due() bool {
+	r.mutex.RLock()
+	due := time.Since(r.checked) >= r.interval
+	r.mutex.RUnlock()
+	if !due {
+		return false
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if time.Since(r.checked) < r.interval {
+		return false
+	}
+	r.checked = time.Now()
+	return true
+}
+
+// current retrieves the loaded certificate.
+func (r *CertReloader) current() *tls.Certificate {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.cert
+}
+
+// load loads the certificate and key files, last modified at certMod and
+// keyMod. The modification times are recorded even if the pair fails to load,
+// so a broken pair is not loaded again until it is modified.
+func (r *CertReloader) load(certMod, keyMod time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.certMod, r.keyMod = certMod, keyMod
+	if err != nil {
+		return fmt.Errorf("load server cert & key; error: %w", err)
+	}
+	r.cert = &cert
+	return nil
+}
+
+// modTimes retrieves the modification times of the certificate and key
+// files.
+func (r *CertReloader) modTimes() (certMod, keyMod time.Time, err error) {
+	certInfo, err := os.Stat(r.certPath)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("stat server cert; path: %s, error: %w", r.certPath, err)
+	}
+	keyInfo, err := os.Stat(r.keyPath)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("stat server key; path: %s, error: %w", r.keyPath, err)
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
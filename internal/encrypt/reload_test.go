@@ -0,0 +1,99 @@
+package encrypt
+
+import (
+	"crypto/x509"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCertReloader(t *testing.T) {
+	ca := newTestCA(t, t.TempDir(), "ca")
+	cert, key := ca.leaf(t, 2, "jobworker", x509.ExtKeyUsageServerAuth)
+
+	reloader, err := NewCertReloader(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertServedSerial(t, reloader, 2)
+
+	// Modifications are not observed until the interval has elapsed since the
+	// files were last checked.
+	ca.leaf(t, 3, "jobworker", x509.ExtKeyUsageServerAuth)
+	touch(t, time.Minute, cert, key)
+	assertServedSerial(t, reloader, 2)
+	reloader.interval = 0
+
+	// A rotated pair is served once either file is modified.
+	assertServedSerial(t, reloader, 3)
+
+	// A malformed pair is not loaded, and the previous certificate remains in
+	// use.
+	writePEM(t, cert, "CERTIFICATE", []byte("malformed"))
+	touch(t, 2*time.Minute, cert)
+	assertServedSerial(t, reloader, 3)
+	if err := reloader.Reload(); err == nil {
+		t.Fatal("expected reload error")
+	}
+	assertServedSerial(t, reloader, 3)
+}
+
+func TestServerCertReload(t *testing.T) {
+	ca := newTestCA(t, t.TempDir(), "ca")
+	serverCert, serverKey := ca.leaf(t, 2, "jobworker", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.leaf(t, 3, "alpha_user", x509.ExtKeyUsageClientAuth)
+
+	serverConfig, err := NewServermTLSConfig(serverCert, serverKey, ca.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientConfig, err := NewClientTLSConfig(clientCert, clientKey, ca.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := serverHandshake(t, serverConfig, clientConfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The server certificate is replaced by one issued for another name, which
+	// is served once the files are next checked.
+	ca.leaf(t, 4, "jobworker", x509.ExtKeyUsageServerAuth, "jobworker.example.com")
+	touch(t, time.Minute, serverCert, serverKey)
+	time.Sleep(reloadInterval)
+
+	clientConfig, err = NewClientTLSConfig(clientCert, clientKey, ca.cert, WithServerName("jobworker.example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := serverHandshake(t, serverConfig, clientConfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// assertServedSerial asserts the certificate served by reloader has serial
+// number serial.
+func assertServedSerial(t *testing.T, reloader *CertReloader, serial int64) {
+	cert, err := reloader.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaf.SerialNumber.Int64() != serial {
+		t.Fatalf("unexpected serial; actual: %v, expected: %v", leaf.SerialNumber, serial)
+	}
+}
+
+// touch sets the modification times of paths to d from now, so changes are
+// observed regardless of the filesystem's timestamp granularity.
+func touch(t *testing.T, d time.Duration, paths ...string) {
+	mod := time.Now().Add(d)
+	for _, path := range paths {
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
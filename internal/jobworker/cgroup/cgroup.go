@@ -2,12 +2,14 @@ package cgroup
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/sys/unix"
@@ -17,18 +19,34 @@ import (
 type Cgroup struct {
 	// ID is the unique identifier of the cgroup.
 	ID uuid.UUID
-	// Memory is the "memory.high" bytes limit applied to this cgroup. A zeroed
-	// value indicates no limit is set.
+	// Memory is the "memory.high" bytes limit applied to this cgroup. Memory
+	// usage beyond the limit is throttled. A zeroed value indicates no limit is
+	// set.
 	Memory uint64
+	// MemoryMax is the "memory.max" bytes limit applied to this cgroup. Memory
+	// usage beyond the limit invokes the OOM killer. A zeroed value indicates
+	// no limit is set.
+	MemoryMax uint64
 	// Cpus is the "cpu.max" limit applied to this cgroup. A zeroed value
 	// indicates no limit is set.
 	Cpus float32
-	// DiskWriteBps is the "io.max" bytes written per second limit for 8 block
-	// devices applied to this cgroup. A zeroed value indicates no limit is set.
+	// DiskWriteBps is the "io.max" bytes written per second limit applied to
+	// this cgroup for the block device of the Service's disk path, see
+	// WithDiskPath. A zeroed value indicates no limit is set.
 	DiskWriteBps uint64
-	// DiskReadBps is the "io.max" bytes read per second limit for 8 block
-	// devices applied to this cgroup. A zeroed value indicates no limit is set.
+	// DiskReadBps is the "io.max" bytes read per second limit applied to this
+	// cgroup for the block device of the Service's disk path, see WithDiskPath.
+	// A zeroed value indicates no limit is set.
 	DiskReadBps uint64
+	// CpuWeight is the "cpu.weight" proportional share applied to this cgroup.
+	// A zeroed value indicates no weight is set.
+	CpuWeight uint64
+	// PidsMax is the "pids.max" limit on the number of processes in this
+	// cgroup. A zeroed value indicates no limit is set.
+	PidsMax uint64
+	// CpuSet is the "cpuset.cpus" list of CPUs this cgroup is pinned to, e.g.
+	// "0-3,8". An empty value indicates no CPUs are pinned.
+	CpuSet string
 
 	// service is the Service a Cgroup belongs to.
 	service Service
@@ -46,23 +64,47 @@ func WithMemory(limit uint64) CgroupOption {
 	return func(c *Cgroup) { c.Memory = limit }
 }
 
+// WithMemoryMax configures a Cgroup to utilize the specified hard memory bytes
+// limit. Unlike WithMemory, processes exceeding the limit are OOM killed.
+func WithMemoryMax(limit uint64) CgroupOption {
+	return func(c *Cgroup) { c.MemoryMax = limit }
+}
+
 // WithCpus configures a Cgroup to utilize the specified cpus limit.
 func WithCpus(limit float32) CgroupOption {
 	return func(c *Cgroup) { c.Cpus = limit }
 }
 
 // WithDiskWriteBps configures a Cgroup to utilize the specified bytes per
-// second limit for disk (block 8 devices) writes.
+// second limit for writes to the block device of the Service's disk path.
 func WithDiskWriteBps(limit uint64) CgroupOption {
 	return func(c *Cgroup) { c.DiskWriteBps = limit }
 }
 
 // WithDiskReadBps configures a Cgroup to utilize the specified bytes per
-// second limit for disk (block 8 devices) reads.
+// second limit for reads from the block device of the Service's disk path.
 func WithDiskReadBps(limit uint64) CgroupOption {
 	return func(c *Cgroup) { c.DiskReadBps = limit }
 }
 
+// WithCpuWeight configures a Cgroup to utilize the specified cpu weight. The
+// weight must be within [MinCpuWeight, MaxCpuWeight].
+func WithCpuWeight(weight uint64) CgroupOption {
+	return func(c *Cgroup) { c.CpuWeight = weight }
+}
+
+// WithPidsMax configures a Cgroup to utilize the specified limit on the
+// number of processes.
+func WithPidsMax(limit uint64) CgroupOption {
+	return func(c *Cgroup) { c.PidsMax = limit }
+}
+
+// WithCpuSet configures a Cgroup to be pinned to the specified list of CPUs,
+// e.g. "0-3,8". See ValidateCpuSet.
+func WithCpuSet(cpus string) CgroupOption {
+	return func(c *Cgroup) { c.CpuSet = cpus }
+}
+
 // controller enables and applies cgroup controls.
 type controller interface {
 	enable() error
@@ -75,11 +117,21 @@ func (c Cgroup) create() error {
 		return fmt.Errorf("create cgroup: %w", err)
 	}
 
-	// determine which controllers should be enabled.
+	// determine which controllers should be enabled. Only the controllers of
+	// the limits set are enabled, so a cgroup without disk limits does not
+	// require the io controller to be available.
 	var set []controller
 	if c.Memory > 0 {
 		set = append(set, newMemoryController(c, c.Memory))
 	}
+	if c.MemoryMax > 0 {
+		set = append(set, newMemoryMaxController(c, c.MemoryMax))
+	}
+	// The OOM killer kills the whole cgroup, rather than a single process,
+	// so a Job is not left partially running.
+	if c.Memory > 0 || c.MemoryMax > 0 {
+		set = append(set, newOomGroupController(c))
+	}
 	if c.Cpus > 0 {
 		set = append(set, newCPUController(c, c.Cpus))
 	}
@@ -89,6 +141,15 @@ func (c Cgroup) create() error {
 	if c.DiskReadBps > 0 {
 		set = append(set, newDiskReadBpsController(c, c.DiskReadBps))
 	}
+	if c.CpuWeight > 0 {
+		set = append(set, newCPUWeightController(c, c.CpuWeight))
+	}
+	if c.PidsMax > 0 {
+		set = append(set, newPidsController(c, c.PidsMax))
+	}
+	if c.CpuSet != "" {
+		set = append(set, newCpusetController(c, c.CpuSet))
+	}
 
 	for _, controller := range set {
 		if err := controller.enable(); err != nil {
@@ -121,8 +182,36 @@ func (c Cgroup) placePID(pid int) error {
 	return nil
 }
 
-// remove removes the jobworker cgroup.
+// freeze freezes the cgroup if frozen is true, suspending all processes within
+// the cgroup and its descendants. Otherwise, the cgroup is thawed.
+func (c Cgroup) freeze(frozen bool) error {
+	file := filepath.Join(c.path, cgroupFreeze)
+	value := "0"
+	if frozen {
+		value = "1"
+	}
+
+	if err := os.WriteFile(file, []byte(value), fileMode); err != nil {
+		return fmt.Errorf("write %s to %s: %w", value, file, err)
+	}
+
+	return nil
+}
+
+// remove removes the jobworker cgroup. Processes migrated out of the cgroup
+// may not have left it by the time the cgroup is removed, so removal is
+// retried while the cgroup is busy, until the Service's remove timeout
+// elapses.
 func (c Cgroup) remove() error {
+	err := retryBusy(c.service.retryTimeout(), c.tryRemove)
+	if errors.Is(err, unix.EBUSY) {
+		return fmt.Errorf("cgroup %s busy after %v: %w", c.path, c.service.retryTimeout(), err)
+	}
+	return err
+}
+
+// tryRemove makes a single attempt to remove the jobworker cgroup.
+func (c Cgroup) tryRemove() error {
 	// Read all pids within cgroup.
 	pids, err := c.readPids()
 	if err != nil {
@@ -232,12 +321,31 @@ func (c Cgroup) removeLeaves() error {
 	for _, leaf := range leaves {
 		path := filepath.Join(c.path, leaf.String())
 		if err := unix.Rmdir(path); err != nil {
-			return fmt.Errorf("rm leaf cgroup; path: %s, error: %v", path, err)
+			return fmt.Errorf("rm leaf cgroup %s: %w", path, err)
 		}
 	}
 	return nil
 }
 
+// retryBusy calls attempt until it succeeds, fails with an error other than
+// EBUSY, or timeout elapses. Attempts are retried with exponential backoff.
+func retryBusy(timeout time.Duration, attempt func() error) error {
+	deadline := time.Now().Add(timeout)
+	backoff := minRemoveBackoff
+	for {
+		err := attempt()
+		if !errors.Is(err, unix.EBUSY) || time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxRemoveBackoff {
+			backoff = maxRemoveBackoff
+		}
+	}
+}
+
 // readLeafPids retrieves all pids that belong to leaf cgroup.
 func readLeafPids(path string) ([]int, error) {
 	fd, err := os.Open(path)
@@ -262,8 +370,55 @@ func readLeafPids(path string) ([]int, error) {
 	return pids, nil
 }
 
+const (
+	// MinCpuWeight is the minimum "cpu.weight" value supported by cgroups v2.
+	MinCpuWeight = 1
+	// MaxCpuWeight is the maximum "cpu.weight" value supported by cgroups v2.
+	MaxCpuWeight = 10000
+	// MinPidsMax is the minimum "pids.max" value supported by jobworker
+	// cgroups. A job's cgroup holds the jobworker process launching the job in
+	// addition to the job's processes.
+	MinPidsMax = 2
+)
+
+// ErrInvalidCpuSet indicates a CPU list is not formatted as a comma-separated
+// list of CPUs and CPU ranges.
+var ErrInvalidCpuSet = errors.New("invalid cpu set")
+
+// ValidateCpuSet checks cpus is a "cpuset.cpus" formatted list of CPUs, being
+// a comma-separated list of CPU numbers and inclusive ranges, e.g. "0-3,8". If
+// cpus is not valid, ErrInvalidCpuSet is returned.
+func ValidateCpuSet(cpus string) error {
+	for _, item := range strings.Split(cpus, ",") {
+		bounds := strings.SplitN(item, "-", 2)
+		first, err := strconv.ParseUint(bounds[0], 10, 16)
+		if err != nil {
+			return fmt.Errorf("%w; cpus: %q", ErrInvalidCpuSet, cpus)
+		}
+		if len(bounds) == 1 {
+			continue
+		}
+		last, err := strconv.ParseUint(bounds[1], 10, 16)
+		if err != nil || last < first {
+			return fmt.Errorf("%w; cpus: %q", ErrInvalidCpuSet, cpus)
+		}
+	}
+	return nil
+}
+
+const (
+	// minRemoveBackoff is the delay before the first retry of removing a busy
+	// cgroup.
+	minRemoveBackoff = 10 * time.Millisecond
+	// maxRemoveBackoff is the maximum delay between retries of removing a busy
+	// cgroup.
+	maxRemoveBackoff = 500 * time.Millisecond
+)
+
 const (
 	// cgroupProcs is the name of the file that contains all processes within a
 	// cgroup.
 	cgroupProcs = "cgroup.procs"
+	// cgroupFreeze is the name of the file that freezes and thaws a cgroup.
+	cgroupFreeze = "cgroup.freeze"
 )
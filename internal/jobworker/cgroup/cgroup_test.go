@@ -9,10 +9,14 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
-	"github.com/tjper/teleport/internal/device"
+	"github.com/google/uuid"
+	"golang.org/x/sys/unix"
 )
 
 func TestServiceSetupAndCleanup(t *testing.T) {
@@ -30,9 +34,11 @@ func TestServiceSetupAndCleanup(t *testing.T) {
 	}
 
 	expected := []string{
+		cpuset,
 		cpu,
 		io,
 		memory,
+		pids,
 	}
 	controllers, err := readControllers(service.path)
 	if err != nil {
@@ -109,6 +115,126 @@ func TestCleanupWithPids(t *testing.T) {
 	}
 }
 
+func TestCleanupWithStoppedPid(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service, err := NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cgroup, err := service.CreateCgroup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("exec sleep 30: %s", err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	if err := service.PlaceInCgroup(*cgroup, cmd.Process.Pid); err != nil {
+		t.Fatalf("place in cgroup; pid: %d, error: %s", cmd.Process.Pid, err)
+	}
+
+	// A stopped process is migrated out of the cgroup, but may leave the cgroup
+	// busy until it is resumed.
+	if err := cmd.Process.Signal(syscall.SIGSTOP); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		_ = cmd.Process.Signal(syscall.SIGCONT)
+	}()
+
+	if err := service.Cleanup(); err != nil {
+		t.Fatalf("service cleanup; error: %s", err)
+	}
+
+	if _, err := os.Stat(service.path); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected cgroup to not exist; path: %s, err: %v", service.path, err)
+	}
+}
+
+func TestRetryBusy(t *testing.T) {
+	errOther := errors.New("other")
+
+	type expected struct {
+		err      error
+		attempts int
+	}
+	tests := map[string]struct {
+		// errs are the errors returned by each attempt. Attempts beyond errs
+		// succeed.
+		errs    []error
+		timeout time.Duration
+		exp     expected
+	}{
+		"success": {
+			timeout: time.Second,
+			exp:     expected{attempts: 1},
+		},
+		"busy then success": {
+			errs:    []error{unix.EBUSY, unix.EBUSY},
+			timeout: time.Second,
+			exp:     expected{attempts: 3},
+		},
+		"other error": {
+			errs:    []error{unix.EBUSY, errOther},
+			timeout: time.Second,
+			exp:     expected{err: errOther, attempts: 2},
+		},
+		"busy beyond timeout": {
+			errs:    []error{unix.EBUSY, unix.EBUSY, unix.EBUSY, unix.EBUSY},
+			timeout: 25 * time.Millisecond,
+			exp:     expected{err: unix.EBUSY, attempts: 2},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var attempts int
+			err := retryBusy(test.timeout, func() error {
+				attempts++
+				if attempts <= len(test.errs) {
+					return fmt.Errorf("attempt %d: %w", attempts, test.errs[attempts-1])
+				}
+				return nil
+			})
+			if !errors.Is(err, test.exp.err) || (err == nil) != (test.exp.err == nil) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if attempts != test.exp.attempts {
+				t.Fatalf("unexpected attempts; actual: %d, expected: %d", attempts, test.exp.attempts)
+			}
+		})
+	}
+}
+
+func TestCleanupError(t *testing.T) {
+	err := error(CleanupError{
+		errors.New("remove cgroup a: busy"),
+		fmt.Errorf("remove cgroup b: %w", unix.EBUSY),
+	})
+
+	if !errors.Is(err, unix.EBUSY) {
+		t.Fatalf("expected error to be EBUSY; error: %v", err)
+	}
+	if errors.Is(err, unix.ENOENT) {
+		t.Fatalf("unexpected error to be ENOENT; error: %v", err)
+	}
+	expected := "cleanup 2 cgroups failed: remove cgroup a: busy; remove cgroup b: device or resource busy"
+	if err.Error() != expected {
+		t.Fatalf("unexpected message; actual: %q, expected: %q", err.Error(), expected)
+	}
+}
+
 func TestCreateCgroup(t *testing.T) {
 	if !isRoot() {
 		t.Skip("must be root to run")
@@ -129,9 +255,11 @@ func TestCreateCgroup(t *testing.T) {
 	}{
 		"no options":              {},
 		"w/ memory limit":         {options: []CgroupOption{WithMemory(1000000000)}},
+		"w/ memory max limit":     {options: []CgroupOption{WithMemoryMax(1000000000)}},
 		"w/ cpu limit":            {options: []CgroupOption{WithCpus(1.5)}},
 		"w/ disk write bps limit": {options: []CgroupOption{WithDiskWriteBps(100000)}},
 		"w/ disk read bps limit":  {options: []CgroupOption{WithDiskReadBps(100000)}},
+		"w/ pids limit":           {options: []CgroupOption{WithPidsMax(64)}},
 	}
 
 	for name, test := range tests {
@@ -148,7 +276,7 @@ func TestCreateCgroup(t *testing.T) {
 	}
 }
 
-func TestPlaceInCgroup(t *testing.T) {
+func TestPidsMax(t *testing.T) {
 	if !isRoot() {
 		t.Skip("must be root to run")
 	}
@@ -163,29 +291,126 @@ func TestPlaceInCgroup(t *testing.T) {
 		}
 	}()
 
-	cgroup, err := service.CreateCgroup()
+	cgroup, err := service.CreateCgroup(WithPidsMax(MinPidsMax))
 	if err != nil {
-		t.Fatal(err)
+		t.Fatalf("create cgroup error: %s", err)
 	}
 
-	cmd := exec.Command("sleep", "30")
-	if err := cmd.Start(); err != nil {
-		t.Fatalf("exec sleep 30: %s", err)
+	controllers, err := readControllers(cgroup.path)
+	if err != nil {
+		t.Fatal(err)
 	}
-
-	if err := service.PlaceInCgroup(*cgroup, cmd.Process.Pid); err != nil {
-		t.Fatalf("place in cgroup; pid: %d, error: %s", cmd.Process.Pid, err)
+	if !reflect.DeepEqual(controllers, []string{pids}) {
+		t.Fatalf("unexpected controllers; actual: %v, expected: %v", controllers, []string{pids})
 	}
 
-	pids, err := readPids(cgroup.path)
+	b, err := os.ReadFile(filepath.Join(cgroup.path, pidsMax))
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(pids) != 1 {
-		t.Fatalf("unexpected pids; actual: %v, expected: %v", pids, cmd.Process.Pid)
+	if value := strings.TrimSpace(string(b)); value != strconv.Itoa(MinPidsMax) {
+		t.Fatalf("unexpected %s; actual: %s, expected: %d", pidsMax, value, MinPidsMax)
+	}
+}
+
+func TestPlaceInCgroup(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	tests := map[string]struct {
+		options []CgroupOption
+	}{
+		"no controllers": {},
+		// A cgroup with controllers enabled may not contain processes itself,
+		// the "no internal processes" constraint; pids are placed in a leaf.
+		"controllers enabled": {
+			options: []CgroupOption{
+				WithMemory(1 << 30),
+				WithCpus(1),
+				WithPidsMax(10),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service, err := NewService()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if err := service.Cleanup(); err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			cgroup, err := service.CreateCgroup(test.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := exec.Command("sleep", "30")
+			if err := cmd.Start(); err != nil {
+				t.Fatalf("exec sleep 30: %s", err)
+			}
+			defer func() { _ = cmd.Process.Kill() }()
+
+			if err := service.PlaceInCgroup(*cgroup, cmd.Process.Pid); err != nil {
+				t.Fatalf("place in cgroup; pid: %d, error: %s", cmd.Process.Pid, err)
+			}
+
+			pids, err := readPids(cgroup.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pids) != 1 {
+				t.Fatalf("unexpected pids; actual: %v, expected: %v", pids, cmd.Process.Pid)
+			}
+			if pids[0] != cmd.Process.Pid {
+				t.Fatalf("unexpected pid; actual: %v, expected: %v", pids[0], cmd.Process.Pid)
+			}
+
+			internal, err := readLeafPids(filepath.Join(cgroup.path, cgroupProcs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(internal) != 0 {
+				t.Fatalf("unexpected internal pids; actual: %v", internal)
+			}
+		})
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	type expected struct {
+		value string
 	}
-	if pids[0] != cmd.Process.Pid {
-		t.Fatalf("unexpected pid; actual: %v, expected: %v", pids[0], cmd.Process.Pid)
+	tests := map[string]struct {
+		frozen bool
+		exp    expected
+	}{
+		"freeze": {frozen: true, exp: expected{value: "1"}},
+		"thaw":   {frozen: false, exp: expected{value: "0"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			cgroup := Cgroup{path: dir}
+
+			if err := cgroup.freeze(test.frozen); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			b, err := os.ReadFile(filepath.Join(dir, cgroupFreeze))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != test.exp.value {
+				t.Fatalf("unexpected value; actual: %s, expected: %s", b, test.exp.value)
+			}
+		})
 	}
 }
 
@@ -210,6 +435,22 @@ func TestControllers(t *testing.T) {
 				values:  "1024",
 			},
 		},
+		"memory max": {
+			file:       "memory.max",
+			controller: newMemoryMaxController(cgroup, 2048),
+			exp: expected{
+				enabled: "+memory\n",
+				values:  "2048",
+			},
+		},
+		"memory oom group": {
+			file:       "memory.oom.group",
+			controller: newOomGroupController(cgroup),
+			exp: expected{
+				enabled: "+memory\n",
+				values:  "1",
+			},
+		},
 		"cpu": {
 			file:       "cpu.max",
 			controller: newCPUController(cgroup, 1.5),
@@ -218,6 +459,30 @@ func TestControllers(t *testing.T) {
 				values:  "150000 100000",
 			},
 		},
+		"cpu weight": {
+			file:       "cpu.weight",
+			controller: newCPUWeightController(cgroup, 250),
+			exp: expected{
+				enabled: "+cpu\n",
+				values:  "250",
+			},
+		},
+		"pids": {
+			file:       "pids.max",
+			controller: newPidsController(cgroup, 64),
+			exp: expected{
+				enabled: "+pids\n",
+				values:  "64",
+			},
+		},
+		"cpuset": {
+			file:       "cpuset.cpus",
+			controller: newCpusetController(cgroup, "0-3,8"),
+			exp: expected{
+				enabled: "+cpuset\n",
+				values:  "0-3,8",
+			},
+		},
 		"disk rbps": {
 			file:       "io.max",
 			controller: newDiskReadBpsController(cgroup, 2048),
@@ -264,6 +529,461 @@ func TestControllers(t *testing.T) {
 	}
 }
 
+func TestCreateWithoutIo(t *testing.T) {
+	tests := map[string]struct {
+		options []CgroupOption
+	}{
+		"memory only":    {options: []CgroupOption{WithMemory(1024)}},
+		"memory max":     {options: []CgroupOption{WithMemory(1024), WithMemoryMax(2048)}},
+		"memory and cpu": {options: []CgroupOption{WithMemory(1024), WithCpus(1), WithCpuWeight(100)}},
+		"pids":           {options: []CgroupOption{WithPidsMax(64)}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// The temporary directory stands in for a host without io delegation;
+			// cgroups without disk limits must not touch io.
+			cgroup := Cgroup{path: filepath.Join(t.TempDir(), "cgroup")}
+			for _, option := range test.options {
+				option(&cgroup)
+			}
+
+			if err := cgroup.create(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			b, err := os.ReadFile(filepath.Join(cgroup.path, cgroupSubtreeControl))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(b), io) {
+				t.Fatalf("unexpected io controller enabled; controllers: %s", b)
+			}
+			if _, err := os.Stat(filepath.Join(cgroup.path, ioMax)); !errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("unexpected %s; error: %v", ioMax, err)
+			}
+		})
+	}
+}
+
+func TestValidateCpuSet(t *testing.T) {
+	type expected struct {
+		err error
+	}
+	tests := map[string]struct {
+		cpus string
+		exp  expected
+	}{
+		"cpu":            {cpus: "0", exp: expected{}},
+		"range":          {cpus: "0-3", exp: expected{}},
+		"list":           {cpus: "0-3,8,10-11", exp: expected{}},
+		"single range":   {cpus: "2-2", exp: expected{}},
+		"empty":          {cpus: "", exp: expected{err: ErrInvalidCpuSet}},
+		"empty item":     {cpus: "0,,1", exp: expected{err: ErrInvalidCpuSet}},
+		"reversed range": {cpus: "3-0", exp: expected{err: ErrInvalidCpuSet}},
+		"open range":     {cpus: "0-", exp: expected{err: ErrInvalidCpuSet}},
+		"negative":       {cpus: "-1", exp: expected{err: ErrInvalidCpuSet}},
+		"whitespace":     {cpus: "0, 1", exp: expected{err: ErrInvalidCpuSet}},
+		"not a number":   {cpus: "all", exp: expected{err: ErrInvalidCpuSet}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateCpuSet(test.cpus)
+			if !errors.Is(err, test.exp.err) || (err == nil) != (test.exp.err == nil) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+		})
+	}
+}
+
+func TestFindCgroup2Mount(t *testing.T) {
+	type expected struct {
+		path string
+		err  error
+	}
+	tests := map[string]struct {
+		mounts string
+		exp    expected
+	}{
+		"unified": {
+			mounts: "22 28 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw\n" +
+				"28 1 254:0 / / rw,relatime shared:1 - ext4 /dev/vda rw\n" +
+				"30 23 0:26 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime shared:4 - cgroup2 cgroup2 rw,nsdelegate\n",
+			exp: expected{path: "/sys/fs/cgroup"},
+		},
+		"hybrid": {
+			mounts: "25 23 0:22 / /sys/fs/cgroup ro,nosuid,nodev,noexec shared:9 - tmpfs tmpfs ro,mode=755\n" +
+				"26 25 0:23 / /sys/fs/cgroup/unified rw,nosuid,nodev,noexec,relatime shared:10 - cgroup2 cgroup2 rw\n" +
+				"29 25 0:26 / /sys/fs/cgroup/memory rw,nosuid,nodev,noexec,relatime shared:13 - cgroup cgroup rw,memory\n",
+			exp: expected{path: "/sys/fs/cgroup/unified"},
+		},
+		"custom mount point": {
+			mounts: "412 28 0:35 / /cgroup2 rw,relatime shared:40 - cgroup2 none rw\n",
+			exp:    expected{path: "/cgroup2"},
+		},
+		"no optional fields": {
+			mounts: "412 28 0:35 / /cgroup2 rw,relatime - cgroup2 none rw\n",
+			exp:    expected{path: "/cgroup2"},
+		},
+		"multiple optional fields": {
+			mounts: "412 28 0:35 / /cgroup2 rw,relatime shared:4 master:1 - cgroup2 none rw\n",
+			exp:    expected{path: "/cgroup2"},
+		},
+		"escaped mount point": {
+			mounts: "412 28 0:35 / /mnt/cgroup\\040two rw,relatime - cgroup2 none rw\n",
+			exp:    expected{path: "/mnt/cgroup two"},
+		},
+		"cgroup2 mount source": {
+			mounts: "412 28 0:35 / /mnt/cgroup2 rw,relatime - tmpfs cgroup2 rw\n",
+			exp:    expected{err: ErrCgroup2NotMounted},
+		},
+		"cgroup v1 only": {
+			mounts: "25 23 0:22 / /sys/fs/cgroup ro,nosuid,nodev,noexec shared:9 - tmpfs tmpfs ro,mode=755\n" +
+				"29 25 0:26 / /sys/fs/cgroup/memory rw,nosuid,nodev,noexec,relatime shared:13 - cgroup cgroup rw,memory\n",
+			exp: expected{err: ErrCgroup2NotMounted},
+		},
+		"malformed": {
+			mounts: "412 28 0:35 cgroup2 -\n",
+			exp:    expected{err: ErrCgroup2NotMounted},
+		},
+		"empty": {
+			exp: expected{err: ErrCgroup2NotMounted},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path, err := findCgroup2Mount(test.mounts)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if path != test.exp.path {
+				t.Fatalf("unexpected path; actual: %v, expected: %v", path, test.exp.path)
+			}
+		})
+	}
+}
+
+func TestCleanupExistingMount(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	path, err := detectMountPath()
+	if errors.Is(err, ErrCgroup2NotMounted) {
+		t.Skip("cgroup2 must be mounted to run")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service, err := NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service.mountPath != path {
+		t.Fatalf("unexpected mount path; actual: %v, expected: %v", service.mountPath, path)
+	}
+
+	if err := service.Cleanup(); err != nil {
+		t.Fatalf("service cleanup; error: %s", err)
+	}
+
+	if after, err := detectMountPath(); err != nil || after != path {
+		t.Fatalf("expected cgroup2 to remain mounted; path: %s, error: %v", after, err)
+	}
+}
+
+func TestReadStats(t *testing.T) {
+	type expected struct {
+		stats *Stats
+		err   error
+	}
+	tests := map[string]struct {
+		files map[string]string
+		exp   expected
+	}{
+		"all stats": {
+			files: map[string]string{
+				memoryCurrent: "4096\n",
+				memoryPeak:    "8192\n",
+				cpuStat:       "usage_usec 1500\nuser_usec 1000\nsystem_usec 500\n",
+				ioStat:        "8:0 rbytes=100 wbytes=200 rios=1 wios=2 dbytes=0 dios=0\n8:16 rbytes=10 wbytes=20 rios=1 wios=1 dbytes=0 dios=0\n",
+				memoryEvents:  "low 0\nhigh 12\nmax 3\noom 1\noom_kill 1\n",
+			},
+			exp: expected{
+				stats: &Stats{
+					MemoryCurrent: 4096,
+					MemoryPeak:    8192,
+					CpuUsageUsec:  1500,
+					IoReadBytes:   110,
+					IoWriteBytes:  220,
+					OomKills:      1,
+				},
+			},
+		},
+		"missing memory.peak": {
+			files: map[string]string{
+				memoryCurrent: "4096\n",
+				cpuStat:       "usage_usec 1500\n",
+				ioStat:        "",
+			},
+			exp: expected{
+				stats: &Stats{MemoryCurrent: 4096, CpuUsageUsec: 1500},
+			},
+		},
+		"cgroup removed": {
+			exp: expected{err: ErrCgroupNotFound},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := Service{path: t.TempDir()}
+			cgroup := Cgroup{ID: uuid.New()}
+
+			if test.files != nil {
+				dir := filepath.Join(service.path, cgroup.ID.String())
+				if err := os.Mkdir(dir, fileMode); err != nil {
+					t.Fatal(err)
+				}
+				for file, content := range test.files {
+					if err := os.WriteFile(filepath.Join(dir, file), []byte(content), fileMode); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			stats, err := service.ReadStats(cgroup)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if !reflect.DeepEqual(stats, test.exp.stats) {
+				t.Fatalf("unexpected stats; actual: %+v, expected: %+v", stats, test.exp.stats)
+			}
+		})
+	}
+}
+
+func TestReadDetailedStats(t *testing.T) {
+	type expected struct {
+		stats *DetailedStats
+		err   error
+	}
+	tests := map[string]struct {
+		files map[string]string
+		exp   expected
+	}{
+		"all stats": {
+			files: map[string]string{
+				cpuPressure:    "some avg10=1.50 avg60=0.75 avg300=0.10 total=12000\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+				memoryPressure: "some avg10=0.00 avg60=0.00 avg300=0.00 total=10\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=5\n",
+				cpuStat:        "usage_usec 1500\nuser_usec 1000\nsystem_usec 500\n",
+				ioStat:         "8:0 rbytes=100 wbytes=200\n",
+			},
+			exp: expected{
+				stats: &DetailedStats{
+					CpuPressure: Pressure{
+						Some: PressureValues{Avg10: 1.5, Avg60: 0.75, Avg300: 0.1, Total: 12000},
+					},
+					MemoryPressure: Pressure{
+						Some: PressureValues{Total: 10},
+						Full: PressureValues{Total: 5},
+					},
+					IoStat:  map[string]map[string]uint64{"8:0": {"rbytes": 100, "wbytes": 200}},
+					CpuStat: map[string]uint64{"usage_usec": 1500, "user_usec": 1000, "system_usec": 500},
+				},
+			},
+		},
+		"pressure unavailable": {
+			files: map[string]string{
+				cpuStat: "usage_usec 1500\n",
+			},
+			exp: expected{
+				stats: &DetailedStats{
+					IoStat:  map[string]map[string]uint64{},
+					CpuStat: map[string]uint64{"usage_usec": 1500},
+				},
+			},
+		},
+		"malformed pressure": {
+			files: map[string]string{
+				cpuPressure: "some avg10=high\n",
+			},
+			exp: expected{err: ErrMalformedStat},
+		},
+		"cgroup removed": {
+			exp: expected{err: ErrCgroupNotFound},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := Service{path: t.TempDir()}
+			cgroup := Cgroup{ID: uuid.New()}
+
+			if test.files != nil {
+				dir := filepath.Join(service.path, cgroup.ID.String())
+				if err := os.Mkdir(dir, fileMode); err != nil {
+					t.Fatal(err)
+				}
+				for file, content := range test.files {
+					if err := os.WriteFile(filepath.Join(dir, file), []byte(content), fileMode); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			stats, err := service.ReadDetailedStats(cgroup)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if !reflect.DeepEqual(stats, test.exp.stats) {
+				t.Fatalf("unexpected stats; actual: %+v, expected: %+v", stats, test.exp.stats)
+			}
+		})
+	}
+}
+
+func TestParsePressure(t *testing.T) {
+	type expected struct {
+		pressure Pressure
+		err      error
+	}
+	tests := map[string]struct {
+		content string
+		exp     expected
+	}{
+		"some and full": {
+			content: "some avg10=0.25 avg60=1.00 avg300=12.34 total=567\nfull avg10=0.10 avg60=0.20 avg300=0.30 total=89\n",
+			exp: expected{pressure: Pressure{
+				Some: PressureValues{Avg10: 0.25, Avg60: 1, Avg300: 12.34, Total: 567},
+				Full: PressureValues{Avg10: 0.1, Avg60: 0.2, Avg300: 0.3, Total: 89},
+			}},
+		},
+		"some only": {
+			content: "some avg10=0.00 avg60=0.00 avg300=0.00 total=42\n",
+			exp:     expected{pressure: Pressure{Some: PressureValues{Total: 42}}},
+		},
+		"empty": {
+			content: "",
+		},
+		"unknown kind": {
+			content: "most avg10=0.00\n",
+			exp:     expected{err: ErrMalformedStat},
+		},
+		"missing value": {
+			content: "some avg10\n",
+			exp:     expected{err: ErrMalformedStat},
+		},
+		"malformed total": {
+			content: "some total=-1\n",
+			exp:     expected{err: ErrMalformedStat},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			pressure, err := parsePressure(test.content)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if pressure != test.exp.pressure {
+				t.Fatalf("unexpected pressure; actual: %+v, expected: %+v", pressure, test.exp.pressure)
+			}
+		})
+	}
+}
+
+func TestParseFlatKeyed(t *testing.T) {
+	type expected struct {
+		values map[string]uint64
+		err    error
+	}
+	tests := map[string]struct {
+		content string
+		exp     expected
+	}{
+		"cpu.stat": {
+			content: "usage_usec 1500\nuser_usec 1000\nsystem_usec 500\nnr_periods 0\n",
+			exp: expected{values: map[string]uint64{
+				"usage_usec":  1500,
+				"user_usec":   1000,
+				"system_usec": 500,
+				"nr_periods":  0,
+			}},
+		},
+		"empty": {
+			content: "",
+			exp:     expected{values: map[string]uint64{}},
+		},
+		"nested line": {
+			content: "8:0 rbytes=100 wbytes=200\n",
+			exp:     expected{err: ErrMalformedStat},
+		},
+		"malformed value": {
+			content: "usage_usec many\n",
+			exp:     expected{err: ErrMalformedStat},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			values, err := parseFlatKeyed(test.content)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if !reflect.DeepEqual(values, test.exp.values) {
+				t.Fatalf("unexpected values; actual: %v, expected: %v", values, test.exp.values)
+			}
+		})
+	}
+}
+
+func TestParseNestedKeyed(t *testing.T) {
+	type expected struct {
+		values map[string]map[string]uint64
+		err    error
+	}
+	tests := map[string]struct {
+		content string
+		exp     expected
+	}{
+		"io.stat": {
+			content: "8:0 rbytes=100 wbytes=200 rios=1 wios=2 dbytes=0 dios=0\n8:16 rbytes=10 wbytes=20 rios=1 wios=1 dbytes=0 dios=0\n",
+			exp: expected{values: map[string]map[string]uint64{
+				"8:0":  {"rbytes": 100, "wbytes": 200, "rios": 1, "wios": 2, "dbytes": 0, "dios": 0},
+				"8:16": {"rbytes": 10, "wbytes": 20, "rios": 1, "wios": 1, "dbytes": 0, "dios": 0},
+			}},
+		},
+		"empty": {
+			content: "",
+			exp:     expected{values: map[string]map[string]uint64{}},
+		},
+		"flat line": {
+			content: "usage_usec 1500\n",
+			exp:     expected{err: ErrMalformedStat},
+		},
+		"malformed value": {
+			content: "8:0 rbytes=lots\n",
+			exp:     expected{err: ErrMalformedStat},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			values, err := parseNestedKeyed(test.content)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if !reflect.DeepEqual(values, test.exp.values) {
+				t.Fatalf("unexpected values; actual: %v, expected: %v", values, test.exp.values)
+			}
+		})
+	}
+}
+
 func readControllers(dir string) ([]string, error) {
 	fd, err := os.Open(filepath.Join(dir, cgroupSubtreeControl))
 	if err != nil {
@@ -324,18 +1044,11 @@ func readPids(cgroupPath string) ([]int, error) {
 }
 
 func ioMaxValue(t *testing.T, key, value string) string {
-	minors, err := device.ReadDeviceMinors(diskDevices, diskPhysicalMinors)
+	dev, err := Service{}.diskDevice()
 	if err != nil {
-		t.Fatal(t)
-	}
-
-	var max uint32
-	for _, minor := range minors {
-		if minor > max {
-			max = minor
-		}
+		t.Fatal(err)
 	}
-	return fmt.Sprintf("%d:%d %s=%s", diskDevices, max, key, value)
+	return fmt.Sprintf("%s %s=%s", dev, key, value)
 }
 
 func isRoot() bool {
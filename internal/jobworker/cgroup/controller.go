@@ -5,8 +5,6 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
-
-	"github.com/tjper/teleport/internal/device"
 )
 
 // newCpuController creates a cpuController instance.
@@ -36,6 +34,28 @@ func (c cpuController) apply() error {
 	return nil
 }
 
+// newCPUWeightController creates a cpuWeightController instance.
+func newCPUWeightController(cgroup Cgroup, weight uint64) *cpuWeightController {
+	return &cpuWeightController{
+		baseController: baseController{name: cpu, cgroup: cgroup},
+		weight:         weight,
+	}
+}
+
+// cpuWeightController enables and applies the "cpu.weight" control.
+type cpuWeightController struct {
+	baseController
+	weight uint64
+}
+
+func (c cpuWeightController) apply() error {
+	weight := strconv.FormatUint(c.weight, 10)
+	if err := c.baseController.apply(cpuWeight, weight); err != nil {
+		return err
+	}
+	return nil
+}
+
 // newMemoryController creates a memoryController instance.
 func newMemoryController(cgroup Cgroup, limit uint64) *memoryController {
 	return &memoryController{
@@ -58,6 +78,90 @@ func (c memoryController) apply() error {
 	return nil
 }
 
+// newMemoryMaxController creates a memoryMaxController instance.
+func newMemoryMaxController(cgroup Cgroup, limit uint64) *memoryMaxController {
+	return &memoryMaxController{
+		baseController: baseController{name: memory, cgroup: cgroup},
+		limit:          limit,
+	}
+}
+
+// memoryMaxController enables and applies the "memory.max" control.
+type memoryMaxController struct {
+	baseController
+	limit uint64
+}
+
+func (c memoryMaxController) apply() error {
+	limit := strconv.FormatUint(c.limit, 10)
+	if err := c.baseController.apply(memoryMax, limit); err != nil {
+		return err
+	}
+	return nil
+}
+
+// newOomGroupController creates an oomGroupController instance.
+func newOomGroupController(cgroup Cgroup) *oomGroupController {
+	return &oomGroupController{
+		baseController: baseController{name: memory, cgroup: cgroup},
+	}
+}
+
+// oomGroupController enables and applies the "memory.oom.group" control.
+type oomGroupController struct {
+	baseController
+}
+
+func (c oomGroupController) apply() error {
+	if err := c.baseController.apply(memoryOomGroup, "1"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// newPidsController creates a pidsController instance.
+func newPidsController(cgroup Cgroup, limit uint64) *pidsController {
+	return &pidsController{
+		baseController: baseController{name: pids, cgroup: cgroup},
+		limit:          limit,
+	}
+}
+
+// pidsController enables and applies the "pids.max" control.
+type pidsController struct {
+	baseController
+	limit uint64
+}
+
+func (c pidsController) apply() error {
+	limit := strconv.FormatUint(c.limit, 10)
+	if err := c.baseController.apply(pidsMax, limit); err != nil {
+		return err
+	}
+	return nil
+}
+
+// newCpusetController creates a cpusetController instance.
+func newCpusetController(cgroup Cgroup, cpus string) *cpusetController {
+	return &cpusetController{
+		baseController: baseController{name: cpuset, cgroup: cgroup},
+		cpus:           cpus,
+	}
+}
+
+// cpusetController enables and applies the "cpuset.cpus" control.
+type cpusetController struct {
+	baseController
+	cpus string
+}
+
+func (c cpusetController) apply() error {
+	if err := c.baseController.apply(cpusetCpus, c.cpus); err != nil {
+		return err
+	}
+	return nil
+}
+
 // diskReadBpsController enables and appplies the rbps "io.max" control.
 type diskReadBpsController struct {
 	baseController
@@ -65,16 +169,14 @@ type diskReadBpsController struct {
 }
 
 func (c diskReadBpsController) apply() error {
-	minors, err := device.ReadDeviceMinors(diskDevices, diskPhysicalMinors)
+	dev, err := c.cgroup.service.diskDevice()
 	if err != nil {
 		return err
 	}
 
-	for _, minor := range minors {
-		value := fmt.Sprintf("%d:%d rbps=%d", diskDevices, minor, c.limit)
-		if err := c.baseController.apply(ioMax, value); err != nil {
-			return err
-		}
+	value := fmt.Sprintf("%s rbps=%d", dev, c.limit)
+	if err := c.baseController.apply(ioMax, value); err != nil {
+		return err
 	}
 	return nil
 }
@@ -102,16 +204,14 @@ type diskWriteBpsController struct {
 }
 
 func (c diskWriteBpsController) apply() error {
-	minors, err := device.ReadDeviceMinors(diskDevices, diskPhysicalMinors)
+	dev, err := c.cgroup.service.diskDevice()
 	if err != nil {
 		return err
 	}
 
-	for _, minor := range minors {
-		value := fmt.Sprintf("%d:%d wbps=%d", diskDevices, minor, c.limit)
-		if err := c.baseController.apply(ioMax, value); err != nil {
-			return err
-		}
+	value := fmt.Sprintf("%s wbps=%d", dev, c.limit)
+	if err := c.baseController.apply(ioMax, value); err != nil {
+		return err
 	}
 	return nil
 }
@@ -146,10 +246,6 @@ func (c baseController) apply(control, value string) error {
 }
 
 const (
-	// diskDevices is major number for disk devices.
-	diskDevices = 8
-	// diskPhysicalMinors is the numbers between disk device minor numbers.
-	diskPhysicalMinors = 16
 	// controllersSubtreeControl is the name of the file that contains all
 	// enabled controllers within a cgroup.
 	cgroupSubtreeControl = "cgroup.subtree_control"
@@ -159,10 +255,38 @@ const (
 	memory = "memory"
 	// io is the cgroup io controller name.
 	io = "io"
+	// pids is the cgroup pids controller name.
+	pids = "pids"
+	// cpuset is the cgroup cpuset controller name.
+	cpuset = "cpuset"
 	// memoryHigh is the memory.high cgroup control.
 	memoryHigh = "memory.high"
+	// memoryMax is the memory.max cgroup control.
+	memoryMax = "memory.max"
+	// memoryOomGroup is the memory.oom.group cgroup control.
+	memoryOomGroup = "memory.oom.group"
 	// cpuMax is the cpu.max cgroup control.
 	cpuMax = "cpu.max"
+	// cpuWeight is the cpu.weight cgroup control.
+	cpuWeight = "cpu.weight"
 	// ioMax is the io.max cgroup control.
 	ioMax = "io.max"
+	// pidsMax is the pids.max cgroup control.
+	pidsMax = "pids.max"
+	// cpusetCpus is the cpuset.cpus cgroup control.
+	cpusetCpus = "cpuset.cpus"
+	// memoryCurrent is the memory.current cgroup interface file.
+	memoryCurrent = "memory.current"
+	// memoryPeak is the memory.peak cgroup interface file.
+	memoryPeak = "memory.peak"
+	// memoryEvents is the memory.events cgroup interface file.
+	memoryEvents = "memory.events"
+	// cpuStat is the cpu.stat cgroup interface file.
+	cpuStat = "cpu.stat"
+	// ioStat is the io.stat cgroup interface file.
+	ioStat = "io.stat"
+	// cpuPressure is the cpu.pressure cgroup interface file.
+	cpuPressure = "cpu.pressure"
+	// memoryPressure is the memory.pressure cgroup interface file.
+	memoryPressure = "memory.pressure"
 )
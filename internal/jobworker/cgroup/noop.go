@@ -0,0 +1,53 @@
+package cgroup
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrFreezeUnsupported indicates a Cgroup could not be frozen because cgroups
+// are not in use.
+var ErrFreezeUnsupported = errors.New("freeze unsupported")
+
+// NoopService mimics Service without interacting with cgroups; Cgroups are not
+// created and their limits are not applied. NoopService allows Jobs to be run
+// on hosts without cgroup2 or root access, e.g. for development.
+type NoopService struct{}
+
+// CreateCgroup creates a Cgroup instance configured by options. No cgroup is
+// created on the host system.
+func (NoopService) CreateCgroup(options ...CgroupOption) (*Cgroup, error) {
+	cgroup := &Cgroup{ID: uuid.New()}
+	for _, option := range options {
+		option(cgroup)
+	}
+	return cgroup, nil
+}
+
+// PlaceInCgroup does nothing; the process is left in its current cgroup.
+func (NoopService) PlaceInCgroup(Cgroup, int) error {
+	return nil
+}
+
+// Freeze returns ErrFreezeUnsupported, as processes are not within a cgroup
+// that may be frozen.
+func (NoopService) Freeze(Cgroup, bool) error {
+	return ErrFreezeUnsupported
+}
+
+// RemoveCgroup does nothing.
+func (NoopService) RemoveCgroup(uuid.UUID) error {
+	return nil
+}
+
+// ReadStats returns ErrCgroupNotFound, as no cgroup exists on the host system.
+func (NoopService) ReadStats(cgroup Cgroup) (*Stats, error) {
+	return nil, ErrCgroupNotFound
+}
+
+// ReadDetailedStats returns ErrCgroupNotFound, as no cgroup exists on the host
+// system.
+func (NoopService) ReadDetailedStats(cgroup Cgroup) (*DetailedStats, error) {
+	return nil, ErrCgroupNotFound
+}
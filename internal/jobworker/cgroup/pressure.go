@@ -0,0 +1,246 @@
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrMalformedStat indicates a cgroup interface file is not formatted as
+// expected.
+var ErrMalformedStat = errors.New("malformed stat")
+
+// DetailedStats are the raw pressure and usage statistics of a Cgroup.
+// Statistics whose interface file does not exist, e.g. "cpu.pressure" on
+// kernels without PSI enabled, are zeroed.
+type DetailedStats struct {
+	// CpuPressure is the "cpu.pressure" CPU pressure stall information.
+	CpuPressure Pressure
+	// MemoryPressure is the "memory.pressure" memory pressure stall
+	// information.
+	MemoryPressure Pressure
+	// IoStat is the "io.stat" statistics of each device, keyed by the
+	// device's "major:minor" number and then by statistic, e.g. "rbytes".
+	IoStat map[string]map[string]uint64
+	// CpuStat is the "cpu.stat" statistics, keyed by statistic, e.g.
+	// "usage_usec".
+	CpuStat map[string]uint64
+}
+
+// Pressure is the pressure stall information of a resource. Some is the share
+// of time at least one task was stalled on the resource, and Full is the share
+// of time all non-idle tasks were stalled simultaneously.
+type Pressure struct {
+	Some PressureValues
+	Full PressureValues
+}
+
+// PressureValues are the pressure stall averages and total of a resource.
+type PressureValues struct {
+	// Avg10 is the percentage of time stalled over the last 10 seconds.
+	Avg10 float64
+	// Avg60 is the percentage of time stalled over the last 60 seconds.
+	Avg60 float64
+	// Avg300 is the percentage of time stalled over the last 300 seconds.
+	Avg300 float64
+	// Total is the total time stalled in microseconds.
+	Total uint64
+}
+
+// ReadDetailedStats reads the raw pressure and usage statistics of the cgroup.
+// If the cgroup does not exist, ErrCgroupNotFound is returned.
+func (s Service) ReadDetailedStats(cgroup Cgroup) (*DetailedStats, error) {
+	cgroup.path = filepath.Join(s.path, cgroup.ID.String())
+	return cgroup.readDetailedStats()
+}
+
+// readDetailedStats reads the raw pressure and usage statistics of the Cgroup.
+func (c Cgroup) readDetailedStats() (*DetailedStats, error) {
+	if _, err := os.Stat(c.path); errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w; path: %s", ErrCgroupNotFound, c.path)
+	}
+
+	stats := DetailedStats{
+		IoStat:  make(map[string]map[string]uint64),
+		CpuStat: make(map[string]uint64),
+	}
+
+	files := []struct {
+		control string
+		parse   func(string) error
+	}{
+		{
+			control: cpuPressure,
+			parse: func(content string) (err error) {
+				stats.CpuPressure, err = parsePressure(content)
+				return err
+			},
+		},
+		{
+			control: memoryPressure,
+			parse: func(content string) (err error) {
+				stats.MemoryPressure, err = parsePressure(content)
+				return err
+			},
+		},
+		{
+			control: ioStat,
+			parse: func(content string) (err error) {
+				stats.IoStat, err = parseNestedKeyed(content)
+				return err
+			},
+		},
+		{
+			control: cpuStat,
+			parse: func(content string) (err error) {
+				stats.CpuStat, err = parseFlatKeyed(content)
+				return err
+			},
+		},
+	}
+	for _, f := range files {
+		if err := c.parseFile(f.control, f.parse); err != nil {
+			return nil, err
+		}
+	}
+
+	return &stats, nil
+}
+
+// parseFile reads the interface file control and passes its content to parse.
+// If control does not exist, parse is not called.
+func (c Cgroup) parseFile(control string, parse func(string) error) error {
+	file := filepath.Join(c.path, control)
+
+	b, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %w", file, err)
+	}
+
+	if err := parse(string(b)); err != nil {
+		return fmt.Errorf("parse %s: %w", file, err)
+	}
+	return nil
+}
+
+// parsePressure parses a pressure stall information formatted content, consisting of
+// "some" and "full" lines, e.g. "some avg10=0.00 avg60=0.00 avg300=0.00
+// total=0". "cpu.pressure" on older kernels reports only the "some" line.
+func parsePressure(content string) (Pressure, error) {
+	var pressure Pressure
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var values *PressureValues
+		switch fields[0] {
+		case "some":
+			values = &pressure.Some
+		case "full":
+			values = &pressure.Full
+		default:
+			return Pressure{}, fmt.Errorf("%w; line: %q", ErrMalformedStat, scanner.Text())
+		}
+
+		for _, field := range fields[1:] {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				return Pressure{}, fmt.Errorf("%w; field: %q", ErrMalformedStat, field)
+			}
+
+			var err error
+			switch parts[0] {
+			case "avg10":
+				values.Avg10, err = strconv.ParseFloat(parts[1], 64)
+			case "avg60":
+				values.Avg60, err = strconv.ParseFloat(parts[1], 64)
+			case "avg300":
+				values.Avg300, err = strconv.ParseFloat(parts[1], 64)
+			case "total":
+				values.Total, err = strconv.ParseUint(parts[1], 10, 64)
+			}
+			if err != nil {
+				return Pressure{}, fmt.Errorf("%w; field: %q", ErrMalformedStat, field)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Pressure{}, err
+	}
+
+	return pressure, nil
+}
+
+// parseFlatKeyed parses a flat keyed formatted content, consisting of "key value"
+// lines, e.g. "usage_usec 1500".
+func parseFlatKeyed(content string) (map[string]uint64, error) {
+	values := make(map[string]uint64)
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%w; line: %q", ErrMalformedStat, scanner.Text())
+		}
+
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w; line: %q", ErrMalformedStat, scanner.Text())
+		}
+		values[fields[0]] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// parseNestedKeyed parses a nested keyed formatted content, consisting of
+// "name key=value ..." lines, e.g. "8:0 rbytes=100 wbytes=200". The values are
+// keyed by the leading name of each line and then by key.
+func parseNestedKeyed(content string) (map[string]map[string]uint64, error) {
+	values := make(map[string]map[string]uint64)
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		nested := make(map[string]uint64)
+		for _, field := range fields[1:] {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("%w; field: %q", ErrMalformedStat, field)
+			}
+			value, err := strconv.ParseUint(parts[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w; field: %q", ErrMalformedStat, field)
+			}
+			nested[parts[0]] = value
+		}
+		values[fields[0]] = nested
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
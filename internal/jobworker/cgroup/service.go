@@ -2,13 +2,16 @@
 package cgroup
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/tjper/teleport/internal/device"
 	"github.com/tjper/teleport/internal/log"
 
 	"github.com/google/uuid"
@@ -18,30 +21,81 @@ import (
 // logger is an object for logging package events to stdout.
 var logger = log.New(os.Stdout, "cgroups")
 
-// NewService creates a Service instance.
-func NewService(options ...ServiceOption) (*Service, error) {
-	s := &Service{
-		mountPath: mountPath,
+// CleanupError is returned by Service.Cleanup when cgroups could not be
+// removed. Each error describes a cgroup that could not be removed.
+type CleanupError []error
+
+func (e CleanupError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("cleanup %d cgroups failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Is checks if any of the errors match target.
+func (e CleanupError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
 	}
+	return false
+}
+
+// ErrCgroup2NotMounted indicates the cgroup2 filesystem is not mounted on the
+// host system.
+var ErrCgroup2NotMounted = errors.New("cgroup2 not mounted")
+
+// NewService creates a Service instance. By default, Service uses an existing
+// cgroup2 mount point, see WithMountPath. If cgroup2 is not mounted, it is
+// mounted on /cgroup2.
+func NewService(options ...ServiceOption) (*Service, error) {
+	s := &Service{}
 	for _, option := range options {
 		option(s)
 	}
 
+	if s.mountPath == "" {
+		path, err := detectMountPath()
+		switch {
+		case errors.Is(err, ErrCgroup2NotMounted):
+			s.mountPath = mountPath
+		case err != nil:
+			return nil, err
+		default:
+			logger.Infof("Using existing cgroup2 mount; path: %s", path)
+			s.mountPath = path
+		}
+	}
+
 	s.path = filepath.Join(s.mountPath, jobWorkerBase)
 
-	if err := s.mount(); err != nil {
+	mounted, err := s.mount()
+	if err != nil {
 		return nil, err
 	}
+	s.mounted = mounted
 
 	controllers := []string{
 		cpu,
 		memory,
-		io,
+		pids,
 	}
 	if err := s.enableControllers(controllers); err != nil {
 		return nil, err
 	}
 
+	// io delegation is unavailable on some hosts. Cgroups without disk limits
+	// do not require io, so only requests for disk limits fail without it.
+	if err := s.enableControllers([]string{io}); err != nil {
+		logger.Warnf("io controller unavailable, disk limits unsupported; error: %v", err)
+	}
+	// Likewise, only requests for cpu sets require cpuset.
+	if err := s.enableControllers([]string{cpuset}); err != nil {
+		logger.Warnf("cpuset controller unavailable, cpu sets unsupported; error: %v", err)
+	}
+
 	return s, nil
 }
 
@@ -50,17 +104,39 @@ func NewService(options ...ServiceOption) (*Service, error) {
 type Service struct {
 	mountPath string
 	path      string
+	// mounted indicates Service mounted the cgroup2 filesystem, and should
+	// unmount it on Cleanup.
+	mounted bool
+	// diskPath is the path whose block device disk limits are applied to.
+	diskPath string
+	// removeTimeout is the maximum duration removing a busy cgroup is retried
+	// for. If zero, defaultRemoveTimeout is used.
+	removeTimeout time.Duration
 }
 
 // ServiceOption mutates the Service instance. This is typically used for
 // configuration with NewService.
 type ServiceOption func(*Service)
 
-// WithMountPath configures the Service instance to mount cgroup2 on mountPath.
+// WithMountPath configures the Service instance to mount cgroup2 on mountPath,
+// rather than using an existing cgroup2 mount point. If cgroup2 is already
+// mounted on mountPath, it is used as is.
 func WithMountPath(mountPath string) ServiceOption {
 	return func(s *Service) { s.mountPath = mountPath }
 }
 
+// WithDiskPath configures the Service instance to apply disk limits to the
+// block device path resides on. By default, the block device of "/" is used.
+func WithDiskPath(path string) ServiceOption {
+	return func(s *Service) { s.diskPath = path }
+}
+
+// WithRemoveTimeout configures the Service instance to retry removing a busy
+// cgroup for up to timeout. By default, removal is retried for 5 seconds.
+func WithRemoveTimeout(timeout time.Duration) ServiceOption {
+	return func(s *Service) { s.removeTimeout = timeout }
+}
+
 // CreateCgroup creates a new Service Cgroup. CgroupOptions may be specified to
 // configure the Cgroup. On success, the created Cgroup is returned to the
 // caller.
@@ -91,6 +167,16 @@ func (s Service) PlaceInCgroup(cgroup Cgroup, pid int) error {
 	return cgroup.placePID(pid)
 }
 
+// Freeze freezes the Service cgroup specified if frozen is true, suspending
+// the processes within it. Otherwise, the cgroup is thawed and its processes
+// resume. Freezing completes asynchronously, shortly after Freeze returns.
+func (s Service) Freeze(cgroup Cgroup, frozen bool) error {
+	logger.Infof("Freezing Cgroup; ID: %v, frozen: %v", cgroup.ID, frozen)
+
+	cgroup.path = filepath.Join(s.path, cgroup.ID.String())
+	return cgroup.freeze(frozen)
+}
+
 // RemoveCgroup removes the jobworker cgroup uniquely identified by the
 // specified id.
 func (s Service) RemoveCgroup(id uuid.UUID) error {
@@ -102,13 +188,20 @@ func (s Service) RemoveCgroup(id uuid.UUID) error {
 }
 
 // Cleanup removes all jobworker Service resources. Whenever a Service instance
-// is used, Cleanup should always be called before application close.
+// is used, Cleanup should always be called before application close. If any
+// cgroups cannot be removed, the remaining cgroups are still removed and a
+// CleanupError is returned.
 func (s Service) Cleanup() error {
 	logger.Infof("Cleaning up jobworker Service Cgroups")
 	if err := s.cleanup(); err != nil {
 		return err
 	}
 
+	// Leave filesystems Service did not mount to their owner.
+	if !s.mounted {
+		return nil
+	}
+
 	if err := s.unmount(); err != nil {
 		return err
 	}
@@ -131,28 +224,30 @@ func (s Service) placeInRootCgroup(pids []int) error {
 }
 
 // mount setups the cgroup2 filesystem and creates a cgroup dedicated to
-// jobworker cgroups.
-func (s Service) mount() error {
+// jobworker cgroups. mount returns true if it mounted the cgroup2 filesystem.
+func (s Service) mount() (bool, error) {
 	// Ensure path to cgroup2 mount point exists.
 	if err := os.MkdirAll(s.mountPath, fileMode); err != nil {
-		return fmt.Errorf("mount service %s: %w", s.mountPath, err)
+		return false, fmt.Errorf("mount service %s: %w", s.mountPath, err)
 	}
 
 	// If the mount path does not exist or has no entries, mount the cgroup2
 	// filesystem.
+	var mounted bool
 	entries, err := os.ReadDir(s.mountPath)
 	if err != nil || len(entries) == 0 {
 		if err := s.mountCgroup2(); err != nil {
-			return err
+			return false, err
 		}
+		mounted = true
 	}
 
 	// cgroup2 filesystem is mounted, ensure jobworker base directory exists.
 	if err := os.MkdirAll(s.path, fileMode); err != nil {
-		return fmt.Errorf("create jobworker cgroup: %w", err)
+		return mounted, fmt.Errorf("create jobworker cgroup: %w", err)
 	}
 
-	return nil
+	return mounted, nil
 }
 
 // mountCgroup2 mounts cgroup2 to the Service mountPath.
@@ -163,6 +258,59 @@ func (s Service) mountCgroup2() error {
 	return nil
 }
 
+// detectMountPath retrieves the mount point of an existing cgroup2 filesystem.
+// If cgroup2 is not mounted, ErrCgroup2NotMounted is returned.
+func detectMountPath() (string, error) {
+	b, err := os.ReadFile(procMountinfo)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", procMountinfo, err)
+	}
+
+	return findCgroup2Mount(string(b))
+}
+
+// findCgroup2Mount retrieves the mount point of the first cgroup2 filesystem
+// in the /proc/self/mountinfo formatted mounts. If there is no cgroup2
+// filesystem, ErrCgroup2NotMounted is returned.
+func findCgroup2Mount(mounts string) (string, error) {
+	for _, line := range strings.Split(mounts, "\n") {
+		// e.g. "30 23 0:26 / /sys/fs/cgroup rw,nosuid shared:4 - cgroup2 cgroup2 rw"
+		//
+		// The optional fields preceding the "-" separator vary in number, so
+		// the filesystem type is located relative to the separator.
+		fields := strings.Fields(line)
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 5 || sep+1 >= len(fields) || fields[sep+1] != "cgroup2" {
+			continue
+		}
+		return unescapeMountPath(fields[4]), nil
+	}
+	return "", ErrCgroup2NotMounted
+}
+
+// unescapeMountPath replaces the octal escapes of a mountinfo mount point,
+// e.g. "\040" for a space, with the characters they represent.
+func unescapeMountPath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if c, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
 // cleanup walks the Service base directory, moving all jobworker pids into the
 // root cgroup and removing the each cgroup directory.
 func (s Service) cleanup() error {
@@ -208,21 +356,35 @@ func (s Service) cleanup() error {
 		return fmt.Errorf("cleanup jobworker cgroup: %w", err)
 	}
 
-	// Remove all jobworker sub cgroups.
+	// Remove all jobworker sub cgroups. A cgroup that cannot be removed does
+	// not prevent the others from being removed.
+	var errs CleanupError
 	for _, cgroup := range cgroups {
 		if err := s.RemoveCgroup(cgroup); err != nil {
-			return err
+			errs = append(errs, fmt.Errorf("remove cgroup %s: %w", cgroup, err))
 		}
 	}
 
 	// Remove root jobworker cgroup.
 	if err := unix.Rmdir(s.path); err != nil {
-		return fmt.Errorf("rm jobworker cgroup: %w", err)
+		errs = append(errs, fmt.Errorf("rm jobworker cgroup: %w", err))
 	}
 
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
 
+// retryTimeout retrieves the maximum duration removing a busy cgroup is
+// retried for.
+func (s Service) retryTimeout() time.Duration {
+	if s.removeTimeout == 0 {
+		return defaultRemoveTimeout
+	}
+	return s.removeTimeout
+}
+
 // unmount unmounts the cgroup2 filesystem.
 func (s Service) unmount() error {
 	if err := unix.Unmount(s.mountPath, 0); err != nil {
@@ -231,6 +393,15 @@ func (s Service) unmount() error {
 	return nil
 }
 
+// diskDevice retrieves the block device disk limits are applied to.
+func (s Service) diskDevice() (device.Device, error) {
+	path := s.diskPath
+	if path == "" {
+		path = defaultDiskPath
+	}
+	return device.BlockDevice(path)
+}
+
 // enableControllers enables the passed controllers for the root and jobworker
 // cgroup.
 func (s Service) enableControllers(controllers []string) error {
@@ -261,8 +432,18 @@ const (
 	// fileMode are the file permissions the jobworker package will use when
 	// accessing files.
 	fileMode = 0644
-	// mountPath is the path the cgroup2 filesystem will be mounted on.
+	// mountPath is the path the cgroup2 filesystem will be mounted on if it is
+	// not already mounted.
 	mountPath = "/cgroup2"
+	// defaultDiskPath is the path whose block device disk limits are applied
+	// to, if not configured.
+	defaultDiskPath = "/"
+	// defaultRemoveTimeout is the maximum duration removing a busy cgroup is
+	// retried for, if not configured.
+	defaultRemoveTimeout = 5 * time.Second
+	// procMountinfo is the path of the file listing the mounts of the
+	// process's mount namespace.
+	procMountinfo = "/proc/self/mountinfo"
 	// jobWorkerBase is the directory name the jobworker cgroups will exist
 	// within.
 	jobWorkerBase = "jobworker"
@@ -0,0 +1,155 @@
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrCgroupNotFound indicates the cgroup does not exist on the host system.
+var ErrCgroupNotFound = errors.New("cgroup not found")
+
+// Stats are the resource usage statistics of a Cgroup. Statistics whose
+// interface file does not exist, e.g. "memory.peak" on older kernels, are
+// zeroed.
+type Stats struct {
+	// MemoryCurrent is the "memory.current" bytes in use by the cgroup.
+	MemoryCurrent uint64
+	// MemoryPeak is the "memory.peak" bytes used by the cgroup.
+	MemoryPeak uint64
+	// CpuUsageUsec is the "cpu.stat" usage_usec CPU time consumed by the
+	// cgroup in microseconds.
+	CpuUsageUsec uint64
+	// IoReadBytes is the "io.stat" rbytes read by the cgroup, summed across
+	// devices.
+	IoReadBytes uint64
+	// IoWriteBytes is the "io.stat" wbytes written by the cgroup, summed across
+	// devices.
+	IoWriteBytes uint64
+	// OomKills is the "memory.events" oom_kill number of processes in the
+	// cgroup killed by the OOM killer.
+	OomKills uint64
+}
+
+// ReadStats reads the resource usage statistics of the cgroup. If the cgroup
+// does not exist, ErrCgroupNotFound is returned.
+func (s Service) ReadStats(cgroup Cgroup) (*Stats, error) {
+	cgroup.path = filepath.Join(s.path, cgroup.ID.String())
+	return cgroup.readStats()
+}
+
+// readStats reads the resource usage statistics of the Cgroup.
+func (c Cgroup) readStats() (*Stats, error) {
+	if _, err := os.Stat(c.path); errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w; path: %s", ErrCgroupNotFound, c.path)
+	}
+
+	var (
+		stats Stats
+		err   error
+	)
+	if stats.MemoryCurrent, err = c.readUint(memoryCurrent); err != nil {
+		return nil, err
+	}
+	if stats.MemoryPeak, err = c.readUint(memoryPeak); err != nil {
+		return nil, err
+	}
+
+	cpuValues, err := c.readKeyed(cpuStat)
+	if err != nil {
+		return nil, err
+	}
+	stats.CpuUsageUsec = cpuValues["usage_usec"]
+
+	ioValues, err := c.readKeyed(ioStat)
+	if err != nil {
+		return nil, err
+	}
+	stats.IoReadBytes = ioValues["rbytes"]
+	stats.IoWriteBytes = ioValues["wbytes"]
+
+	memoryValues, err := c.readKeyed(memoryEvents)
+	if err != nil {
+		return nil, err
+	}
+	stats.OomKills = memoryValues["oom_kill"]
+
+	return &stats, nil
+}
+
+// readUint reads the single value interface file control. If control does not
+// exist, 0 is returned.
+func (c Cgroup) readUint(control string) (uint64, error) {
+	file := filepath.Join(c.path, control)
+
+	b, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read %s: %w", file, err)
+	}
+
+	value, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", file, err)
+	}
+
+	return value, nil
+}
+
+// readKeyed reads the keyed interface file control, summing the values of
+// each key. Both flat keyed ("key value") and nested keyed
+// ("device key=value ...") lines are supported. If control does not exist, an
+// empty map is returned.
+func (c Cgroup) readKeyed(control string) (map[string]uint64, error) {
+	file := filepath.Join(c.path, control)
+	values := make(map[string]uint64)
+
+	fd, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", file, err)
+	}
+	defer fd.Close()
+
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		// Flat keyed line.
+		if len(fields) == 2 && !strings.Contains(fields[1], "=") {
+			value, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse %s: %w", file, err)
+			}
+			values[fields[0]] += value
+			continue
+		}
+
+		// Nested keyed line, the leading field identifies the device.
+		for _, field := range fields[1:] {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			value, err := strconv.ParseUint(parts[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse %s: %w", file, err)
+			}
+			values[parts[0]] += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan %s: %w", file, err)
+	}
+
+	return values, nil
+}
@@ -9,14 +9,40 @@ import (
 	"strings"
 
 	"github.com/tjper/teleport/internal/jobworker"
+	igrpc "github.com/tjper/teleport/internal/jobworker/grpc"
+	"github.com/tjper/teleport/internal/jobworker/output"
 	"github.com/tjper/teleport/internal/log"
 )
 
 var (
-	keyFlag    = flag.String("key", "", "path to server private key")
-	certFlag   = flag.String("cert", "", "path to server certificate")
-	caCertFlag = flag.String("ca_cert", "", "path to CA certificate")
-	portFlag   = flag.Int("port", 8080, "port to serve jobworker API")
+	keyFlag             = flag.String("key", "", "path to server private key")
+	certFlag            = flag.String("cert", "", "path to server certificate, reloaded with key once either file changes")
+	caCertFlag          = flag.String("ca_cert", "", "comma-separated paths to CA certificates, or directories of them, client certificates may be signed by")
+	crlFlag             = flag.String("crl", "", "path to a certificate revocation list of revoked client certificates, reloaded once modified or on SIGHUP; empty disables revocation checks")
+	portFlag            = flag.Int("port", 8080, "port to serve jobworker API")
+	reflectionFlag      = flag.Bool("reflection", false, "register gRPC server reflection, exposing the API schema to authenticated clients")
+	envAllowFlag        = flag.String("env_allow", "", "comma-separated environment variable keys clients may set; empty allows all keys not denied")
+	envDenyFlag         = flag.String("env_deny", strings.Join(igrpc.DefaultEnvDeny, ","), "comma-separated environment variable keys clients may not set")
+	envStripFlag        = flag.Bool("env_strip", false, "strip denied environment variables rather than rejecting the request")
+	tlsMinFlag          = flag.String("tls_min_version", "1.3", "minimum TLS version accepted, \"1.2\" or \"1.3\"")
+	tlsCipherFlag       = flag.String("tls_cipher_suites", "", "comma-separated TLS 1.2 cipher suites accepted; empty accepts Go's secure defaults")
+	tlsCurvesFlag       = flag.String("tls_curves", "", "comma-separated elliptic curves used for key exchange, in order of preference; empty uses Go's defaults")
+	maxJobsFlag         = flag.Int("max_jobs", 0, "maximum number of jobs running at once; 0 is unlimited")
+	jobsPerUserFlag     = flag.Int("jobs_per_user", 0, "maximum number of jobs each user may run at once; 0 is unlimited")
+	diskPathFlag        = flag.String("disk_path", "/", "path whose block device job disk limits are applied to")
+	disableCgroupsFlag  = flag.Bool("disable_cgroups", false, "run jobs without cgroups, for development only; limits are rejected")
+	streamBufferFlag    = flag.Int("stream_buffer", igrpc.DefaultStreamBuffer, "number of output chunks held in memory per output stream")
+	metricsPortFlag     = flag.Int("metrics_port", 0, "port to serve Prometheus metrics at /metrics; 0 disables metrics")
+	retainMaxJobsFlag   = flag.Int("retain_max_jobs", 0, "maximum number of finished jobs retained; once exceeded, the earliest finished jobs and their output are purged; 0 is unlimited")
+	retainJobsForFlag   = flag.Duration("retain_jobs_for", 0, "duration finished jobs and their output are retained before being purged; 0 retains them until shutdown")
+	outputRetentionFlag = flag.Duration("output_retention", 0, "alias of -retain_jobs_for")
+	allowlistFlag       = flag.String("allowlist", "", "path to a file of command names clients may run, one per line; empty allows all commands")
+	maxOutputBytesFlag  = flag.Uint64("max_output_bytes", 0, "output limit in bytes of jobs not specifying one, and the largest limit jobs may specify; 0 is unlimited")
+	allowRootJobsFlag   = flag.Bool("allow_root_jobs", false, "allow clients to run jobs as root, uid or gid 0")
+	stateDirFlag        = flag.String("state_dir", "", "directory job metadata is recorded within, so jobs survive server restarts; empty keeps jobs in memory only")
+	outputRotateFlag    = flag.Int64("output_rotate_bytes", 0, "size in bytes at which job output rolls over to a new segment; 0 disables rotation")
+	outputDirFlag       = flag.String("output_dir", output.DefaultRoot, "directory job output is written within")
+	defaultRoleFlag     = flag.String("default_role", igrpc.RoleAdmin, "role of users whose certificates carry no recognized role, \"admin\", \"read-only\", or \"none\" to deny them all methods")
 )
 
 // logger is an object for logging package events to stdout.
@@ -36,6 +62,16 @@ const (
 	ecListen
 	// ecServe indicates the jobworker API was unable to serve its content.
 	ecServe
+	// ecMetricsListen indicates the metrics server was unable to listen.
+	ecMetricsListen
+	// ecAllowlist indicates the command allowlist could not be read.
+	ecAllowlist
+)
+
+const (
+	// logLevelEnv is the environment variable specifying the log level, one of
+	// "error", "warn", "info", or "debug". Defaults to "info".
+	logLevelEnv = "JOBWORKER_LOG_LEVEL"
 )
 
 const (
@@ -51,6 +87,14 @@ func Run() int {
 		return help("Too few arguments")
 	}
 
+	if name, ok := os.LookupEnv(logLevelEnv); ok {
+		level, err := log.ParseLevel(name)
+		if err != nil {
+			return help(fmt.Sprintf("Environment variable %s must be \"error\", \"warn\", \"info\", or \"debug\".", logLevelEnv))
+		}
+		log.SetLevel(level)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -89,9 +133,71 @@ Available Commands:
 
 Global Flags:
   -port       port to serve jobworker API
-  -cert       server x509 certificate
+  -reflection register gRPC server reflection (default false)
+  -cert       server x509 certificate, reloaded with -key once either changes
   -key        server private key
-  -ca_cert    certificate authority cert
+  -ca_cert    certificate authority certs or directories of them,
+              comma-separated; client certs signed by any of them are
+              accepted
+  -crl        certificate revocation list of revoked client certs, reloaded
+              once modified or on SIGHUP (default empty, no revocation
+              checks)
+  -env_allow  environment variable keys clients may set
+  -env_deny   environment variable keys clients may not set
+  -env_strip  strip denied environment variables instead of rejecting
+  -tls_min_version
+              minimum TLS version accepted, 1.2 or 1.3 (default 1.3)
+  -tls_cipher_suites
+              TLS 1.2 cipher suites accepted, comma-separated, e.g.
+              TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 (default empty, Go's
+              secure defaults)
+  -tls_curves elliptic curves used for key exchange in order of preference,
+              comma-separated, any of X25519, P256, P384, or P521 (default
+              empty, Go's defaults)
+  -max_jobs   maximum number of jobs running at once (default 0, unlimited)
+  -jobs_per_user
+              maximum number of jobs each user may run at once (default 0,
+              unlimited)
+  -disk_path  path whose block device disk limits apply to (default /)
+  -disable_cgroups
+              run jobs without cgroups, for development only; jobs are not
+              isolated and limits are rejected (default false)
+  -stream_buffer
+              number of output chunks held in memory per output stream
+              (default 16)
+  -metrics_port
+              port to serve Prometheus metrics at /metrics (default 0,
+              disabled)
+  -retain_jobs_for
+              duration finished jobs and their output are retained before
+              being purged, e.g. 30m (default 0, retained until shutdown)
+  -output_retention
+              alias of -retain_jobs_for
+  -retain_max_jobs
+              maximum number of finished jobs retained; the earliest
+              finished are purged beyond it (default 0, unlimited)
+  -allowlist  file of command names clients may run, one per line (default
+              empty, all commands allowed)
+  -max_output_bytes
+              output limit in bytes of jobs not specifying one, and the
+              largest limit jobs may specify (default 0, unlimited)
+  -allow_root_jobs
+              allow clients to run jobs as root, uid or gid 0 (default false)
+  -state_dir  directory job metadata is recorded within, so jobs survive
+              server restarts (default empty, jobs kept in memory only)
+  -output_rotate_bytes
+              size in bytes at which job output rolls over to a new segment,
+              e.g. <id>.log.1 (default 0, not rotated)
+  -output_dir directory job output is written within (default
+              /var/log/jobworker)
+  -default_role
+              role of users whose certs carry no admin or read-only role,
+              admin, read-only, or none to deny them all methods (default
+              admin)
+
+Environment Variables:
+  JOBWORKER_LOG_LEVEL
+              log level, one of error, warn, info, or debug (default info)
 `)
 	fmt.Fprint(os.Stdout, b.String())
 	return ecUnrecognized
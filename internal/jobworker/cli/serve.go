@@ -2,21 +2,29 @@ package cli
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 
 	"github.com/tjper/teleport/internal/encrypt"
 	"github.com/tjper/teleport/internal/jobworker/cgroup"
 	igrpc "github.com/tjper/teleport/internal/jobworker/grpc"
 	"github.com/tjper/teleport/internal/jobworker/job"
 	"github.com/tjper/teleport/internal/jobworker/user"
+	"github.com/tjper/teleport/internal/metrics"
 	pb "github.com/tjper/teleport/proto/gen/go/jobworker/v1"
 
 	"golang.org/x/sys/unix"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 // runServe initializes and configures a gprc.JobWorker instance to serve
@@ -34,18 +42,82 @@ func runServe(ctx context.Context) int {
 		return ecUnrecognized
 	}
 
-	cgroupSvc, err := cgroup.NewService()
+	if *streamBufferFlag < 0 {
+		help("Option -stream_buffer must not be negative.")
+		return ecUnrecognized
+	}
+	if *retainMaxJobsFlag < 0 {
+		help("Option -retain_max_jobs must not be negative.")
+		return ecUnrecognized
+	}
+	retainJobsFor := *retainJobsForFlag
+	if retainJobsFor == 0 {
+		retainJobsFor = *outputRetentionFlag
+	}
+	switch {
+	case *retainJobsForFlag != 0 && *outputRetentionFlag != 0 && *retainJobsForFlag != *outputRetentionFlag:
+		help("Options -retain_jobs_for and -output_retention must not differ.")
+		return ecUnrecognized
+	case retainJobsFor < 0:
+		help("Option -retain_jobs_for must not be negative.")
+		return ecUnrecognized
+	}
+	if *defaultRoleFlag != igrpc.RoleNone && !igrpc.ValidRole(*defaultRoleFlag) {
+		help("Option -default_role must be \"admin\", \"read-only\", or \"none\".")
+		return ecUnrecognized
+	}
+
+	tlsMinVersion, err := encrypt.ParseTLSVersion(*tlsMinFlag)
+	if err != nil {
+		help("Option -tls_min_version must be \"1.2\" or \"1.3\".")
+		return ecUnrecognized
+	}
+	if tlsMinVersion < tls.VersionTLS13 {
+		logger.Warnf(
+			"WARNING: accepting TLS %s connections. TLS 1.3 is recommended; only lower the minimum TLS version for legacy clients.",
+			*tlsMinFlag,
+		)
+	}
+	tlsCipherSuites, err := encrypt.ParseCipherSuites(splitList(*tlsCipherFlag))
 	if err != nil {
-		logger.Errorf("cgroup service setup; error: %v", err)
-		return ecCgroupService
+		help(fmt.Sprintf("Option -tls_cipher_suites is invalid; %v.", err))
+		return ecUnrecognized
 	}
-	defer func() {
-		if err := cgroupSvc.Cleanup(); err != nil {
-			logger.Errorf("cgroup service cleanup; error: %v", err)
+	tlsCurves, err := encrypt.ParseCurves(splitList(*tlsCurvesFlag))
+	if err != nil {
+		help(fmt.Sprintf("Option -tls_curves is invalid; %v.", err))
+		return ecUnrecognized
+	}
+
+	var cgroupSvc job.ICgroupService
+	if *disableCgroupsFlag {
+		logger.Warnf(
+			"WARNING: cgroups disabled. Jobs are not isolated and limits are rejected; only disable cgroups for development.",
+		)
+		cgroupSvc = cgroup.NoopService{}
+	} else {
+		svc, err := cgroup.NewService(cgroup.WithDiskPath(*diskPathFlag))
+		if err != nil {
+			logger.Errorf("cgroup service setup; error: %v", err)
+			return ecCgroupService
 		}
-	}()
+		defer func() {
+			if err := svc.Cleanup(); err != nil {
+				logger.Errorf("cgroup service cleanup; error: %v", err)
+			}
+		}()
+		cgroupSvc = svc
+	}
 
-	jobSvc, err := job.NewService(cgroupSvc)
+	jobSvc, err := job.NewService(
+		cgroupSvc,
+		job.WithMaxJobs(*maxJobsFlag),
+		job.WithPerOwnerLimit(*jobsPerUserFlag),
+		job.WithOutputRetention(retainJobsFor),
+		job.WithMaxRetainedJobs(*retainMaxJobsFlag),
+		job.WithStateDir(*stateDirFlag),
+		job.WithOutputDir(*outputDirFlag),
+	)
 	if err != nil {
 		logger.Errorf("job service setup; error: %v", err)
 		return ecJobService
@@ -57,9 +129,68 @@ func runServe(ctx context.Context) int {
 	}()
 
 	userSvc := user.Service{}
-	jw := igrpc.NewJobWorker(jobSvc, userSvc)
+	envPolicy := igrpc.EnvPolicy{
+		Allow: splitList(*envAllowFlag),
+		Deny:  splitList(*envDenyFlag),
+		Strip: *envStripFlag,
+	}
+	authorizer := igrpc.RoleAuthorizer{DefaultRoles: []string{*defaultRoleFlag}}
+	jwOptions := []igrpc.JobWorkerOption{
+		igrpc.WithAuthorizer(authorizer),
+		igrpc.WithEnvPolicy(envPolicy),
+		igrpc.WithStreamBuffer(*streamBufferFlag),
+		igrpc.WithMaxOutputBytes(*maxOutputBytesFlag),
+		igrpc.WithOutputRotateBytes(*outputRotateFlag),
+	}
+	if *disableCgroupsFlag {
+		jwOptions = append(jwOptions, igrpc.WithLimitsDisabled())
+	}
+	if *allowRootJobsFlag {
+		jwOptions = append(jwOptions, igrpc.WithAllowRootJobs())
+	}
+	if *allowlistFlag != "" {
+		allowlist, err := igrpc.ReadAllowlist(*allowlistFlag)
+		if err != nil {
+			logger.Errorf("reading allowlist; error: %v", err)
+			return ecAllowlist
+		}
+		logger.Infof("restricting commands to allowlist; commands: %d", len(allowlist))
+		jwOptions = append(jwOptions, igrpc.WithAllowlist(allowlist))
+	}
+	capacity, err := igrpc.ReadHostCapacity()
+	if err != nil {
+		logger.Warnf("reading host capacity, percent limits and capacity checks unsupported; error: %v", err)
+	} else {
+		jwOptions = append(jwOptions, igrpc.WithHostCapacity(capacity))
+	}
+	jw := igrpc.NewJobWorker(jobSvc, userSvc, jwOptions...)
 
-	tlsConfig, err := encrypt.NewServermTLSConfig(*certFlag, *keyFlag, *caCertFlag)
+	tlsOptions := []encrypt.TLSOption{encrypt.WithMinVersion(tlsMinVersion)}
+	if len(tlsCipherSuites) > 0 {
+		tlsOptions = append(tlsOptions, encrypt.WithCipherSuites(tlsCipherSuites))
+	}
+	if len(tlsCurves) > 0 {
+		tlsOptions = append(tlsOptions, encrypt.WithCurvePreferences(tlsCurves))
+	}
+	var crl *encrypt.CRL
+	if *crlFlag != "" {
+		crl, err = encrypt.LoadCRL(*crlFlag)
+		if err != nil {
+			logger.Errorf("load CRL; error: %v", err)
+			return ecTLSConfig
+		}
+		if crl.Expired() {
+			logger.Warnf("WARNING: CRL is past its next update; path: %s", *crlFlag)
+		}
+		tlsOptions = append(tlsOptions, encrypt.WithCRL(crl))
+	}
+
+	tlsConfig, err := encrypt.NewServermTLSConfigWithCAs(
+		*certFlag,
+		*keyFlag,
+		splitList(*caCertFlag),
+		tlsOptions...,
+	)
 	if err != nil {
 		logger.Errorf("setup mTLS config; error: %v", err)
 		return ecTLSConfig
@@ -69,10 +200,49 @@ func runServe(ctx context.Context) int {
 	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
 	pb.RegisterJobWorkerServiceServer(srv, jw)
 
+	// Report the jobworker API as serving via the standard gRPC health checking
+	// service; the cgroup and job services have been setup.
+	healthSvc := health.NewServer()
+	healthSvc.SetServingStatus(pb.JobWorkerService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthSvc)
+
+	if *reflectionFlag {
+		// Reflection exposes the API schema to clients, e.g. grpcurl. Clients
+		// must still authenticate via mTLS.
+		reflection.Register(srv)
+	}
+
+	// Serve metrics on a separate port, so they may be scraped without mTLS.
+	var metricsSrv *http.Server
+	if *metricsPortFlag > 0 {
+		metricsAddr := fmt.Sprintf(":%d", *metricsPortFlag)
+		metricsLis, err := net.Listen("tcp", metricsAddr)
+		if err != nil {
+			logger.Errorf("listen on %s; error: %v", metricsAddr, err)
+			return ecMetricsListen
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(
+			job.JobsTotal,
+			job.JobsRunning,
+			job.JobDuration,
+			job.JobsEscalated,
+		))
+		metricsSrv = &http.Server{Handler: mux}
+
+		go func() {
+			logger.Infof("metrics listening on %s", metricsAddr)
+			if err := metricsSrv.Serve(metricsLis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Errorf("serve metrics on %s; error: %v", metricsAddr, err)
+			}
+		}()
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	// Listen for SIGINT and SIGTERM to stop gRPC server.
+	// Listen for SIGINT and SIGTERM to stop gRPC and metrics servers.
 	stopc := make(chan os.Signal, 1)
 	signal.Notify(stopc, unix.SIGINT, unix.SIGTERM)
 	go func() {
@@ -81,10 +251,43 @@ func runServe(ctx context.Context) int {
 			return
 		case signal := <-stopc:
 			logger.Infof("signal received; signal: %s", signal.String())
+			// Report NOT_SERVING so health probes observe the drain while
+			// in-flight requests complete.
+			healthSvc.Shutdown()
 			srv.GracefulStop()
+			if metricsSrv != nil {
+				if err := metricsSrv.Shutdown(context.Background()); err != nil {
+					logger.Errorf("metrics server shutdown; error: %v", err)
+				}
+			}
 		}
 	}()
 
+	// Listen for SIGHUP to reload the CRL, so certificates may be revoked
+	// without restarting the server.
+	if crl != nil {
+		reloadc := make(chan os.Signal, 1)
+		signal.Notify(reloadc, unix.SIGHUP)
+		defer signal.Stop(reloadc)
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-reloadc:
+					if err := crl.Reload(); err != nil {
+						logger.Errorf("reload CRL; error: %v", err)
+						continue
+					}
+					if crl.Expired() {
+						logger.Warnf("WARNING: CRL is past its next update; path: %s", *crlFlag)
+					}
+					logger.Infof("CRL reloaded; path: %s", *crlFlag)
+				}
+			}
+		}()
+	}
+
 	addr := fmt.Sprintf(":%d", *portFlag)
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -101,3 +304,14 @@ func runServe(ctx context.Context) int {
 
 	return ecSuccess
 }
+
+// splitList splits a comma-separated list, omitting empty elements.
+func splitList(list string) []string {
+	var elems []string
+	for _, elem := range strings.Split(list, ",") {
+		if elem = strings.TrimSpace(elem); elem != "" {
+			elems = append(elems, elem)
+		}
+	}
+	return elems
+}
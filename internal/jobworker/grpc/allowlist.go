@@ -0,0 +1,42 @@
+package grpc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Allowlist is the set of command names clients may run. Command names are
+// matched exactly, so "ls" does not permit "/bin/ls".
+type Allowlist map[string]bool
+
+// ReadAllowlist reads the Allowlist file at path. Each line of the file is a
+// command name; blank lines and lines beginning with "#" are ignored.
+func ReadAllowlist(path string) (Allowlist, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open allowlist %s; error: %w", path, err)
+	}
+	defer fd.Close()
+
+	return readAllowlist(fd)
+}
+
+// readAllowlist reads an Allowlist formatted r.
+func readAllowlist(r io.Reader) (Allowlist, error) {
+	allowlist := make(Allowlist)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		allowlist[name] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan allowlist; error: %w", err)
+	}
+	return allowlist, nil
+}
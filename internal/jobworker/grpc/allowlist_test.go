@@ -0,0 +1,88 @@
+package grpc
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/tjper/teleport/proto/gen/go/jobworker/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReadAllowlist(t *testing.T) {
+	type expected struct {
+		allowlist Allowlist
+	}
+	tests := map[string]struct {
+		file string
+		exp  expected
+	}{
+		"names": {
+			file: "ls\n/usr/bin/tail\n",
+			exp:  expected{allowlist: Allowlist{"ls": true, "/usr/bin/tail": true}},
+		},
+		"comments and blank lines": {
+			file: "# readers\nls\n\n  cat  \n",
+			exp:  expected{allowlist: Allowlist{"ls": true, "cat": true}},
+		},
+		"empty": {
+			exp: expected{allowlist: Allowlist{}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			allowlist, err := readAllowlist(strings.NewReader(test.file))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(allowlist, test.exp.allowlist) {
+				t.Fatalf("unexpected allowlist; actual: %v, expected: %v", allowlist, test.exp.allowlist)
+			}
+		})
+	}
+}
+
+func TestStartCommandNotAllowed(t *testing.T) {
+	type expected struct {
+		code codes.Code
+	}
+	tests := map[string]struct {
+		allowlist Allowlist
+		name      string
+		exp       expected
+	}{
+		"not allowed": {
+			allowlist: Allowlist{"ls": true},
+			name:      "rm",
+			exp:       expected{code: codes.PermissionDenied},
+		},
+		"path of allowed name": {
+			allowlist: Allowlist{"ls": true},
+			name:      "/bin/ls",
+			exp:       expected{code: codes.PermissionDenied},
+		},
+		"empty allowlist": {
+			allowlist: Allowlist{},
+			name:      "ls",
+			exp:       expected{code: codes.PermissionDenied},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			jw := NewJobWorker(nil, fakeUserService{}, WithAllowlist(test.allowlist))
+
+			_, err := jw.Start(context.Background(), &pb.StartRequest{
+				Command: &pb.Command{Name: test.name},
+				Limits:  &pb.Limits{},
+			})
+			if status.Code(err) != test.exp.code {
+				t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), test.exp.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// RoleAdmin permits all JobWorkerService methods.
+	RoleAdmin = "admin"
+	// RoleReadOnly permits only the JobWorkerService methods that read jobs,
+	// e.g. Status and Output. Jobs may not be started, stopped, or otherwise
+	// changed.
+	RoleReadOnly = "read-only"
+	// RoleNone permits no JobWorkerService methods. It is not recognized as a
+	// certificate's role, but may be given as a default, see
+	// RoleAuthorizer.DefaultRoles.
+	RoleNone = "none"
+)
+
+// readOnlyMethods are the JobWorkerService methods permitted by RoleReadOnly.
+var readOnlyMethods = map[string]bool{
+	"Status":      true,
+	"StatusWatch": true,
+	"Stats":       true,
+	"Wait":        true,
+	"Output":      true,
+}
+
+// Authorizer determines which JobWorkerService methods users may call.
+type Authorizer interface {
+	// Authorize checks if a user with roles may call method, the name of a
+	// JobWorkerService method, e.g. "Start".
+	Authorize(roles []string, method string) bool
+}
+
+// RoleAuthorizer is an Authorizer permitting methods by RoleAdmin and
+// RoleReadOnly. A user may call a method if any of its roles permit the
+// method. Unrecognized roles, e.g. an ordinary Organization such as
+// "Acme Inc", are ignored. Users without a recognized role, e.g. those whose
+// certificates predate roles, are given DefaultRoles.
+type RoleAuthorizer struct {
+	// DefaultRoles are the roles of users without a recognized role. If empty,
+	// such users may call no methods.
+	DefaultRoles []string
+}
+
+// Authorize checks if a user with roles may call method.
+func (a RoleAuthorizer) Authorize(roles []string, method string) bool {
+	if !recognized(roles) {
+		roles = a.DefaultRoles
+	}
+	for _, role := range roles {
+		switch role {
+		case RoleAdmin:
+			return true
+		case RoleReadOnly:
+			if readOnlyMethods[method] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidRole checks if role is recognized by RoleAuthorizer.
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleReadOnly
+}
+
+// recognized checks if any of roles is recognized by RoleAuthorizer.
+func recognized(roles []string) bool {
+	for _, role := range roles {
+		if ValidRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize retrieves the user associated with ctx, and checks the user may
+// call method. If the user could not be retrieved, an Unauthenticated error is
+// returned. If the user may not call method, a PermissionDenied error is
+// returned.
+func (jw JobWorker) authorize(ctx context.Context, method string) (string, error) {
+	user, ok := jw.userSvc.User(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	roles, _ := jw.userSvc.Roles(ctx)
+	if !jw.authorizer.Authorize(roles, method) {
+		logger.Infof("permission denied; user: %s, roles: %v, method: %s", user, roles, method)
+		return "", status.Error(codes.PermissionDenied, "permission denied")
+	}
+
+	return user, nil
+}
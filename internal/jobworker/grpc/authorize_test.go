@@ -0,0 +1,162 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/tjper/teleport/proto/gen/go/jobworker/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRoleAuthorizer(t *testing.T) {
+	type expected struct {
+		ok bool
+	}
+	tests := map[string]struct {
+		authorizer RoleAuthorizer
+		roles      []string
+		method     string
+		exp        expected
+	}{
+		"no roles": {
+			roles:  nil,
+			method: "Status",
+			exp:    expected{ok: false},
+		},
+		"no roles default read-only status": {
+			authorizer: RoleAuthorizer{DefaultRoles: []string{RoleReadOnly}},
+			roles:      nil,
+			method:     "Status",
+			exp:        expected{ok: true},
+		},
+		"no roles default read-only start": {
+			authorizer: RoleAuthorizer{DefaultRoles: []string{RoleReadOnly}},
+			roles:      nil,
+			method:     "Start",
+			exp:        expected{ok: false},
+		},
+		"no roles default none status": {
+			authorizer: RoleAuthorizer{DefaultRoles: []string{RoleNone}},
+			roles:      nil,
+			method:     "Status",
+			exp:        expected{ok: false},
+		},
+		"no roles default admin start": {
+			authorizer: RoleAuthorizer{DefaultRoles: []string{RoleAdmin}},
+			roles:      nil,
+			method:     "Start",
+			exp:        expected{ok: true},
+		},
+		"admin start": {
+			roles:  []string{RoleAdmin},
+			method: "Start",
+			exp:    expected{ok: true},
+		},
+		"read-only status": {
+			roles:  []string{RoleReadOnly},
+			method: "Status",
+			exp:    expected{ok: true},
+		},
+		"read-only output": {
+			roles:  []string{RoleReadOnly},
+			method: "Output",
+			exp:    expected{ok: true},
+		},
+		"read-only start": {
+			roles:  []string{RoleReadOnly},
+			method: "Start",
+			exp:    expected{ok: false},
+		},
+		"read-only delete": {
+			roles:  []string{RoleReadOnly},
+			method: "Delete",
+			exp:    expected{ok: false},
+		},
+		"read-only start default admin": {
+			authorizer: RoleAuthorizer{DefaultRoles: []string{RoleAdmin}},
+			roles:      []string{RoleReadOnly},
+			method:     "Start",
+			exp:        expected{ok: false},
+		},
+		"read-only and admin start": {
+			roles:  []string{RoleReadOnly, RoleAdmin},
+			method: "Start",
+			exp:    expected{ok: true},
+		},
+		"unknown role": {
+			roles:  []string{"Acme Inc"},
+			method: "Status",
+			exp:    expected{ok: false},
+		},
+		"unknown role default read-only status": {
+			authorizer: RoleAuthorizer{DefaultRoles: []string{RoleReadOnly}},
+			roles:      []string{"Acme Inc"},
+			method:     "Status",
+			exp:        expected{ok: true},
+		},
+		"unknown role and read-only start": {
+			authorizer: RoleAuthorizer{DefaultRoles: []string{RoleAdmin}},
+			roles:      []string{"Acme Inc", RoleReadOnly},
+			method:     "Start",
+			exp:        expected{ok: false},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ok := test.authorizer.Authorize(test.roles, test.method)
+			if ok != test.exp.ok {
+				t.Fatalf("unexpected ok; actual: %v, expected: %v", ok, test.exp.ok)
+			}
+		})
+	}
+}
+
+func TestDefaultAuthorizer(t *testing.T) {
+	type expected struct {
+		ok bool
+	}
+	tests := map[string]struct {
+		roles []string
+		exp   expected
+	}{
+		"no roles": {
+			roles: nil,
+			exp:   expected{ok: true},
+		},
+		"unknown role": {
+			roles: []string{"Acme Inc"},
+			exp:   expected{ok: true},
+		},
+		"read-only": {
+			roles: []string{RoleReadOnly},
+			exp:   expected{ok: false},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Users without a recognized role may call all methods by default, so
+			// certificates predating roles remain usable.
+			jw := NewJobWorker(nil, fakeUserService{})
+			ok := jw.authorizer.Authorize(test.roles, "Start")
+			if ok != test.exp.ok {
+				t.Fatalf("unexpected ok; actual: %v, expected: %v", ok, test.exp.ok)
+			}
+		})
+	}
+}
+
+func TestStartPermissionDenied(t *testing.T) {
+	jw := NewJobWorker(nil, fakeUserService{roles: []string{RoleReadOnly}})
+
+	_, err := jw.Start(context.Background(), &pb.StartRequest{
+		Command: &pb.Command{Name: "ls"},
+		Limits:  &pb.Limits{},
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), codes.PermissionDenied)
+	}
+}
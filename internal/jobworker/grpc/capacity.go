@@ -0,0 +1,102 @@
+package grpc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	pb "github.com/tjper/teleport/proto/gen/go/jobworker/v1"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrMemTotalNotFound indicates the total memory of the host could not be
+// found in /proc/meminfo.
+var ErrMemTotalNotFound = errors.New("MemTotal not found")
+
+// meminfo is the path of the file detailing the host's memory.
+const meminfo = "/proc/meminfo"
+
+// HostCapacity is the resource capacity of the host running Jobs. Limits
+// specified as percentages are resolved against a HostCapacity.
+type HostCapacity struct {
+	// Memory is the total memory of the host in bytes.
+	Memory uint64
+	// Cpus is the number of CPUs of the host.
+	Cpus int
+}
+
+// ReadHostCapacity reads the HostCapacity of the host from /proc.
+func ReadHostCapacity() (HostCapacity, error) {
+	fd, err := os.Open(meminfo)
+	if err != nil {
+		return HostCapacity{}, fmt.Errorf("open %s; error: %w", meminfo, err)
+	}
+	defer fd.Close()
+
+	memory, err := readMemTotal(fd)
+	if err != nil {
+		return HostCapacity{}, err
+	}
+
+	return HostCapacity{Memory: memory, Cpus: runtime.NumCPU()}, nil
+}
+
+// readMemTotal reads the MemTotal line of a /proc/meminfo formatted r, and
+// returns the total memory in bytes.
+func readMemTotal(r io.Reader) (uint64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// e.g. "MemTotal:       16314612 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse MemTotal; error: %w", err)
+		}
+		return kb * 1024, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("scan %s; error: %w", meminfo, err)
+	}
+	return 0, ErrMemTotalNotFound
+}
+
+// resolve converts limits specified as percentages of the HostCapacity into
+// absolute limits. limits is not modified; if limits are not specified as
+// percentages, an equivalent copy of limits is returned.
+func (c HostCapacity) resolve(limits *pb.Limits) *pb.Limits {
+	resolved := proto.Clone(limits).(*pb.Limits)
+	if limits.Units != pb.LimitUnits_LIMIT_UNITS_PERCENT {
+		return resolved
+	}
+
+	resolved.Units = pb.LimitUnits_LIMIT_UNITS_UNSPECIFIED
+	resolved.Memory = c.Memory * limits.Memory / 100
+	resolved.MemoryMax = c.Memory * limits.MemoryMax / 100
+	resolved.Cpus = float32(c.Cpus) * limits.Cpus / 100
+	return resolved
+}
+
+// validate checks absolute limits do not exceed the HostCapacity. Zero
+// limits indicate no limit, and are always valid. If a limit exceeds the
+// HostCapacity, an error describing the limit is returned.
+func (c HostCapacity) validate(limits *pb.Limits) error {
+	if limits.Cpus > float32(c.Cpus) {
+		return fmt.Errorf("cpus limit %g exceeds %d available", limits.Cpus, c.Cpus)
+	}
+	if limits.Memory > c.Memory {
+		return fmt.Errorf("memory limit %d exceeds %d available", limits.Memory, c.Memory)
+	}
+	if limits.MemoryMax > c.Memory {
+		return fmt.Errorf("memory max limit %d exceeds %d available", limits.MemoryMax, c.Memory)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	pb "github.com/tjper/teleport/proto/gen/go/jobworker/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestResolveLimits(t *testing.T) {
+	capacity := HostCapacity{Memory: 8 << 30, Cpus: 4}
+
+	type expected struct {
+		limits *pb.Limits
+	}
+	tests := map[string]struct {
+		limits *pb.Limits
+		exp    expected
+	}{
+		"absolute": {
+			limits: &pb.Limits{Memory: 1 << 20, Cpus: 0.5},
+			exp:    expected{limits: &pb.Limits{Memory: 1 << 20, Cpus: 0.5}},
+		},
+		"percent": {
+			limits: &pb.Limits{
+				Memory:    25,
+				MemoryMax: 50,
+				Cpus:      50,
+				PidsMax:   10,
+				Units:     pb.LimitUnits_LIMIT_UNITS_PERCENT,
+			},
+			exp: expected{limits: &pb.Limits{
+				Memory:    2 << 30,
+				MemoryMax: 4 << 30,
+				Cpus:      2,
+				PidsMax:   10,
+			}},
+		},
+		"percent fraction of a cpu": {
+			limits: &pb.Limits{Cpus: 12.5, Units: pb.LimitUnits_LIMIT_UNITS_PERCENT},
+			exp:    expected{limits: &pb.Limits{Cpus: 0.5}},
+		},
+		"percent whole host": {
+			limits: &pb.Limits{Memory: 100, Cpus: 100, Units: pb.LimitUnits_LIMIT_UNITS_PERCENT},
+			exp:    expected{limits: &pb.Limits{Memory: 8 << 30, Cpus: 4}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			limits := capacity.resolve(test.limits)
+			if !proto.Equal(limits, test.exp.limits) {
+				t.Fatalf("unexpected limits; actual: %v, expected: %v", limits, test.exp.limits)
+			}
+		})
+	}
+}
+
+func TestValidateLimits(t *testing.T) {
+	capacity := HostCapacity{Memory: 8 << 30, Cpus: 16}
+
+	type expected struct {
+		err string
+	}
+	tests := map[string]struct {
+		limits *pb.Limits
+		exp    expected
+	}{
+		"no limits": {
+			limits: &pb.Limits{},
+		},
+		"within capacity": {
+			limits: &pb.Limits{Memory: 1 << 30, MemoryMax: 2 << 30, Cpus: 2.5},
+		},
+		"whole host": {
+			limits: &pb.Limits{Memory: 8 << 30, MemoryMax: 8 << 30, Cpus: 16},
+		},
+		"cpus exceed": {
+			limits: &pb.Limits{Cpus: 512},
+			exp:    expected{err: "cpus limit 512 exceeds 16 available"},
+		},
+		"memory exceeds": {
+			limits: &pb.Limits{Memory: 9 << 30},
+			exp:    expected{err: "memory limit 9663676416 exceeds 8589934592 available"},
+		},
+		"memory max exceeds": {
+			limits: &pb.Limits{MemoryMax: 9 << 30},
+			exp:    expected{err: "memory max limit 9663676416 exceeds 8589934592 available"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var actual string
+			if err := capacity.validate(test.limits); err != nil {
+				actual = err.Error()
+			}
+			if actual != test.exp.err {
+				t.Fatalf("unexpected error; actual: %q, expected: %q", actual, test.exp.err)
+			}
+		})
+	}
+}
+
+func TestReadMemTotal(t *testing.T) {
+	type expected struct {
+		memory uint64
+		err    error
+	}
+	tests := map[string]struct {
+		meminfo string
+		exp     expected
+	}{
+		"meminfo": {
+			meminfo: "MemTotal:       16314612 kB\nMemFree:         1024000 kB\n",
+			exp:     expected{memory: 16314612 * 1024},
+		},
+		"missing": {
+			meminfo: "MemFree:         1024000 kB\n",
+			exp:     expected{err: ErrMemTotalNotFound},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			memory, err := readMemTotal(strings.NewReader(test.meminfo))
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if memory != test.exp.memory {
+				t.Fatalf("unexpected memory; actual: %v, expected: %v", memory, test.exp.memory)
+			}
+		})
+	}
+}
+
+func TestStartCapacityLimitsInvalid(t *testing.T) {
+	type expected struct {
+		code codes.Code
+	}
+	tests := map[string]struct {
+		options []JobWorkerOption
+		limits  *pb.Limits
+		exp     expected
+	}{
+		"memory above 100": {
+			options: []JobWorkerOption{WithHostCapacity(HostCapacity{Memory: 1 << 30, Cpus: 1})},
+			limits:  &pb.Limits{Memory: 101, Units: pb.LimitUnits_LIMIT_UNITS_PERCENT},
+			exp:     expected{code: codes.InvalidArgument},
+		},
+		"cpus above 100": {
+			options: []JobWorkerOption{WithHostCapacity(HostCapacity{Memory: 1 << 30, Cpus: 1})},
+			limits:  &pb.Limits{Cpus: 100.5, Units: pb.LimitUnits_LIMIT_UNITS_PERCENT},
+			exp:     expected{code: codes.InvalidArgument},
+		},
+		"absolute cpus exceed capacity": {
+			options: []JobWorkerOption{WithHostCapacity(HostCapacity{Memory: 1 << 30, Cpus: 1})},
+			limits:  &pb.Limits{Cpus: 2},
+			exp:     expected{code: codes.InvalidArgument},
+		},
+		"absolute memory exceeds capacity": {
+			options: []JobWorkerOption{WithHostCapacity(HostCapacity{Memory: 1 << 30, Cpus: 1})},
+			limits:  &pb.Limits{MemoryMax: 2 << 30},
+			exp:     expected{code: codes.InvalidArgument},
+		},
+		"capacity unknown": {
+			limits: &pb.Limits{Memory: 50, Units: pb.LimitUnits_LIMIT_UNITS_PERCENT},
+			exp:    expected{code: codes.FailedPrecondition},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			jw := NewJobWorker(nil, fakeUserService{}, test.options...)
+
+			_, err := jw.Start(context.Background(), &pb.StartRequest{
+				Command: &pb.Command{Name: "ls"},
+				Limits:  test.limits,
+			})
+			if status.Code(err) != test.exp.code {
+				t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), test.exp.code)
+			}
+		})
+	}
+}
@@ -1,20 +1,158 @@
 package grpc
 
 import (
+	"sort"
+	"strings"
+
+	"github.com/tjper/teleport/internal/jobworker/cgroup"
 	"github.com/tjper/teleport/internal/jobworker/job"
+	"github.com/tjper/teleport/internal/jobworker/reexec"
 	pb "github.com/tjper/teleport/proto/gen/go/jobworker/v1"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+func toStatusDetail(j *job.Job) *pb.StatusDetail {
+	detail := &pb.StatusDetail{
+		Status:   toStatus(j.Status()),
+		ExitCode: int32(j.ExitCode()),
+	}
+	if startedAt := j.StartedAt(); !startedAt.IsZero() {
+		detail.StartedAt = timestamppb.New(startedAt)
+	}
+	if finishedAt := j.FinishedAt(); !finishedAt.IsZero() {
+		detail.FinishedAt = timestamppb.New(finishedAt)
+	}
+	if signal := j.Signal(); signal != 0 {
+		detail.TermSignal = unix.SignalName(signal)
+	}
+	detail.StoppedReason = toStoppedReason(j.StoppedReason())
+	detail.SetupError = j.SetupError()
+	detail.OomKilled = j.OomKilled()
+	detail.OutputTruncated = j.OutputTruncated()
+	return detail
+}
+
+func toStoppedReason(r job.StoppedReason) pb.StoppedReason {
+	switch r {
+	case job.Manual:
+		return pb.StoppedReason_STOPPED_REASON_MANUAL
+	case job.Timeout:
+		return pb.StoppedReason_STOPPED_REASON_TIMEOUT
+	case job.OutOfMemory:
+		return pb.StoppedReason_STOPPED_REASON_OUT_OF_MEMORY
+	case job.OutputLimit:
+		return pb.StoppedReason_STOPPED_REASON_OUTPUT_LIMIT
+	default:
+		return pb.StoppedReason_STOPPED_REASON_UNSPECIFIED
+	}
+}
+
+func toUsage(stats *cgroup.Stats) *pb.Usage {
+	if stats == nil {
+		return nil
+	}
+	return &pb.Usage{
+		MemoryCurrent: stats.MemoryCurrent,
+		MemoryPeak:    stats.MemoryPeak,
+		CpuUsageUsec:  stats.CpuUsageUsec,
+		IoReadBytes:   stats.IoReadBytes,
+		IoWriteBytes:  stats.IoWriteBytes,
+		OomKills:      stats.OomKills,
+	}
+}
+
+func toStatsResponse(stats *cgroup.DetailedStats) *pb.StatsResponse {
+	resp := &pb.StatsResponse{
+		CpuPressure:    toPressure(stats.CpuPressure),
+		MemoryPressure: toPressure(stats.MemoryPressure),
+		CpuStat:        stats.CpuStat,
+	}
+
+	devices := make([]string, 0, len(stats.IoStat))
+	for device := range stats.IoStat {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+	for _, device := range devices {
+		resp.IoStat = append(resp.IoStat, &pb.IoStat{Device: device, Values: stats.IoStat[device]})
+	}
+
+	return resp
+}
+
+func toPressure(p cgroup.Pressure) *pb.Pressure {
+	return &pb.Pressure{
+		Some: toPressureValues(p.Some),
+		Full: toPressureValues(p.Full),
+	}
+}
+
+func toPressureValues(v cgroup.PressureValues) *pb.PressureValues {
+	return &pb.PressureValues{
+		Avg10:     v.Avg10,
+		Avg60:     v.Avg60,
+		Avg300:    v.Avg300,
+		TotalUsec: v.Total,
+	}
+}
+
+func toCredential(cred *pb.Credential) *reexec.Credential {
+	if cred == nil {
+		return nil
+	}
+	return &reexec.Credential{Uid: cred.Uid, Gid: cred.Gid}
+}
+
+func toCommand(cmd reexec.Command) *pb.Command {
+	command := &pb.Command{Name: cmd.Name, Args: cmd.Args}
+	if cmd.Credential != nil {
+		command.Credential = &pb.Credential{Uid: cmd.Credential.Uid, Gid: cmd.Credential.Gid}
+	}
+	for _, kv := range cmd.Env {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if command.Env == nil {
+			command.Env = make(map[string]string)
+		}
+		command.Env[parts[0]] = parts[1]
+	}
+	return command
+}
+
+func toLimits(j *job.Job) *pb.Limits {
+	limits := j.Limits()
+	return &pb.Limits{
+		Memory:          limits.Memory,
+		MemoryMax:       limits.MemoryMax,
+		Cpus:            limits.Cpus,
+		DiskWriteBps:    limits.DiskWriteBps,
+		DiskReadBps:     limits.DiskReadBps,
+		CpuWeight:       limits.CpuWeight,
+		PidsMax:         limits.PidsMax,
+		CpuSet:          limits.CpuSet,
+		MaxOutputBytes:  uint64(j.MaxOutputBytes()),
+		KillOnMaxOutput: j.KillOnMaxOutput(),
+	}
+}
+
 func toStatus(s job.Status) pb.Status {
 	switch s {
 	case job.Pending:
 		return pb.Status_STATUS_PENDING
 	case job.Running:
 		return pb.Status_STATUS_RUNNING
+	case job.Paused:
+		return pb.Status_STATUS_PAUSED
 	case job.Stopped:
 		return pb.Status_STATUS_STOPPED
 	case job.Exited:
 		return pb.Status_STATUS_EXITED
+	case job.Failed:
+		return pb.Status_STATUS_FAILED
 	default:
 		return pb.Status_STATUS_UNSPECIFIED
 	}
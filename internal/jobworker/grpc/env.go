@@ -0,0 +1,74 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrEnvDenied indicates a client requested an environment variable denied by
+// the EnvPolicy.
+var ErrEnvDenied = errors.New("environment variable denied")
+
+// DefaultEnvDeny is the default set of environment variable keys clients may
+// not set. These keys alter how the dynamic loader links a Job's command.
+var DefaultEnvDeny = []string{"LD_PRELOAD", "LD_LIBRARY_PATH", "LD_AUDIT"}
+
+// EnvPolicy determines which environment variables clients may set for a Job.
+type EnvPolicy struct {
+	// Allow is the set of keys clients may set. If empty, all keys not in Deny
+	// are allowed.
+	Allow []string
+	// Deny is the set of keys clients may not set.
+	Deny []string
+	// Strip indicates denied keys should be removed from the environment
+	// rather than rejected.
+	Strip bool
+}
+
+// apply applies the EnvPolicy to env and returns the result in "key=value"
+// form, sorted by key. If env contains a denied key and the policy does not
+// strip denied keys, ErrEnvDenied is returned.
+func (p EnvPolicy) apply(env map[string]string) ([]string, error) {
+	var allowed []string
+	for _, key := range envKeys(env) {
+		if p.denied(key) {
+			if p.Strip {
+				logger.Infof("stripping denied environment variable; key: %s", key)
+				continue
+			}
+			return nil, fmt.Errorf("%w; key: %s", ErrEnvDenied, key)
+		}
+		allowed = append(allowed, fmt.Sprintf("%s=%s", key, env[key]))
+	}
+
+	return allowed, nil
+}
+
+// envKeys retrieves the keys of env, sorted.
+func envKeys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// denied checks if key may not be set by clients.
+func (p EnvPolicy) denied(key string) bool {
+	for _, deny := range p.Deny {
+		if key == deny {
+			return true
+		}
+	}
+	if len(p.Allow) == 0 {
+		return false
+	}
+	for _, allow := range p.Allow {
+		if key == allow {
+			return false
+		}
+	}
+	return true
+}
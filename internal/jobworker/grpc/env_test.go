@@ -0,0 +1,101 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	pb "github.com/tjper/teleport/proto/gen/go/jobworker/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestEnvPolicy(t *testing.T) {
+	type expected struct {
+		env []string
+		err error
+	}
+	tests := map[string]struct {
+		policy EnvPolicy
+		env    map[string]string
+		exp    expected
+	}{
+		"no env": {
+			policy: EnvPolicy{Deny: DefaultEnvDeny},
+			exp:    expected{env: nil, err: nil},
+		},
+		"allowed": {
+			policy: EnvPolicy{Deny: DefaultEnvDeny},
+			env:    map[string]string{"FOO": "bar", "BAZ": "qux"},
+			exp:    expected{env: []string{"BAZ=qux", "FOO=bar"}, err: nil},
+		},
+		"reject denied": {
+			policy: EnvPolicy{Deny: DefaultEnvDeny},
+			env:    map[string]string{"FOO": "bar", "LD_PRELOAD": "/tmp/evil.so"},
+			exp:    expected{env: nil, err: ErrEnvDenied},
+		},
+		"strip denied": {
+			policy: EnvPolicy{Deny: DefaultEnvDeny, Strip: true},
+			env:    map[string]string{"FOO": "bar", "LD_PRELOAD": "/tmp/evil.so"},
+			exp:    expected{env: []string{"FOO=bar"}, err: nil},
+		},
+		"reject not allowed": {
+			policy: EnvPolicy{Allow: []string{"FOO"}},
+			env:    map[string]string{"FOO": "bar", "BAZ": "qux"},
+			exp:    expected{env: nil, err: ErrEnvDenied},
+		},
+		"strip not allowed": {
+			policy: EnvPolicy{Allow: []string{"FOO"}, Strip: true},
+			env:    map[string]string{"FOO": "bar", "BAZ": "qux"},
+			exp:    expected{env: []string{"FOO=bar"}, err: nil},
+		},
+		"deny takes precedence over allow": {
+			policy: EnvPolicy{Allow: []string{"LD_PRELOAD"}, Deny: DefaultEnvDeny},
+			env:    map[string]string{"LD_PRELOAD": "/tmp/evil.so"},
+			exp:    expected{env: nil, err: ErrEnvDenied},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			env, err := test.policy.apply(test.env)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if !reflect.DeepEqual(env, test.exp.env) {
+				t.Fatalf("unexpected env; actual: %v, expected: %v", env, test.exp.env)
+			}
+		})
+	}
+}
+
+func TestStartEnvDenied(t *testing.T) {
+	jw := NewJobWorker(nil, fakeUserService{})
+
+	_, err := jw.Start(context.Background(), &pb.StartRequest{
+		Command: &pb.Command{
+			Name: "ls",
+			Env:  map[string]string{"LD_PRELOAD": "/tmp/evil.so"},
+		},
+		Limits: &pb.Limits{},
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), codes.PermissionDenied)
+	}
+}
+
+// fakeUserService is an IUserService that authenticates all requests as
+// test_user with roles.
+type fakeUserService struct {
+	roles []string
+}
+
+func (fakeUserService) User(context.Context) (string, bool) {
+	return "test_user", true
+}
+
+func (s fakeUserService) Roles(context.Context) ([]string, bool) {
+	return s.roles, true
+}
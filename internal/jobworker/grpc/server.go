@@ -4,7 +4,11 @@ package grpc
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/tjper/teleport/internal/jobworker/cgroup"
 	"github.com/tjper/teleport/internal/jobworker/job"
@@ -15,15 +19,100 @@ import (
 
 	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 // logger is an object for logging package events to stdout.
 var logger = log.New(os.Stdout, "grpc")
 
 // NewJobWorker creates a JobWorker instance.
-func NewJobWorker(jobSvc *job.Service, userSvc IUserService) *JobWorker {
-	return &JobWorker{jobSvc: jobSvc, userSvc: userSvc}
+func NewJobWorker(
+	jobSvc *job.Service,
+	userSvc IUserService,
+	options ...JobWorkerOption,
+) *JobWorker {
+	jw := &JobWorker{
+		jobSvc:       jobSvc,
+		userSvc:      userSvc,
+		envPolicy:    EnvPolicy{Deny: DefaultEnvDeny},
+		authorizer:   RoleAuthorizer{DefaultRoles: []string{RoleAdmin}},
+		streamBuffer: DefaultStreamBuffer,
+	}
+	for _, option := range options {
+		option(jw)
+	}
+	return jw
+}
+
+// JobWorkerOption is a function that mutates JobWorker instances. Typically
+// used with NewJobWorker.
+type JobWorkerOption func(*JobWorker)
+
+// WithHostCapacity configures a JobWorker to resolve limits specified as
+// percentages against capacity, and to reject limits exceeding capacity. If
+// not configured, percentage limits are rejected.
+func WithHostCapacity(capacity HostCapacity) JobWorkerOption {
+	return func(jw *JobWorker) { jw.capacity = &capacity }
+}
+
+// WithLimitsDisabled configures a JobWorker to reject requests specifying
+// limits. Typically used when Jobs are not run within cgroups, and limits
+// cannot be applied.
+func WithLimitsDisabled() JobWorkerOption {
+	return func(jw *JobWorker) { jw.limitsDisabled = true }
+}
+
+// DefaultStreamBuffer is the default number of chunks held in memory per Output
+// stream.
+const DefaultStreamBuffer = 16
+
+// WithStreamBuffer configures a JobWorker to hold up to size chunks in memory
+// per Output stream. Larger buffers trade memory for throughput to slow
+// clients.
+func WithStreamBuffer(size int) JobWorkerOption {
+	return func(jw *JobWorker) { jw.streamBuffer = size }
+}
+
+// WithAllowlist configures a JobWorker to only start Jobs whose command name
+// is within allowlist. If not configured, any command may be started.
+func WithAllowlist(allowlist Allowlist) JobWorkerOption {
+	return func(jw *JobWorker) { jw.allowlist = allowlist }
+}
+
+// WithMaxOutputBytes configures a JobWorker to limit the output of Jobs to at
+// most limit bytes. Jobs whose limits do not specify max_output_bytes are
+// limited to limit, and requests specifying a larger limit are rejected.
+func WithMaxOutputBytes(limit uint64) JobWorkerOption {
+	return func(jw *JobWorker) { jw.maxOutputBytes = limit }
+}
+
+// WithOutputRotateBytes configures a JobWorker to rotate the output of Jobs
+// once each segment of the output holds limit bytes. If not configured, or
+// limit is 0, output is not rotated.
+func WithOutputRotateBytes(limit int64) JobWorkerOption {
+	return func(jw *JobWorker) { jw.outputRotateBytes = limit }
+}
+
+// WithAllowRootJobs configures a JobWorker to allow clients to run Jobs with a
+// credential of uid or gid 0. If not configured, such requests are rejected.
+func WithAllowRootJobs() JobWorkerOption {
+	return func(jw *JobWorker) { jw.allowRootJobs = true }
+}
+
+// WithAuthorizer configures a JobWorker to authorize method calls with
+// authorizer. If not configured, a RoleAuthorizer with RoleAdmin as its
+// default role is used, so users without a recognized role, e.g. those whose
+// certificates predate roles, may call all methods.
+func WithAuthorizer(authorizer Authorizer) JobWorkerOption {
+	return func(jw *JobWorker) { jw.authorizer = authorizer }
+}
+
+// WithEnvPolicy configures a JobWorker to apply policy to the environment
+// variables of started Jobs.
+func WithEnvPolicy(policy EnvPolicy) JobWorkerOption {
+	return func(jw *JobWorker) { jw.envPolicy = policy }
 }
 
 var _ pb.JobWorkerServiceServer = (*JobWorker)(nil)
@@ -34,6 +123,9 @@ type IUserService interface {
 	// should indicate if the user could be retrieved. The user return value
 	// should be the user's unique identifer.
 	User(ctx context.Context) (string, bool)
+	// Roles retrieves the roles of the user associated with the ctx. The ok
+	// return value should indicate if the user could be retrieved.
+	Roles(ctx context.Context) ([]string, bool)
 }
 
 // Jobworker provides mechanisms for starting, stopping, fetching status, and
@@ -42,77 +134,272 @@ type IUserService interface {
 type JobWorker struct {
 	jobSvc  *job.Service
 	userSvc IUserService
+	// envPolicy determines which environment variables clients may set.
+	envPolicy EnvPolicy
+	// authorizer determines which methods users may call.
+	authorizer Authorizer
+	// allowlist is the set of command names clients may run. nil if any
+	// command may be run.
+	allowlist Allowlist
+	// capacity is the capacity of the host, used to resolve limits specified
+	// as percentages. nil if unknown.
+	capacity *HostCapacity
+	// limitsDisabled indicates limits cannot be applied to Jobs, and requests
+	// specifying limits are rejected.
+	limitsDisabled bool
+	// allowRootJobs indicates clients may run Jobs as root, uid or gid 0.
+	allowRootJobs bool
+	// maxOutputBytes is the output limit applied to Jobs whose limits do not
+	// specify one, and the largest output limit clients may specify. 0 if
+	// output is unlimited by default.
+	maxOutputBytes uint64
+	// outputRotateBytes is the size of each segment of Jobs' output. 0 if
+	// output is not rotated.
+	outputRotateBytes int64
+	// streamBuffer is the number of chunks held in memory per Output stream.
+	streamBuffer int
 }
 
 func (jw JobWorker) Start(ctx context.Context, req *pb.StartRequest) (*pb.StartResponse, error) {
-	user, ok := jw.userSvc.User(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	user, err := jw.authorize(ctx, "Start")
+	if err != nil {
+		return nil, err
 	}
 
 	valid := validator.New()
 	valid.AssertFunc(func() bool { return req.Command != nil }, "command empty")
 	valid.AssertFunc(func() bool { return req.Command.Name != "" }, "command name empty")
 	valid.AssertFunc(func() bool { return req.Limits != nil }, "limits empty")
+	valid.AssertFunc(
+		func() bool {
+			weight := req.Limits.CpuWeight
+			return weight == 0 || (weight >= cgroup.MinCpuWeight && weight <= cgroup.MaxCpuWeight)
+		},
+		fmt.Sprintf("cpu weight must be within [%d, %d]", cgroup.MinCpuWeight, cgroup.MaxCpuWeight),
+	)
+	valid.AssertFunc(
+		func() bool { return req.Limits.PidsMax == 0 || req.Limits.PidsMax >= cgroup.MinPidsMax },
+		fmt.Sprintf("pids max must be at least %d", cgroup.MinPidsMax),
+	)
+	valid.AssertFunc(
+		func() bool {
+			limits := req.Limits
+			return limits.Memory == 0 || limits.MemoryMax == 0 || limits.Memory <= limits.MemoryMax
+		},
+		"memory must not exceed memory max",
+	)
+	valid.AssertFunc(
+		func() bool { return req.Limits.CpuSet == "" || cgroup.ValidateCpuSet(req.Limits.CpuSet) == nil },
+		"cpu set must be a comma-separated list of cpus and cpu ranges, e.g. \"0-3,8\"",
+	)
+	valid.AssertFunc(
+		func() bool { return req.Limits.MaxOutputBytes <= jw.outputCap() },
+		fmt.Sprintf("max output bytes must not exceed %d", jw.outputCap()),
+	)
+	valid.AssertFunc(
+		func() bool {
+			return !req.Limits.KillOnMaxOutput || req.Limits.MaxOutputBytes != 0 || jw.maxOutputBytes != 0
+		},
+		"kill on max output requires max output bytes",
+	)
+	valid.AssertFunc(
+		func() bool {
+			limits := req.Limits
+			if limits.Units != pb.LimitUnits_LIMIT_UNITS_PERCENT {
+				return true
+			}
+			return limits.Memory <= 100 && limits.MemoryMax <= 100 && limits.Cpus >= 0 && limits.Cpus <= 100
+		},
+		"percent limits must be within (0, 100]",
+	)
+	valid.AssertFunc(
+		func() bool {
+			for key := range req.Command.Env {
+				if key == "" || strings.Contains(key, "=") {
+					return false
+				}
+			}
+			return true
+		},
+		"env key empty or contains \"=\"",
+	)
+	valid.AssertFunc(
+		func() bool { return req.Timeout == nil || req.Timeout.CheckValid() == nil },
+		"invalid timeout",
+	)
+	valid.AssertFunc(
+		func() bool { return req.Timeout.AsDuration() >= 0 },
+		"negative timeout",
+	)
 	if err := valid.Err(); err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	logger.Infof("processing StartRequest; Command: %v", req.Command)
+	env, err := jw.checkPolicy(req.Command, req.Limits)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := req.Limits
+	if limits.Units == pb.LimitUnits_LIMIT_UNITS_PERCENT {
+		if jw.capacity == nil {
+			return nil, status.Error(codes.FailedPrecondition, "host capacity unknown, percent limits unsupported")
+		}
+		limits = jw.capacity.resolve(limits)
+	}
+	if jw.capacity != nil {
+		if err := jw.capacity.validate(limits); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	limits = jw.defaultLimits(limits)
+
+	// Env values may hold client secrets, so only the keys are logged.
+	logger.Infof(
+		"processing StartRequest; name: %v, args: %v, env keys: %v",
+		req.Command.Name,
+		req.Command.Args,
+		envKeys(req.Command.Env),
+	)
 
 	j, err := job.New(
 		user,
 		reexec.Command{
-			Name: req.Command.Name,
-			Args: req.Command.Args,
+			Name:           req.Command.Name,
+			Args:           req.Command.Args,
+			Env:            env,
+			Credential:     toCredential(req.Command.Credential),
+			IsolatePid:     req.IsolatePid,
+			IsolateMount:   req.IsolateMount,
+			IsolateNetwork: req.IsolateNetwork,
 		},
+		job.WithTimeout(req.Timeout.AsDuration()),
+		job.WithMaxOutputBytes(int64(limits.MaxOutputBytes)),
+		job.WithKillOnMaxOutput(limits.KillOnMaxOutput),
+		job.WithOutputRotateBytes(jw.outputRotateBytes),
 	)
 	if err != nil {
 		logger.Errorf("building Job; error: %v", err)
 		return nil, status.Error(codes.Internal, "error building job")
 	}
 
-	if err := jw.jobSvc.StartJob(
-		ctx,
-		*j,
-		cgroupOptions(req.Limits)...,
-	); err != nil {
+	err = jw.jobSvc.StartJob(ctx, *j, cgroupOptions(limits)...)
+	if errors.Is(err, job.ErrMaxJobs) {
+		return nil, status.Error(codes.ResourceExhausted, "max jobs running, retry once a job finishes")
+	}
+	if errors.Is(err, job.ErrQuotaExceeded) {
+		return nil, status.Error(codes.ResourceExhausted, "job quota exceeded, retry once one of your jobs finishes")
+	}
+	if err != nil {
 		logger.Errorf("starting Job; error: %v", err)
 		return nil, status.Error(codes.Internal, "error starting job")
 	}
 
+	// StartJob starts a copy of j, so the started Job is fetched to report its
+	// status, e.g. Running, or Failed if its command could not be run.
+	started, err := jw.jobSvc.FetchJob(ctx, j.ID)
+	if err != nil {
+		logger.Errorf("fetching started Job; job: %s, error: %v", j.ID, err)
+		return nil, status.Error(codes.Internal, "error fetching started job")
+	}
+
 	logger.Infof("Job started; ID: %v", j.ID)
 	return &pb.StartResponse{
 		JobId:   j.ID.String(),
 		Command: req.Command,
-		Status: &pb.StatusDetail{
-			Status:   toStatus(j.Status()),
-			ExitCode: int32(j.ExitCode()),
-		},
-		Limits: req.Limits,
+		Status:  toStatusDetail(started),
+		Limits:  limits,
+	}, nil
+}
+
+func (jw JobWorker) Restart(ctx context.Context, req *pb.RestartRequest) (*pb.StartResponse, error) {
+	user, err := jw.authorize(ctx, "Restart")
+	if err != nil {
+		return nil, err
+	}
+
+	if req.JobId == "" {
+		return nil, status.Error(codes.InvalidArgument, validator.Format("empty job ID"))
+	}
+
+	j, err := jw.fetchJob(ctx, user, req.JobId)
+	if err != nil {
+		return nil, err
+	}
+
+	// The finished Job may have been started under a looser policy, e.g. by a
+	// previous server restored from its state directory, so the command is
+	// checked against the current policy. The command is re-run unchanged, so
+	// an environment the policy would strip is rejected.
+	command := toCommand(j.Command())
+	env, err := jw.checkPolicy(command, toLimits(j))
+	if err != nil {
+		return nil, err
+	}
+	if len(env) != len(command.Env) {
+		return nil, status.Error(codes.PermissionDenied, "job environment no longer allowed")
+	}
+
+	restarted, err := jw.jobSvc.RestartJob(ctx, j.ID)
+	if errors.Is(err, job.ErrJobActive) {
+		return nil, status.Error(codes.FailedPrecondition, "job has not finished")
+	}
+	if errors.Is(err, job.ErrEnvNotRecorded) {
+		return nil, status.Error(codes.FailedPrecondition, "job environment not retained across server restarts, start the job again")
+	}
+	if errors.Is(err, job.ErrMaxJobs) {
+		return nil, status.Error(codes.ResourceExhausted, "max jobs running, retry once a job finishes")
+	}
+	if errors.Is(err, job.ErrQuotaExceeded) {
+		return nil, status.Error(codes.ResourceExhausted, "job quota exceeded, retry once one of your jobs finishes")
+	}
+	if err != nil {
+		logger.Errorf("restarting Job; job: %s, error: %v", j.ID, err)
+		return nil, status.Error(codes.Internal, "error restarting job")
+	}
+
+	logger.Infof("Job restarted; ID: %v, restarted ID: %v", j.ID, restarted.ID)
+	return &pb.StartResponse{
+		JobId:   restarted.ID.String(),
+		Command: toCommand(restarted.Command()),
+		Status:  toStatusDetail(restarted),
+		Limits:  toLimits(restarted),
 	}, nil
 }
 
 func (jw JobWorker) Stop(ctx context.Context, req *pb.StopRequest) (*pb.StopResponse, error) {
-	user, ok := jw.userSvc.User(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	user, err := jw.authorize(ctx, "Stop")
+	if err != nil {
+		return nil, err
 	}
 
 	if req.JobId == "" {
 		return nil, status.Error(codes.InvalidArgument, validator.Format("empty job ID"))
 	}
 
+	valid := validator.New()
+	valid.AssertFunc(
+		func() bool { return req.GracePeriod == nil || req.GracePeriod.CheckValid() == nil },
+		"invalid grace period",
+	)
+	valid.AssertFunc(
+		func() bool { return req.GracePeriod.AsDuration() >= 0 },
+		"negative grace period",
+	)
+	if err := valid.Err(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	j, err := jw.fetchJob(ctx, user, req.JobId)
 	if err != nil {
 		return nil, err
 	}
 
-	if j.Status() != job.Running {
+	if s := j.Status(); s != job.Running && s != job.Paused {
 		return nil, status.Error(codes.FailedPrecondition, "job is not running")
 	}
 
-	if err := jw.jobSvc.StopJob(ctx, j.ID); err != nil {
+	if err := jw.jobSvc.StopJob(ctx, j.ID, req.GracePeriod.AsDuration()); err != nil {
 		logger.Errorf("stop job; job: %s, error: %v", j.ID, err)
 		return nil, status.Error(codes.Internal, "error stopping job")
 	}
@@ -120,10 +407,97 @@ func (jw JobWorker) Stop(ctx context.Context, req *pb.StopRequest) (*pb.StopResp
 	return &pb.StopResponse{}, nil
 }
 
+func (jw JobWorker) Pause(ctx context.Context, req *pb.PauseRequest) (*pb.PauseResponse, error) {
+	user, err := jw.authorize(ctx, "Pause")
+	if err != nil {
+		return nil, err
+	}
+
+	if req.JobId == "" {
+		return nil, status.Error(codes.InvalidArgument, validator.Format("empty job ID"))
+	}
+
+	j, err := jw.fetchJob(ctx, user, req.JobId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = jw.jobSvc.PauseJob(ctx, j.ID)
+	if errors.Is(err, job.ErrJobNotRunning) {
+		return nil, status.Error(codes.FailedPrecondition, "job is not running")
+	}
+	if errors.Is(err, cgroup.ErrFreezeUnsupported) {
+		return nil, status.Error(codes.FailedPrecondition, "cgroups disabled, pause unsupported")
+	}
+	if err != nil {
+		logger.Errorf("pause job; job: %s, error: %v", j.ID, err)
+		return nil, status.Error(codes.Internal, "error pausing job")
+	}
+
+	return &pb.PauseResponse{}, nil
+}
+
+func (jw JobWorker) Resume(ctx context.Context, req *pb.ResumeRequest) (*pb.ResumeResponse, error) {
+	user, err := jw.authorize(ctx, "Resume")
+	if err != nil {
+		return nil, err
+	}
+
+	if req.JobId == "" {
+		return nil, status.Error(codes.InvalidArgument, validator.Format("empty job ID"))
+	}
+
+	j, err := jw.fetchJob(ctx, user, req.JobId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = jw.jobSvc.ResumeJob(ctx, j.ID)
+	if errors.Is(err, job.ErrJobNotPaused) {
+		return nil, status.Error(codes.FailedPrecondition, "job is not paused")
+	}
+	if err != nil {
+		logger.Errorf("resume job; job: %s, error: %v", j.ID, err)
+		return nil, status.Error(codes.Internal, "error resuming job")
+	}
+
+	return &pb.ResumeResponse{}, nil
+}
+
+func (jw JobWorker) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	user, err := jw.authorize(ctx, "Delete")
+	if err != nil {
+		return nil, err
+	}
+
+	if req.JobId == "" {
+		return nil, status.Error(codes.InvalidArgument, validator.Format("empty job ID"))
+	}
+
+	j, err := jw.fetchJob(ctx, user, req.JobId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = jw.jobSvc.DeleteJob(ctx, j.ID)
+	if errors.Is(err, job.ErrJobActive) {
+		return nil, status.Error(codes.FailedPrecondition, "job has not finished")
+	}
+	if errors.Is(err, job.ErrJobNotFound) {
+		return nil, status.Error(codes.NotFound, "unknown job ID")
+	}
+	if err != nil {
+		logger.Errorf("delete job; job: %s, error: %v", j.ID, err)
+		return nil, status.Error(codes.Internal, "error deleting job")
+	}
+
+	return &pb.DeleteResponse{}, nil
+}
+
 func (jw JobWorker) Status(ctx context.Context, req *pb.StatusRequest) (*pb.StatusResponse, error) {
-	user, ok := jw.userSvc.User(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	user, err := jw.authorize(ctx, "Status")
+	if err != nil {
+		return nil, err
 	}
 
 	if req.JobId == "" {
@@ -135,18 +509,52 @@ func (jw JobWorker) Status(ctx context.Context, req *pb.StatusRequest) (*pb.Stat
 		return nil, err
 	}
 
+	stats, err := jw.jobSvc.FetchUsage(ctx, j.ID)
+	if err != nil {
+		logger.Errorf("fetch job usage; job: %s, error: %v", j.ID, err)
+		return nil, status.Error(codes.Internal, "error fetching job usage")
+	}
+
 	return &pb.StatusResponse{
-		Status: &pb.StatusDetail{
-			Status:   toStatus(j.Status()),
-			ExitCode: int32(j.ExitCode()),
-		},
+		Status: toStatusDetail(j),
+		Usage:  toUsage(stats),
 	}, nil
 }
 
-func (jw JobWorker) Output(req *pb.OutputRequest, stream pb.JobWorkerService_OutputServer) error {
-	user, ok := jw.userSvc.User(stream.Context())
-	if !ok {
-		return status.Error(codes.Unauthenticated, "unauthenticated")
+func (jw JobWorker) Stats(ctx context.Context, req *pb.StatsRequest) (*pb.StatsResponse, error) {
+	user, err := jw.authorize(ctx, "Stats")
+	if err != nil {
+		return nil, err
+	}
+
+	if req.JobId == "" {
+		return nil, status.Error(codes.InvalidArgument, validator.Format("empty job ID"))
+	}
+
+	j, err := jw.fetchJob(ctx, user, req.JobId)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := jw.jobSvc.FetchStats(ctx, j.ID)
+	if errors.Is(err, job.ErrJobNotRunning) {
+		return nil, status.Error(codes.FailedPrecondition, "job is not running")
+	}
+	if errors.Is(err, cgroup.ErrCgroupNotFound) {
+		return nil, status.Error(codes.FailedPrecondition, "job stats unavailable")
+	}
+	if err != nil {
+		logger.Errorf("fetch job stats; job: %s, error: %v", j.ID, err)
+		return nil, status.Error(codes.Internal, "error fetching job stats")
+	}
+
+	return toStatsResponse(stats), nil
+}
+
+func (jw JobWorker) StatusWatch(req *pb.StatusWatchRequest, stream pb.JobWorkerService_StatusWatchServer) error {
+	user, err := jw.authorize(stream.Context(), "StatusWatch")
+	if err != nil {
+		return err
 	}
 
 	if req.JobId == "" {
@@ -161,24 +569,203 @@ func (jw JobWorker) Output(req *pb.OutputRequest, stream pb.JobWorkerService_Out
 	ctx, cancel := context.WithCancel(stream.Context())
 	defer cancel()
 
-	outputc := make(chan []byte, streamBuffer)
+	statusc := make(chan job.Status)
 	go func() {
-		if err := j.StreamOutput(ctx, outputc, chunkSize); err != nil {
-			logger.Errorf("streaming output from job; job: %s, error: %v", j.ID, err)
+		if err := j.WatchStatus(ctx, statusc); err != nil && !errors.Is(err, context.Canceled) {
+			logger.Errorf("watching status of job; job: %s, error: %v", j.ID, err)
+		}
+		close(statusc)
+	}()
+
+	for s := range statusc {
+		detail := toStatusDetail(j)
+		detail.Status = toStatus(s)
+		if err := stream.Send(&pb.StatusResponse{Status: detail}); err != nil {
+			logger.Errorf("streaming status to client; job: %s, error: %s", j.ID, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (jw JobWorker) Wait(ctx context.Context, req *pb.WaitRequest) (*pb.WaitResponse, error) {
+	user, err := jw.authorize(ctx, "Wait")
+	if err != nil {
+		return nil, err
+	}
+
+	if req.JobId == "" {
+		return nil, status.Error(codes.InvalidArgument, validator.Format("empty job ID"))
+	}
+
+	j, err := jw.fetchJob(ctx, user, req.JobId)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := jw.jobSvc.WaitJob(ctx, j.ID); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	if req.RequireSuccess && !j.Succeeded() {
+		return nil, status.Errorf(
+			codes.Aborted,
+			"job did not succeed; status: %s, exit code: %d",
+			j.Status(),
+			j.ExitCode(),
+		)
+	}
+
+	return &pb.WaitResponse{
+		Status: toStatusDetail(j),
+	}, nil
+}
+
+func (jw JobWorker) Output(req *pb.OutputRequest, stream pb.JobWorkerService_OutputServer) error {
+	user, err := jw.authorize(stream.Context(), "Output")
+	if err != nil {
+		return err
+	}
+
+	if req.JobId == "" {
+		return status.Error(codes.InvalidArgument, validator.Format("empty job ID"))
+	}
+	if req.TailBytes < 0 {
+		return status.Error(codes.InvalidArgument, validator.Format("negative tail bytes"))
+	}
+	if req.TailBytes > 0 && req.Offset != 0 {
+		return status.Error(codes.InvalidArgument, validator.Format("offset and tail bytes both specified"))
+	}
+	if req.FromEnd && (req.Offset != 0 || req.TailBytes != 0) {
+		return status.Error(codes.InvalidArgument, validator.Format("from end combined with offset or tail bytes"))
+	}
+	chunkSize := defaultChunkSize
+	if req.ChunkSize != 0 {
+		if req.ChunkSize < minChunkSize || req.ChunkSize > maxChunkSize {
+			return status.Error(
+				codes.InvalidArgument,
+				validator.Format(fmt.Sprintf("chunk size must be within [%d, %d]", minChunkSize, maxChunkSize)),
+			)
 		}
+		chunkSize = int(req.ChunkSize)
+	}
+
+	j, err := jw.fetchJob(stream.Context(), user, req.JobId)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	outputc := make(chan job.Chunk, jw.streamBuffer)
+	errc := make(chan error, 1)
+	options := []job.StreamOption{
+		job.WithOffset(req.Offset),
+		job.WithTailBytes(req.TailBytes),
+		job.WithFollow(req.Follow == nil || *req.Follow),
+	}
+	if req.FromEnd {
+		options = append(options, job.WithFromEnd())
+	}
+	go func() {
+		errc <- j.StreamOutput(ctx, outputc, chunkSize, options...)
 		close(outputc)
 	}()
 
-	for b := range outputc {
-		if err := stream.Send(&pb.OutputResponse{Output: b}); err != nil {
+	var streamed int64
+	defer func() { stream.SetTrailer(outputTrailer(j, streamed)) }()
+
+	for chunk := range outputc {
+		if err := stream.Send(&pb.OutputResponse{Output: chunk.Data, Offset: chunk.Offset}); err != nil {
 			logger.Errorf("streaming output to client; job: %s, error: %s", j.ID, err)
 			return err
 		}
+		streamed += int64(len(chunk.Data))
+	}
+
+	err = <-errc
+	if errors.Is(err, job.ErrOutputNotReady) {
+		return status.Error(codes.FailedPrecondition, "job output not ready")
+	}
+	if errors.Is(err, job.ErrOutputPurged) || errors.Is(err, job.ErrOutputExpired) {
+		return status.Error(codes.NotFound, "job output expired")
+	}
+	if errors.Is(err, job.ErrOffsetOutOfRange) {
+		return status.Error(codes.InvalidArgument, validator.Format("offset out of range"))
+	}
+	if err != nil {
+		logger.Errorf("streaming output from job; job: %s, error: %v", j.ID, err)
 	}
 
 	return nil
 }
 
+const (
+	// trailerStreamedBytes is the Output trailer key of the number of output
+	// bytes streamed to the client.
+	trailerStreamedBytes = "output-streamed-bytes"
+	// trailerOutputSize is the Output trailer key of the job's final output
+	// size in bytes. Only set if the job is no longer running.
+	trailerOutputSize = "output-size"
+	// trailerOutputTruncated is the Output trailer key indicating the job's
+	// output was truncated. Only set if the job is no longer running and its
+	// output was truncated.
+	trailerOutputTruncated = "output-truncated"
+)
+
+// outputTrailer creates the Output trailer of a stream of j's output that
+// streamed the passed number of bytes. Clients may compare the streamed bytes
+// against the final output size to detect output that was not received.
+func outputTrailer(j *job.Job, streamed int64) metadata.MD {
+	trailer := metadata.Pairs(trailerStreamedBytes, strconv.FormatInt(streamed, 10))
+
+	// Status is checked before the size, so the size is final.
+	if s := j.Status(); s == job.Running || s == job.Paused {
+		return trailer
+	}
+	size, err := j.OutputSize()
+	if err != nil {
+		return trailer
+	}
+	trailer.Set(trailerOutputSize, strconv.FormatInt(size, 10))
+	if j.OutputTruncated() {
+		trailer.Set(trailerOutputTruncated, "true")
+	}
+
+	return trailer
+}
+
+// checkPolicy checks cmd and limits against the JobWorker's policy: the
+// command allowlist, whether jobs may run as root, the environment policy, and
+// whether limits are supported. The environment of cmd with the policy applied
+// is returned, in "key=value" form.
+func (jw JobWorker) checkPolicy(cmd *pb.Command, limits *pb.Limits) ([]string, error) {
+	if jw.allowlist != nil && !jw.allowlist[cmd.Name] {
+		return nil, status.Error(codes.PermissionDenied, "command not allowed")
+	}
+
+	if cred := cmd.Credential; cred != nil && (cred.Uid == 0 || cred.Gid == 0) && !jw.allowRootJobs {
+		return nil, status.Error(codes.PermissionDenied, "running jobs as root not allowed")
+	}
+
+	if jw.limitsDisabled && len(cgroupOptions(limits)) > 0 {
+		return nil, status.Error(codes.FailedPrecondition, "cgroups disabled, limits unsupported")
+	}
+
+	env, err := jw.envPolicy.apply(cmd.Env)
+	if errors.Is(err, ErrEnvDenied) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err != nil {
+		logger.Errorf("applying env policy; error: %v", err)
+		return nil, status.Error(codes.Internal, "error applying env policy")
+	}
+
+	return env, nil
+}
+
 func (jw JobWorker) fetchJob(ctx context.Context, user string, jobID string) (*job.Job, error) {
 	id, err := uuid.Parse(jobID)
 	if err != nil {
@@ -203,6 +790,26 @@ func (jw JobWorker) fetchJob(ctx context.Context, user string, jobID string) (*j
 	return j, nil
 }
 
+// defaultLimits applies the JobWorker's default limits to the limits not
+// specified by limits. limits is not modified; if no defaults apply, limits is
+// returned.
+func (jw JobWorker) defaultLimits(limits *pb.Limits) *pb.Limits {
+	if limits.MaxOutputBytes != 0 || jw.maxOutputBytes == 0 {
+		return limits
+	}
+	defaulted := proto.Clone(limits).(*pb.Limits)
+	defaulted.MaxOutputBytes = jw.maxOutputBytes
+	return defaulted
+}
+
+// outputCap is the largest output limit clients may specify.
+func (jw JobWorker) outputCap() uint64 {
+	if jw.maxOutputBytes > 0 && jw.maxOutputBytes < math.MaxInt64 {
+		return jw.maxOutputBytes
+	}
+	return math.MaxInt64
+}
+
 // cgroupOptions builds a slice of cgroup.CgroupOptions based on the limits.
 func cgroupOptions(limits *pb.Limits) []cgroup.CgroupOption {
 	var cgroups []cgroup.CgroupOption
@@ -213,18 +820,23 @@ func cgroupOptions(limits *pb.Limits) []cgroup.CgroupOption {
 	}
 
 	add(limits.Memory > 0, cgroup.WithMemory(limits.Memory))
+	add(limits.MemoryMax > 0, cgroup.WithMemoryMax(limits.MemoryMax))
 	add(limits.Cpus > 0, cgroup.WithCpus(limits.Cpus))
 	add(limits.DiskReadBps > 0, cgroup.WithDiskReadBps(limits.DiskReadBps))
 	add(limits.DiskWriteBps > 0, cgroup.WithDiskWriteBps(limits.DiskWriteBps))
+	add(limits.CpuWeight > 0, cgroup.WithCpuWeight(limits.CpuWeight))
+	add(limits.PidsMax > 0, cgroup.WithPidsMax(limits.PidsMax))
+	add(limits.CpuSet != "", cgroup.WithCpuSet(limits.CpuSet))
 
 	return cgroups
 }
 
 const (
-	// streamBuffer is the default stream buffer size. This is the number of
-	// chunks that may be held in memory.
-	streamBuffer = 16
-
-	// chunkSize is the size in bytes of each chunk to stream.
-	chunkSize = 128
+	// defaultChunkSize is the size in bytes of each chunk to stream, if not
+	// specified by the client.
+	defaultChunkSize = 32 << 10
+	// minChunkSize is the minimum chunk size in bytes a client may specify.
+	minChunkSize = 1 << 10
+	// maxChunkSize is the maximum chunk size in bytes a client may specify.
+	maxChunkSize = 1 << 20
 )
@@ -0,0 +1,243 @@
+package grpc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	pb "github.com/tjper/teleport/proto/gen/go/jobworker/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestStartLimitsInvalid(t *testing.T) {
+	type expected struct {
+		code codes.Code
+	}
+	tests := map[string]struct {
+		limits *pb.Limits
+		exp    expected
+	}{
+		"memory exceeds memory max": {
+			limits: &pb.Limits{Memory: 2048, MemoryMax: 1024},
+			exp:    expected{code: codes.InvalidArgument},
+		},
+		"percent memory exceeds memory max": {
+			limits: &pb.Limits{Memory: 50, MemoryMax: 25, Units: pb.LimitUnits_LIMIT_UNITS_PERCENT},
+			exp:    expected{code: codes.InvalidArgument},
+		},
+		"cpu weight out of range": {
+			limits: &pb.Limits{CpuWeight: 10001},
+			exp:    expected{code: codes.InvalidArgument},
+		},
+		"pids max too small": {
+			limits: &pb.Limits{PidsMax: 1},
+			exp:    expected{code: codes.InvalidArgument},
+		},
+		"cpu set malformed": {
+			limits: &pb.Limits{CpuSet: "0-3,"},
+			exp:    expected{code: codes.InvalidArgument},
+		},
+		"kill on max output without max output bytes": {
+			limits: &pb.Limits{KillOnMaxOutput: true},
+			exp:    expected{code: codes.InvalidArgument},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			jw := NewJobWorker(nil, fakeUserService{})
+
+			_, err := jw.Start(context.Background(), &pb.StartRequest{
+				Command: &pb.Command{Name: "ls"},
+				Limits:  test.limits,
+			})
+			if status.Code(err) != test.exp.code {
+				t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), test.exp.code)
+			}
+		})
+	}
+}
+
+func TestStartLimitsDisabled(t *testing.T) {
+	type expected struct {
+		code codes.Code
+	}
+	tests := map[string]struct {
+		limits *pb.Limits
+		exp    expected
+	}{
+		"memory": {
+			limits: &pb.Limits{Memory: 1 << 20},
+			exp:    expected{code: codes.FailedPrecondition},
+		},
+		"pids max": {
+			limits: &pb.Limits{PidsMax: 10},
+			exp:    expected{code: codes.FailedPrecondition},
+		},
+		"percent cpus": {
+			limits: &pb.Limits{Cpus: 50, Units: pb.LimitUnits_LIMIT_UNITS_PERCENT},
+			exp:    expected{code: codes.FailedPrecondition},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			jw := NewJobWorker(nil, fakeUserService{}, WithLimitsDisabled())
+
+			_, err := jw.Start(context.Background(), &pb.StartRequest{
+				Command: &pb.Command{Name: "ls"},
+				Limits:  test.limits,
+			})
+			if status.Code(err) != test.exp.code {
+				t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), test.exp.code)
+			}
+		})
+	}
+}
+
+func TestStartCredential(t *testing.T) {
+	type expected struct {
+		code codes.Code
+	}
+	tests := map[string]struct {
+		credential *pb.Credential
+		exp        expected
+	}{
+		"root uid": {
+			credential: &pb.Credential{Uid: 0, Gid: 65534},
+			exp:        expected{code: codes.PermissionDenied},
+		},
+		"root gid": {
+			credential: &pb.Credential{Uid: 65534, Gid: 0},
+			exp:        expected{code: codes.PermissionDenied},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			jw := NewJobWorker(nil, fakeUserService{})
+
+			_, err := jw.Start(context.Background(), &pb.StartRequest{
+				Command: &pb.Command{Name: "id", Credential: test.credential},
+				Limits:  &pb.Limits{},
+			})
+			if status.Code(err) != test.exp.code {
+				t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), test.exp.code)
+			}
+		})
+	}
+}
+
+func TestDefaultLimits(t *testing.T) {
+	type expected struct {
+		limits *pb.Limits
+	}
+	tests := map[string]struct {
+		options []JobWorkerOption
+		limits  *pb.Limits
+		exp     expected
+	}{
+		"no default": {
+			limits: &pb.Limits{PidsMax: 10},
+			exp:    expected{limits: &pb.Limits{PidsMax: 10}},
+		},
+		"default max output bytes": {
+			options: []JobWorkerOption{WithMaxOutputBytes(1024)},
+			limits:  &pb.Limits{PidsMax: 10},
+			exp:     expected{limits: &pb.Limits{PidsMax: 10, MaxOutputBytes: 1024}},
+		},
+		"specified max output bytes": {
+			options: []JobWorkerOption{WithMaxOutputBytes(1024)},
+			limits:  &pb.Limits{MaxOutputBytes: 512},
+			exp:     expected{limits: &pb.Limits{MaxOutputBytes: 512}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			jw := NewJobWorker(nil, fakeUserService{}, test.options...)
+
+			limits := jw.defaultLimits(test.limits)
+			if !proto.Equal(limits, test.exp.limits) {
+				t.Fatalf("unexpected limits; actual: %v, expected: %v", limits, test.exp.limits)
+			}
+		})
+	}
+}
+
+func TestStartMaxOutputBytesExceeded(t *testing.T) {
+	jw := NewJobWorker(nil, fakeUserService{}, WithMaxOutputBytes(1024))
+
+	_, err := jw.Start(context.Background(), &pb.StartRequest{
+		Command: &pb.Command{Name: "ls"},
+		Limits:  &pb.Limits{MaxOutputBytes: 2048},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), codes.InvalidArgument)
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	type expected struct {
+		env  []string
+		code codes.Code
+	}
+	tests := map[string]struct {
+		options []JobWorkerOption
+		cmd     *pb.Command
+		limits  *pb.Limits
+		exp     expected
+	}{
+		"allowed": {
+			cmd:    &pb.Command{Name: "ls", Env: map[string]string{"FOO": "bar"}},
+			limits: &pb.Limits{},
+			exp:    expected{env: []string{"FOO=bar"}, code: codes.OK},
+		},
+		"command not allowed": {
+			options: []JobWorkerOption{WithAllowlist(Allowlist{"echo": true})},
+			cmd:     &pb.Command{Name: "ls"},
+			limits:  &pb.Limits{},
+			exp:     expected{code: codes.PermissionDenied},
+		},
+		"root not allowed": {
+			cmd:    &pb.Command{Name: "ls", Credential: &pb.Credential{Uid: 0, Gid: 1000}},
+			limits: &pb.Limits{},
+			exp:    expected{code: codes.PermissionDenied},
+		},
+		"env denied": {
+			options: []JobWorkerOption{WithEnvPolicy(EnvPolicy{Deny: []string{"FOO"}})},
+			cmd:     &pb.Command{Name: "ls", Env: map[string]string{"FOO": "bar"}},
+			limits:  &pb.Limits{},
+			exp:     expected{code: codes.PermissionDenied},
+		},
+		"env stripped": {
+			options: []JobWorkerOption{WithEnvPolicy(EnvPolicy{Deny: []string{"FOO"}, Strip: true})},
+			cmd:     &pb.Command{Name: "ls", Env: map[string]string{"FOO": "bar", "BAZ": "qux"}},
+			limits:  &pb.Limits{},
+			exp:     expected{env: []string{"BAZ=qux"}, code: codes.OK},
+		},
+		"limits disabled": {
+			options: []JobWorkerOption{WithLimitsDisabled()},
+			cmd:     &pb.Command{Name: "ls"},
+			limits:  &pb.Limits{PidsMax: 10},
+			exp:     expected{code: codes.FailedPrecondition},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			jw := NewJobWorker(nil, fakeUserService{}, test.options...)
+
+			env, err := jw.checkPolicy(test.cmd, test.limits)
+			if status.Code(err) != test.exp.code {
+				t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), test.exp.code)
+			}
+			if !reflect.DeepEqual(env, test.exp.env) {
+				t.Fatalf("unexpected env; actual: %v, expected: %v", env, test.exp.env)
+			}
+		})
+	}
+}
@@ -6,23 +6,44 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/tjper/teleport/internal/jobworker"
+	"github.com/tjper/teleport/internal/jobworker/cgroup"
 	"github.com/tjper/teleport/internal/jobworker/output"
 	"github.com/tjper/teleport/internal/jobworker/reexec"
 
 	"github.com/google/uuid"
 )
 
-// New creates a new Job instance.
+// ErrOutputNotReady indicates the Job's output has not been created and the
+// Job is no longer running to create it.
+var ErrOutputNotReady = errors.New("output not ready")
+
+// ErrOffsetOutOfRange indicates a streaming offset lies outside of the Job's
+// output.
+var ErrOffsetOutOfRange = errors.New("offset out of range")
+
+// ErrOutputPurged indicates the Job's output has been purged. See
+// Service.DeleteJob.
+var ErrOutputPurged = errors.New("output purged")
+
+// ErrOutputExpired indicates the Job's output was created, but has since been
+// removed from the host by other means than Service.DeleteJob.
+var ErrOutputExpired = errors.New("output expired")
+
+// New creates a new Job instance. JobOptions may be specified to configure
+// the Job.
 func New(
 	owner string,
 	cmd reexec.Command,
+	options ...JobOption,
 ) (*Job, error) {
 	var closers []io.Closer
 	cleanup := func() {
@@ -46,35 +67,86 @@ func New(
 	closers = append(closers, continueOut)
 	closers = append(closers, continueIn)
 
-	shellCmd, err := os.Executable()
+	statusOut, statusIn, err := os.Pipe()
 	if err != nil {
 		cleanup()
-		return nil, fmt.Errorf("fetch current exec; error: %w", err)
+		return nil, fmt.Errorf("new job status pipe; error: %w", err)
 	}
+	closers = append(closers, statusOut)
+	closers = append(closers, statusIn)
 
-	ctx, cancel := context.WithCancel(context.Background())
-
-	executable := exec.CommandContext(ctx, shellCmd, jobworker.Reexec)
-	executable.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-	executable.ExtraFiles = []*os.File{cmdOut, continueOut}
+	startedOut, startedIn, err := os.Pipe()
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("new job started pipe; error: %w", err)
+	}
+	closers = append(closers, startedOut)
+	closers = append(closers, startedIn)
 
 	id := uuid.New()
+	job := &Job{
+		mutex:          new(sync.RWMutex),
+		ID:             id,
+		Owner:          owner,
+		cmd:            cmd,
+		status:         Pending,
+		statusSnapshot: newStatusSnapshot(Pending),
+		exitCode:       noExit,
+		done:           make(chan struct{}),
+		started:        make(chan struct{}),
+		cmdIn:          cmdIn,
+		cmdOut:         cmdOut,
+		continueIn:     continueIn,
+		continueOut:    continueOut,
+		statusIn:       statusIn,
+		statusOut:      statusOut,
+		startedIn:      startedIn,
+		startedOut:     startedOut,
+	}
+	for _, option := range options {
+		option(job)
+	}
+
+	// A timeout is enforced by the Job's context, which stops the Job once
+	// done.
+	if job.timeout > 0 {
+		job.ctx, job.cancel = context.WithTimeout(context.Background(), job.timeout)
+	} else {
+		job.ctx, job.cancel = context.WithCancel(context.Background())
+	}
+
 	logger.Infof("Constructed New Job; ID: %v", id)
-	return &Job{
-		mutex:       new(sync.RWMutex),
-		ID:          id,
-		Owner:       owner,
-		cmd:         cmd,
-		status:      Pending,
-		exitCode:    noExit,
-		ctx:         ctx,
-		cancel:      cancel,
-		exec:        executable,
-		cmdIn:       cmdIn,
-		cmdOut:      cmdOut,
-		continueIn:  continueIn,
-		continueOut: continueOut,
-	}, nil
+	return job, nil
+}
+
+// JobOption is a function that mutates Job instances. Typically used with New.
+type JobOption func(*Job)
+
+// WithTimeout configures a Job to be stopped if it runs longer than timeout.
+// A zeroed timeout indicates the Job may run indefinitely.
+func WithTimeout(timeout time.Duration) JobOption {
+	return func(j *Job) { j.timeout = timeout }
+}
+
+// WithMaxOutputBytes configures a Job to discard output beyond limit bytes.
+// Once output is discarded, output.TruncatedNotice is appended to the output.
+// A zeroed limit indicates the output is unbounded.
+func WithMaxOutputBytes(limit int64) JobOption {
+	return func(j *Job) { j.maxOutputBytes = limit }
+}
+
+// WithKillOnMaxOutput configures a Job to be killed once its output exceeds
+// the limit configured by WithMaxOutputBytes, rather than continuing with its
+// output discarded. The Job is stopped with the OutputLimit StoppedReason.
+func WithKillOnMaxOutput(kill bool) JobOption {
+	return func(j *Job) { j.killOnMaxOutput = kill }
+}
+
+// WithOutputRotateBytes configures a Job to rotate its output once each
+// segment of the output holds limit bytes, see output.RotatingWriter. A zeroed
+// limit indicates the output is not rotated.
+func WithOutputRotateBytes(limit int64) JobOption {
+	return func(j *Job) { j.outputRotateBytes = limit }
 }
 
 // Job represents a single arbitrary command and its related entities
@@ -89,67 +161,228 @@ type Job struct {
 	// Owner is the user responsible for Job instance creation.
 	Owner string
 
-	cmd      reexec.Command
-	status   Status
-	exitCode int
+	cmd    reexec.Command
+	status Status
+	// statusSnapshot holds the latest status, so Status may be read without
+	// locking the Job. It is updated by each status transition, prior to status
+	// listeners being notified.
+	statusSnapshot *atomic.Value
+	exitCode       int
+	// signal is the signal that terminated the Job, or 0 if the Job was not
+	// terminated by a signal.
+	signal syscall.Signal
+	// startedAt is the time the Job's executable was started.
+	startedAt time.Time
+	// finishedAt is the time the Job's executable exited.
+	finishedAt time.Time
+	// stopping indicates the Job has been requested to stop.
+	stopping bool
+	// statusListeners are notified of each status change. See listenStatus.
+	statusListeners []chan Status
+	// stoppedReason is the reason the Job was stopped.
+	stoppedReason StoppedReason
+	// setupError describes why the Job's executable failed to setup the Job's
+	// command. Empty if the command was setup.
+	setupError string
+	// usage is the resource usage of the Job, captured once the Job is no
+	// longer running.
+	usage *cgroup.Stats
+	// timeout is the maximum duration the Job may run. A zeroed timeout
+	// indicates no maximum.
+	timeout time.Duration
+	// maxOutputBytes is the maximum number of output bytes the Job may write.
+	// A zeroed maxOutputBytes indicates no maximum.
+	maxOutputBytes int64
+	// killOnMaxOutput indicates the Job is killed once its output exceeds
+	// maxOutputBytes.
+	killOnMaxOutput bool
+	// outputRotateBytes is the size of each segment of the Job's output. A
+	// zeroed outputRotateBytes indicates the output is not rotated.
+	outputRotateBytes int64
+	// outputStore locates the Job's output, set by Service.StartJob. A zeroed
+	// outputStore locates output within output.DefaultRoot.
+	outputStore output.Store
+	// cgroupOptions configure the cgroup the Job is run within, set by
+	// Service.StartJob. Retained so the Job may be restarted.
+	cgroupOptions []cgroup.CgroupOption
+	// envKeys are the keys of the environment variables of a Job restored from
+	// a record, whose values were not recorded. See Service.RestartJob.
+	envKeys []string
+	// streams is the number of active streams of the Job's output.
+	streams int
+	// purged indicates the Job's output has been purged. The output is removed
+	// once no streams are active.
+	purged bool
 
 	// context.Context is usually utilized at the function level. However, here
 	// it is being used to coordinate the cancelling of all async Job resources.
 	ctx    context.Context
 	cancel context.CancelFunc
+	// done is closed once the Job's executable has exited.
+	done chan struct{}
+	// started is closed once the Job's executable has reported whether the
+	// Job's command began executing. See watchStarted.
+	started chan struct{}
+
+	exec                                     *exec.Cmd
+	cmdIn, continueIn                        io.WriteCloser
+	cmdOut, continueOut, statusIn, startedIn *os.File
+	statusOut, startedOut                    io.ReadCloser
+}
+
+// Chunk is a portion of a Job's output.
+type Chunk struct {
+	// Data is the output.
+	Data []byte
+	// Offset is the byte offset within the Job's output following Data.
+	// Streaming may be resumed from Offset.
+	Offset int64
+}
+
+// StreamOption is a function that mutates streamOptions. Typically used with
+// Job.StreamOutput.
+type StreamOption func(*streamOptions)
 
-	exec                    *exec.Cmd
-	cmdIn, cmdOut           io.WriteCloser
-	continueIn, continueOut io.WriteCloser
+// WithOffset configures Job.StreamOutput to begin streaming from the byte
+// offset within the Job's output. A negative offset is relative to the end of
+// the output, e.g. -1024 begins streaming with the last 1024 bytes.
+func WithOffset(offset int64) StreamOption {
+	return func(o *streamOptions) { o.offset = offset }
+}
+
+// WithTailBytes configures Job.StreamOutput to begin streaming with the last
+// n bytes of the Job's output. If the output is smaller than n bytes,
+// streaming begins from the start of the output. WithTailBytes takes
+// precedence over WithOffset.
+func WithTailBytes(n int64) StreamOption {
+	return func(o *streamOptions) { o.tailBytes = n }
+}
+
+// WithFollow configures whether Job.StreamOutput follows the output of a
+// running Job. If follow is false, Job.StreamOutput returns upon reaching the
+// end of the output, even if the Job is running. By default, output is
+// followed.
+func WithFollow(follow bool) StreamOption {
+	return func(o *streamOptions) { o.follow = follow }
+}
+
+// WithFromEnd configures Job.StreamOutput to begin streaming from the end of
+// the Job's output, so only output written after streaming begins is streamed.
+// WithFromEnd takes precedence over WithOffset and WithTailBytes.
+func WithFromEnd() StreamOption {
+	return func(o *streamOptions) { o.fromEnd = true }
+}
+
+// streamOptions configure Job.StreamOutput.
+type streamOptions struct {
+	// offset is the byte offset streaming begins from.
+	offset int64
+	// tailBytes is the number of bytes from the end of the output streaming
+	// begins from. If zero, offset is used.
+	tailBytes int64
+	// fromEnd indicates streaming begins from the end of the output.
+	fromEnd bool
+	// follow indicates output of a running Job is followed.
+	follow bool
 }
 
 // StreamOutput streams Job's output to the passed stream channel in chunks of
-// size chunkSize. StreamOutput will return if either of the following
-// circumstances occur:
+// at most size chunkSize. StreamOptions may be specified to configure where
+// streaming begins and whether output is followed. StreamOutput will return if
+// any of the following circumstances occur:
 //
 // 1) The ctx is cancelled.
 // 2) The Job is no longer running and the end of the output is reached.
-func (j Job) StreamOutput(ctx context.Context, stream chan<- []byte, chunkSize int) error {
+// 3) The output is not followed and the end of the output is reached.
+//
+// If the output has not been created and the Job is running, StreamOutput
+// waits for it to be created. If the Job has finished without creating output,
+// e.g. its command could not be setup, StreamOutput returns without streaming.
+// If the Job has not started, ErrOutputNotReady is returned.
+//
+// An offset beyond the end of a running Job's output waits for the output to
+// reach it, while an offset beyond the end of a finished Job's output returns
+// without streaming. A negative offset reaching before the start of the output
+// returns ErrOffsetOutOfRange. If the Job's output has been purged,
+// ErrOutputPurged is returned, and if it has otherwise been removed,
+// ErrOutputExpired is returned.
+func (j *Job) StreamOutput(
+	ctx context.Context,
+	stream chan<- Chunk,
+	chunkSize int,
+	options ...StreamOption,
+) error {
+	// The output is not removed while it is being streamed.
+	if err := j.acquireStream(); err != nil {
+		return err
+	}
+	defer j.releaseStream()
+
+	opts := streamOptions{follow: true}
+	for _, option := range options {
+		option(&opts)
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	fd, err := os.Open(output.File(j.ID))
+	r, err := j.openOutput(ctx)
+	if errors.Is(err, ErrOutputNotReady) && j.Status().terminal() {
+		// The Job's executable creates the output before executing the Job's
+		// command, so a Job that started had output.
+		if !j.StartedAt().IsZero() {
+			return fmt.Errorf("%w; job: %v", ErrOutputExpired, j.ID)
+		}
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("open job output; error: %w", err)
+		return err
 	}
 	go func() {
 		<-ctx.Done()
-		fd.Close()
+		r.Close()
 	}()
 
+	offset, err := j.seekOutput(r, opts)
+	if err != nil {
+		return err
+	}
+
 	b := make([]byte, chunkSize)
 	for {
-		n, err := fd.Read(b)
+		// Status is checked before reading, so output written by a Job that has
+		// since exited is still read.
+		active := j.Status().active()
+		n, err := j.readChunk(ctx, r, b)
 		// If any bytes were read at all, write to stream.
 		if n > 0 {
+			offset += int64(n)
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case stream <- b[:n]:
+			case stream <- Chunk{Data: b[:n], Offset: offset}:
 			}
+			// The stream's receiver may hold the chunk while further output is
+			// read, allocate a new buffer rather than overwriting it.
+			b = make([]byte, chunkSize)
 		}
 		// If context has been cancelled return to caller.
 		if errors.Is(ctx.Err(), context.Canceled) {
 			return ctx.Err()
 		}
-		// If EOF and job is running, wait for output from job.
-		if errors.Is(err, io.EOF) && j.Status() == Running {
-			// TODO: This is a placeholder. Streaming implementation using inotify
-			// API will be in another PR. This will be replaced by something like
-			// select {
-			// case <-ctx.Done():
-			//   return ctx.Err()
-			// case <-j.watchOutput()
-			// }
-			time.Sleep(time.Second)
+		// If EOF, job is running, and output is followed, wait for output from
+		// job.
+		if errors.Is(err, io.EOF) && opts.follow && active {
+			// Poll for further output. The interval bounds the delay before newly
+			// written output is streamed, regardless of chunkSize.
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(outputFollowInterval):
+			}
 			continue
 		}
-		/// If EOF and job is not running, return.
+		/// If EOF and job is not running or output is not followed, return.
 		if errors.Is(err, io.EOF) {
 			return nil
 		}
@@ -159,30 +392,288 @@ func (j Job) StreamOutput(ctx context.Context, stream chan<- []byte, chunkSize i
 	}
 }
 
-// Status retrieves the Job status.
-func (j Job) Status() Status {
+// OutputSize retrieves the size of the Job's output in bytes. If the output
+// has not been created, ErrOutputNotReady is returned.
+func (j *Job) OutputSize() (int64, error) {
+	size, err := j.outputStore.Size(j.ID)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, ErrOutputNotReady
+	}
+	if err != nil {
+		return 0, fmt.Errorf("stat job output; error: %w", err)
+	}
+	return size, nil
+}
+
+// OutputTruncated indicates the Job wrote more output than its output limit,
+// and output was discarded. See WithMaxOutputBytes.
+func (j *Job) OutputTruncated() bool {
+	if j.maxOutputBytes == 0 {
+		return false
+	}
+	// Truncated output includes output.TruncatedNotice, exceeding the limit.
+	size, err := j.OutputSize()
+	return err == nil && size > j.maxOutputBytes
+}
+
+// openOutput opens the Job's output, across all of its segments. The Job's
+// executable creates the output shortly after starting; while the Job is
+// running, openOutput waits for the output to be created.
+func (j *Job) openOutput(ctx context.Context) (*output.Reader, error) {
+	ticker := time.NewTicker(outputPollInterval)
+	defer ticker.Stop()
+
+	for {
+		running := j.Status().active()
+		r, err := j.outputStore.Open(j.ID)
+		if err == nil {
+			return r, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("open job output; error: %w", err)
+		}
+		// Status is checked before opening, so output created by a Job that has
+		// since exited is still opened.
+		if !running {
+			return nil, ErrOutputNotReady
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// seekOutput seeks r to where streaming begins within the Job's output, as
+// configured by opts. The resulting offset is returned.
+func (j *Job) seekOutput(r *output.Reader, opts streamOptions) (int64, error) {
+	size, err := j.outputStore.Size(j.ID)
+	if err != nil {
+		return 0, fmt.Errorf("stat job output; error: %w", err)
+	}
+
+	var start int64
+	switch {
+	case opts.fromEnd:
+		start = size
+	case opts.tailBytes > 0:
+		start = size - opts.tailBytes
+		if start < 0 {
+			start = 0
+		}
+	case opts.offset < 0:
+		start = size + opts.offset
+	default:
+		start = opts.offset
+	}
+	// An offset beyond the end of the output is waited upon if the Job is
+	// running, and otherwise reads no output.
+	if start < 0 {
+		return 0, fmt.Errorf("%w; offset: %d, size: %d", ErrOffsetOutOfRange, opts.offset, size)
+	}
+
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("seek job output; offset: %d, error: %w", start, err)
+	}
+
+	return start, nil
+}
+
+// WatchStatus streams the Job's status to the passed stream channel, beginning
+// with the current status and followed by each status change. WatchStatus
+// will return if either of the following circumstances occur:
+//
+// 1) The ctx is cancelled.
+// 2) The Job's status is terminal, being Stopped or Exited.
+func (j *Job) WatchStatus(ctx context.Context, stream chan<- Status) error {
+	listener, unregister := j.listenStatus()
+	defer unregister()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case status, ok := <-listener:
+			if !ok {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case stream <- status:
+			}
+		}
+	}
+}
+
+// readChunk reads Job output from r into b. If the read does not fill b and
+// the Job is running, readChunk waits up to coalesceWindow for additional
+// output. This coalesces rapid small writes into a single chunk, reducing the
+// number of chunks streamed to clients.
+func (j *Job) readChunk(ctx context.Context, r io.Reader, b []byte) (int, error) {
+	n, err := r.Read(b)
+	if err != nil || n == 0 || n == len(b) {
+		return n, err
+	}
+
+	timer := time.NewTimer(coalesceWindow)
+	defer timer.Stop()
+	ticker := time.NewTicker(coalesceInterval)
+	defer ticker.Stop()
+
+	for n < len(b) && j.Status().active() {
+		select {
+		case <-ctx.Done():
+			return n, nil
+		case <-timer.C:
+			return n, nil
+		case <-ticker.C:
+		}
+
+		m, err := r.Read(b[n:])
+		n += m
+		if err != nil && !errors.Is(err, io.EOF) {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// Command retrieves the command the Job runs.
+func (j *Job) Command() reexec.Command {
+	return j.cmd
+}
+
+// Limits retrieves the limits of the cgroup the Job is run within, as
+// configured by the options passed to Service.StartJob. The returned Cgroup
+// does not exist on the host.
+func (j *Job) Limits() cgroup.Cgroup {
+	var limits cgroup.Cgroup
+	for _, option := range j.cgroupOptions {
+		option(&limits)
+	}
+	return limits
+}
+
+// MaxOutputBytes retrieves the maximum number of output bytes the Job may
+// write. 0 indicates no maximum. See WithMaxOutputBytes.
+func (j *Job) MaxOutputBytes() int64 {
+	return j.maxOutputBytes
+}
+
+// KillOnMaxOutput indicates the Job is killed once its output exceeds its
+// MaxOutputBytes. See WithKillOnMaxOutput.
+func (j *Job) KillOnMaxOutput() bool {
+	return j.killOnMaxOutput
+}
+
+// Status retrieves the Job status. Status is read from the Job's status
+// snapshot, avoiding contention on the Job's mutex when polled.
+func (j *Job) Status() Status {
+	if j.statusSnapshot != nil {
+		return j.statusSnapshot.Load().(Status)
+	}
+
 	j.mutex.RLock()
 	defer j.mutex.RUnlock()
 	return j.status
 }
 
 // ExitCode retrieves the Job exit code.
-func (j Job) ExitCode() int {
+func (j *Job) ExitCode() int {
 	j.mutex.RLock()
 	defer j.mutex.RUnlock()
 	return j.exitCode
 }
 
+// Succeeded checks if the Job exited with exit code 0.
+func (j *Job) Succeeded() bool {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	return j.status == Exited && j.exitCode == 0
+}
+
+// StoppedReason retrieves the reason the Job was stopped. If the Job was not
+// stopped by the Service, an empty StoppedReason is returned.
+func (j *Job) StoppedReason() StoppedReason {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	return j.stoppedReason
+}
+
+// Signal retrieves the signal that terminated the Job. If the Job was not
+// terminated by a signal, 0 is returned.
+func (j *Job) Signal() syscall.Signal {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	return j.signal
+}
+
+// SetupError retrieves the reason the Job's executable failed to setup the
+// Job's command, e.g. the command was not found. If the command was setup, an
+// empty string is returned. SetupError distinguishes a command that could not
+// be run from a command that exited with reexec.CommandFailure.
+func (j *Job) SetupError() string {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	return j.setupError
+}
+
+// Usage retrieves the resource usage of the Job captured once the Job was no
+// longer running. If the Job is running or its usage could not be captured,
+// nil is returned.
+func (j *Job) Usage() *cgroup.Stats {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	return j.usage
+}
+
+// OomKilled indicates the OOM killer killed a process of the Job, as captured
+// once the Job was no longer running.
+func (j *Job) OomKilled() bool {
+	usage := j.Usage()
+	return usage != nil && usage.OomKills > 0
+}
+
+// StartedAt retrieves the time the Job started. The zero time is returned if
+// the Job has not started.
+func (j *Job) StartedAt() time.Time {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	return j.startedAt
+}
+
+// FinishedAt retrieves the time the Job finished. The zero time is returned if
+// the Job has not finished.
+func (j *Job) FinishedAt() time.Time {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	return j.finishedAt
+}
+
 // cleanup releases all resources tied to the Job. cleanup should be called
-// once the Job is no longer being used.
-func (j Job) cleanup() {
+// once the Job is no longer being used. The Job's output is not released, and
+// may be streamed until the Job is deleted, see Service.DeleteJob.
+func (j *Job) cleanup() {
 	j.stop()
 
+	// The Job's executable was started, and counted as running, by start.
+	if j.exec != nil && j.exec.Process != nil {
+		JobsRunning.Dec()
+	}
+
 	closers := []io.Closer{
 		j.cmdIn,
 		j.cmdOut,
 		j.continueIn,
 		j.continueOut,
+		j.statusIn,
+		j.statusOut,
+		j.startedIn,
+		j.startedOut,
 	}
 
 	for _, closer := range closers {
@@ -190,13 +681,80 @@ func (j Job) cleanup() {
 	}
 }
 
-// start launches the Job.
-func (j *Job) start() error {
+// acquireStream registers an active stream of the Job's output. If the output
+// has been purged, ErrOutputPurged is returned. Each successful acquireStream
+// call must be followed by a releaseStream call once the stream completes.
+func (j *Job) acquireStream() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.purged {
+		return fmt.Errorf("%w; job: %v", ErrOutputPurged, j.ID)
+	}
+	j.streams++
+	return nil
+}
+
+// releaseStream releases a stream registered by acquireStream. If the output
+// has been purged and no other streams are active, the output is removed.
+func (j *Job) releaseStream() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.streams--
+	if j.purged && j.streams == 0 {
+		j.removeOutput()
+	}
+}
+
+// purge purges the Job's output. If no streams are active, the output is
+// removed immediately. Otherwise, the output is removed once the last active
+// stream completes.
+func (j *Job) purge() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.purged {
+		return
+	}
+	j.purged = true
+	if j.streams == 0 {
+		j.removeOutput()
+	}
+}
+
+// removeOutput removes the Job's output. The Job's mutex must be held.
+func (j *Job) removeOutput() {
+	err := j.outputStore.Remove(j.ID)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Errorf("removing job output; job: %v, error: %v", j.ID, err)
+	}
+}
+
+// start launches the Job. executable is the path of the jobworker executable
+// that will be re-executed to launch the Job's command.
+func (j *Job) start(executable string) error {
 	logger.Infof("starting Job; ID: %v", j.ID)
 
+	j.exec = exec.CommandContext(j.ctx, executable, jobworker.Reexec)
+	j.exec.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:    true,
+		Cloneflags: j.cmd.Cloneflags(),
+	}
+	j.exec.ExtraFiles = []*os.File{j.cmdOut, j.continueOut, j.statusIn, j.startedIn}
+
 	if err := j.exec.Start(); err != nil {
+		close(j.started)
 		return fmt.Errorf("start child process; error: %w", err)
 	}
+	JobsRunning.Inc()
+
+	// Close the parent's status and started pipe writers, so reading the pipes
+	// returns once the child process exits.
+	if err := j.statusIn.Close(); err != nil {
+		logger.Errorf("closing status pipe; err: %s", err)
+	}
+	if err := j.startedIn.Close(); err != nil {
+		logger.Errorf("closing started pipe; err: %s", err)
+	}
+	go j.watchStarted()
 
 	// Write job details to cmdIn pipe. Child process will read and launch
 	// grandchild process.
@@ -208,8 +766,12 @@ func (j *Job) start() error {
 		}()
 
 		reexecJob := reexec.Job{
-			ID:  j.ID,
-			Cmd: j.cmd,
+			ID:              j.ID,
+			Cmd:             j.cmd,
+			MaxOutputBytes:  j.maxOutputBytes,
+			KillOnMaxOutput: j.killOnMaxOutput,
+			RotateBytes:     j.outputRotateBytes,
+			OutputDir:       j.outputStore.Root,
 		}
 		b, err := json.Marshal(reexecJob)
 		if err != nil {
@@ -222,42 +784,189 @@ func (j *Job) start() error {
 		}
 	}()
 
+	return nil
+}
+
+// watchStarted waits for the Job's executable to report the Job's command
+// began executing, then sets the Job Running. If the executable exits without
+// executing the command, the Job is not set Running. Once the report is
+// handled, started is closed.
+func (j *Job) watchStarted() {
+	defer close(j.started)
+
+	b := make([]byte, 1)
+	if n, _ := j.startedOut.Read(b); n == 0 {
+		return
+	}
+	logger.Debugf("Job command started; ID: %s", j.ID)
+
+	j.setStartedAt(time.Now())
 	j.setStatus(Running)
 	logger.Infof("Job running; ID: %v", j.ID)
-
-	return nil
 }
 
-// stop terminates the Job.
-func (j Job) stop() {
+// stop terminates the Job. The Job's process group is killed, so commands
+// launched by the Job's command are terminated along with it. The Job's
+// context is canceled first, so the exit is attributed to the stop.
+func (j *Job) stop() {
 	j.cancel()
+	j.killProcessGroup()
 }
 
-// wait blocks until the Job has exited.
-func (j *Job) wait() error {
+// killProcessGroup sends SIGKILL to the Job's process group. The Job's
+// executable is the leader of its process group; signaling the negative pid
+// reaches the executable and every process descending from it. If the Job has
+// not started, killProcessGroup does nothing.
+func (j *Job) killProcessGroup() {
+	if j.exec == nil || j.exec.Process == nil {
+		return
+	}
+	err := syscall.Kill(-j.pid(), syscall.SIGKILL)
+	if err != nil && !errors.Is(err, syscall.ESRCH) {
+		logger.Errorf("killing job process group; job: %v, error: %v", j.ID, err)
+	}
+}
+
+// stopGracefully terminates the Job by sending SIGTERM to the Job's process
+// group. If the Job has not exited within grace, or ctx is cancelled, the Job
+// is killed. stopGracefully returns once the Job has exited.
+func (j *Job) stopGracefully(ctx context.Context, grace time.Duration) error {
+	j.setStopping()
+
+	// The Job's executable is the leader of its process group, signal the
+	// negative pid to reach the executable and the command it launched.
+	if err := syscall.Kill(-j.pid(), syscall.SIGTERM); err != nil {
+		j.stop()
+		return fmt.Errorf("signal job process group; error: %w", err)
+	}
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	select {
+	case <-j.done:
+		return nil
+	case <-timer.C:
+		logger.Warnf("Job escalated to SIGKILL, grace period elapsed; ID: %v, command: %v", j.ID, j.cmd.Name)
+		JobsEscalated.Inc()
+	case <-ctx.Done():
+	}
+
+	j.stop()
+	<-j.done
+	return nil
+}
+
+// wait blocks until the Job has exited. readStats reads the resource usage of
+// the cgroup the Job is running within; the usage is captured once the Job
+// has exited, and distinguishes a Job killed by the OOM killer from one killed
+// by another process. release is called once the Job's processes have been
+// killed, before the Job is set to a terminal status, so a Job observed to
+// have finished no longer counts against running Job limits.
+func (j *Job) wait(readStats func() (*cgroup.Stats, error), release func()) error {
+	defer close(j.done)
+
 	var exitErr *exec.ExitError
 	err := j.exec.Wait()
+
+	// Processes the Job's command left running, e.g. in the background, must
+	// not outlive the Job.
+	j.killProcessGroup()
+	release()
+
+	// Ensure the Job is set Running, if its command executed, before it is set
+	// to a terminal status.
+	<-j.started
+	j.setFinishedAt(time.Now())
 	if err != nil && !errors.As(err, &exitErr) {
 		return fmt.Errorf("waiting for child; error: %w", err)
 	}
 
+	exit := j.readExit()
+	j.setSignal(exit.Signal)
+	j.setSetupError(exit.SetupError)
+
+	usage, err := readStats()
+	if err != nil && !errors.Is(err, cgroup.ErrCgroupNotFound) {
+		logger.Errorf("reading job usage; job: %v, error: %v", j.ID, err)
+	}
+	j.setUsage(usage)
+
 	// Determine nature of process exit.
-	switch code := j.exec.ProcessState.ExitCode(); code {
-	// If job exit code is -1, process was terminated by a signal.
-	case noExit:
+	switch code := exit.Code; {
+	// If the command could not be setup, it never ran.
+	case exit.SetupError != "":
+		j.setStatus(Failed)
+	// If the process was terminated by a signal, the exit code is -1. If the Job
+	// was requested to stop, the command may have exited gracefully in response.
+	case exit.Signaled || code == noExit || j.isStopping():
+		j.setStoppedReason(j.stopCause(usage != nil && usage.OomKills > 0, exit.OutputLimited))
 		j.setStatus(Stopped)
 	default:
-		j.setStatus(Exited)
+		// Exit code is set prior to status so status listeners observe it.
 		j.setExitCode(code)
+		j.setStatus(Exited)
+	}
+
+	JobsTotal.With(string(j.Status())).Inc()
+	if startedAt := j.StartedAt(); !startedAt.IsZero() {
+		JobDuration.Observe(j.FinishedAt().Sub(startedAt).Seconds())
 	}
 
 	logger.Infof("Job no longer waiting; status: %v, exit code: %v", j.Status(), j.ExitCode())
 	return nil
 }
 
+// stopCause determines why the Job was stopped. oomKilled indicates the OOM
+// killer killed a process of the Job, and outputLimited indicates the Job's
+// command was killed for exceeding its output limit. If the Job was not
+// stopped by the Service, the OOM killer, or its output limit, e.g. its
+// command was killed by another process, an empty StoppedReason is returned.
+func (j *Job) stopCause(oomKilled, outputLimited bool) StoppedReason {
+	switch {
+	case errors.Is(j.ctx.Err(), context.DeadlineExceeded):
+		return Timeout
+	case j.isStopping() || errors.Is(j.ctx.Err(), context.Canceled):
+		return Manual
+	case oomKilled:
+		return OutOfMemory
+	case outputLimited:
+		return OutputLimit
+	default:
+		return ""
+	}
+}
+
+// readExit retrieves the exit state of the Job's command. The exit state is
+// reported by the Job's executable over the status pipe. If the executable did
+// not report, e.g. it was killed, the executable's own exit state is used.
+// readExit should be called once the executable has exited.
+func (j *Job) readExit() reexec.Exit {
+	b, err := io.ReadAll(j.statusOut)
+	if err != nil {
+		logger.Errorf("reading status pipe; job: %v, error: %v", j.ID, err)
+	}
+
+	var exit reexec.Exit
+	if len(b) > 0 {
+		err := json.Unmarshal(b, &exit)
+		if err == nil {
+			return exit
+		}
+		logger.Errorf("unmarshal exit; job: %v, error: %v", j.ID, err)
+	}
+
+	exit.Code = j.exec.ProcessState.ExitCode()
+	if status, ok := j.exec.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		exit.Signaled = true
+		exit.Signal = status.Signal()
+	}
+	return exit
+}
+
 // signalContinue instructs the Job's executable to continue.
-func (j Job) signalContinue() error {
-	logger.Infof("Job signal continue to child; ID: %s", j.ID)
+func (j *Job) signalContinue() error {
+	logger.Debugf("Job signal continue to child; ID: %s", j.ID)
 	if err := j.continueIn.Close(); err != nil {
 		return fmt.Errorf("signal continue to child; error: %w", err)
 	}
@@ -265,22 +974,157 @@ func (j Job) signalContinue() error {
 }
 
 // pid retrieves the Job's executable's pid.
-func (j Job) pid() int {
+func (j *Job) pid() int {
 	return j.exec.Process.Pid
 }
 
+// setStatus sets the Job status and notifies status listeners. Once the
+// status is terminal, status listeners are closed.
 func (j *Job) setStatus(s Status) {
 	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.updateStatus(s)
+}
+
+// transitionStatus sets the Job status to to if the status is from. The
+// returned bool indicates if the status was set.
+func (j *Job) transitionStatus(from, to Status) bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.status != from {
+		return false
+	}
+	j.updateStatus(to)
+	return true
+}
+
+// updateStatus sets the Job status and notifies status listeners. The Job's
+// mutex must be held.
+func (j *Job) updateStatus(s Status) {
 	j.status = s
+	if j.statusSnapshot != nil {
+		j.statusSnapshot.Store(s)
+	}
+	for _, listener := range j.statusListeners {
+		notifyStatus(listener, s)
+		if s.terminal() {
+			close(listener)
+		}
+	}
+	if s.terminal() {
+		j.statusListeners = nil
+	}
+}
+
+// notifyStatus sends s to listener. If listener is full, e.g. the Job was
+// repeatedly paused and resumed while the listener was not received from, the
+// listener's oldest status is dropped so the latest status is received and
+// notifyStatus does not block.
+func notifyStatus(listener chan Status, s Status) {
+	for {
+		select {
+		case listener <- s:
+			return
+		default:
+		}
+		select {
+		case <-listener:
+		default:
+		}
+	}
+}
+
+// listenStatus registers a status listener. The returned channel receives the
+// current Job status followed by each status change, and is closed once the
+// status is terminal. The returned function unregisters the listener.
+func (j *Job) listenStatus() (<-chan Status, func()) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	// Size the listener to hold the status changes of a Job that is not
+	// paused, see notifyStatus.
+	listener := make(chan Status, maxStatusChanges)
+	listener <- j.status
+	if j.status.terminal() {
+		close(listener)
+		return listener, func() {}
+	}
+	j.statusListeners = append(j.statusListeners, listener)
+
+	unregister := func() {
+		j.mutex.Lock()
+		defer j.mutex.Unlock()
+		for i := range j.statusListeners {
+			if j.statusListeners[i] == listener {
+				j.statusListeners = append(j.statusListeners[:i], j.statusListeners[i+1:]...)
+				return
+			}
+		}
+	}
+	return listener, unregister
+}
+
+func (j *Job) setStoppedReason(reason StoppedReason) {
+	j.mutex.Lock()
+	j.stoppedReason = reason
 	j.mutex.Unlock()
 }
 
+func (j *Job) setSignal(sig syscall.Signal) {
+	j.mutex.Lock()
+	j.signal = sig
+	j.mutex.Unlock()
+}
+
+func (j *Job) setSetupError(msg string) {
+	j.mutex.Lock()
+	j.setupError = msg
+	j.mutex.Unlock()
+}
+
+func (j *Job) setUsage(usage *cgroup.Stats) {
+	j.mutex.Lock()
+	j.usage = usage
+	j.mutex.Unlock()
+}
+
+func (j *Job) setStartedAt(t time.Time) {
+	j.mutex.Lock()
+	j.startedAt = t
+	j.mutex.Unlock()
+}
+
+func (j *Job) setFinishedAt(t time.Time) {
+	j.mutex.Lock()
+	j.finishedAt = t
+	j.mutex.Unlock()
+}
+
+func (j *Job) setStopping() {
+	j.mutex.Lock()
+	j.stopping = true
+	j.mutex.Unlock()
+}
+
+func (j *Job) isStopping() bool {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	return j.stopping
+}
+
 func (j *Job) setExitCode(code int) {
 	j.mutex.Lock()
 	j.exitCode = code
 	j.mutex.Unlock()
 }
 
+// newStatusSnapshot creates a Job status snapshot holding s.
+func newStatusSnapshot(s Status) *atomic.Value {
+	snapshot := new(atomic.Value)
+	snapshot.Store(s)
+	return snapshot
+}
+
 // Status represents the possible statuses of a Job.
 type Status string
 
@@ -289,14 +1133,66 @@ const (
 	Pending Status = "pending"
 	// Running indicates the job is currently running.
 	Running Status = "running"
+	// Paused indicates the job's processes are frozen, see Service.PauseJob.
+	Paused Status = "paused"
 	// Stopped indicates the job has been manually terminated.
 	Stopped Status = "stopped"
 	// Exited indicates the job exited and returned an exit code.
 	Exited Status = "exited"
+	// Failed indicates the job's command could not be setup, e.g. the command
+	// was not found. See Job.SetupError.
+	Failed Status = "failed"
+)
+
+// terminal checks if the Status is final; a Job's status does not change once
+// terminal.
+func (s Status) terminal() bool {
+	return s == Stopped || s == Exited || s == Failed
+}
+
+// active checks if the Status is of a Job whose command has started and not
+// yet finished; the Job may be paused.
+func (s Status) active() bool {
+	return s == Running || s == Paused
+}
+
+// StoppedReason represents the reasons a Job may be stopped.
+type StoppedReason string
+
+const (
+	// Manual indicates the Job was stopped by a StopJob call.
+	Manual StoppedReason = "manual"
+	// Timeout indicates the Job was stopped after exceeding its timeout.
+	Timeout StoppedReason = "timeout"
+	// OutOfMemory indicates the Job was killed by the OOM killer after
+	// exceeding its memory limit.
+	OutOfMemory StoppedReason = "out_of_memory"
+	// OutputLimit indicates the Job was killed after its output exceeded its
+	// limit. See WithKillOnMaxOutput.
+	OutputLimit StoppedReason = "output_limit"
 )
 
 const (
+	// maxStatusChanges is the maximum number of status changes a Job that is
+	// not paused may go through: Pending, Running, then Stopped, Exited, or
+	// Failed.
+	maxStatusChanges = 3
+
 	// noExit is the default process exit code. It indicates a process has not
 	// exited, or it was terminated by a signal.
 	noExit = -1
+
+	// coalesceWindow is the maximum duration StreamOutput will wait for
+	// additional output to fill a partially read chunk.
+	coalesceWindow = 10 * time.Millisecond
+	// coalesceInterval is the interval at which StreamOutput checks for
+	// additional output while coalescing a chunk.
+	coalesceInterval = 2 * time.Millisecond
+
+	// outputPollInterval is the interval at which StreamOutput checks for the
+	// creation of a running Job's output.
+	outputPollInterval = 10 * time.Millisecond
+	// outputFollowInterval is the interval at which StreamOutput checks for
+	// further output of a running Job, once it has streamed all output.
+	outputFollowInterval = 50 * time.Millisecond
 )
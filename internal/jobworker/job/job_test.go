@@ -0,0 +1,503 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tjper/teleport/internal/jobworker/output"
+	"github.com/tjper/teleport/internal/jobworker/reexec"
+
+	"github.com/google/uuid"
+)
+
+func BenchmarkStreamOutput(b *testing.B) {
+	store := output.Store{Root: b.TempDir()}
+
+	const (
+		writes        = 200
+		writeInterval = time.Millisecond
+		chunkSize     = 128
+	)
+	payload := []byte("output\n")
+
+	var messages int
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		job := &Job{mutex: new(sync.RWMutex), ID: uuid.New(), status: Running, outputStore: store}
+		fd, err := os.Create(store.File(job.ID))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		// Simulate a chatty job writing small amounts of output.
+		go func() {
+			defer job.setStatus(Exited)
+			for w := 0; w < writes; w++ {
+				if _, err := fd.Write(payload); err != nil {
+					return
+				}
+				time.Sleep(writeInterval)
+			}
+		}()
+
+		stream := make(chan Chunk)
+		errc := make(chan error, 1)
+		go func() {
+			errc <- job.StreamOutput(context.Background(), stream, chunkSize)
+			close(stream)
+		}()
+		for range stream {
+			messages++
+		}
+		if err := <-errc; err != nil {
+			b.Fatal(err)
+		}
+
+		fd.Close()
+		os.Remove(store.File(job.ID))
+	}
+
+	b.ReportMetric(float64(messages)/float64(b.N), "msgs/op")
+	b.ReportMetric(float64(messages)/time.Since(start).Seconds(), "msgs/s")
+}
+
+func BenchmarkStatus(b *testing.B) {
+	job, err := New("bench_user", reexec.Command{Name: "true"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer job.cleanup()
+
+	// Emulate a running Job, whose state is written while clients poll its
+	// status.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				job.setFinishedAt(time.Now())
+			}
+		}
+	}()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = job.Status()
+		}
+	})
+}
+
+func TestStatusSnapshot(t *testing.T) {
+	job, err := New("test_user", reexec.Command{Name: "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer job.cleanup()
+
+	if job.Status() != Pending {
+		t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), Pending)
+	}
+
+	listener, unregister := job.listenStatus()
+	defer unregister()
+	<-listener // the listener first receives the current status
+
+	for _, status := range []Status{Running, Exited} {
+		job.setStatus(status)
+
+		// Listeners are notified after the snapshot is updated, so a notified
+		// listener observes the latest status.
+		notified := <-listener
+		if notified != status {
+			t.Fatalf("unexpected notified status; actual: %v, expected: %v", notified, status)
+		}
+		if job.Status() != status {
+			t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), status)
+		}
+	}
+}
+
+func TestListenStatusPaused(t *testing.T) {
+	job, err := New("test_user", reexec.Command{Name: "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer job.cleanup()
+	job.setStatus(Running)
+
+	listener, unregister := job.listenStatus()
+	defer unregister()
+
+	// Repeated pauses exceed the listener's capacity; setStatus must not block
+	// and the latest statuses are retained.
+	for i := 0; i < 2*maxStatusChanges; i++ {
+		job.setStatus(Paused)
+		job.setStatus(Running)
+	}
+	job.setStatus(Exited)
+
+	var statuses []Status
+	for status := range listener {
+		statuses = append(statuses, status)
+	}
+	expected := []Status{Paused, Running, Exited}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Fatalf("unexpected statuses; actual: %v, expected: %v", statuses, expected)
+	}
+}
+
+func TestStreamOutputOffset(t *testing.T) {
+	store := output.Store{Root: t.TempDir()}
+
+	type expected struct {
+		output string
+		offset int64
+		err    error
+	}
+	tests := map[string]struct {
+		offset    int64
+		tailBytes int64
+		fromEnd   bool
+		// appended is output written after streaming begins, while the Job is
+		// running.
+		appended string
+		exp      expected
+	}{
+		"from start": {
+			offset: 0,
+			exp:    expected{output: "hello world\n", offset: 12},
+		},
+		"from middle": {
+			offset: 6,
+			exp:    expected{output: "world\n", offset: 12},
+		},
+		"at end": {
+			offset: 12,
+			exp:    expected{output: "", offset: 12},
+		},
+		"beyond end of exited job": {
+			offset: 20,
+			exp:    expected{output: "", offset: 20},
+		},
+		"from end": {
+			offset: -6,
+			exp:    expected{output: "world\n", offset: 12},
+		},
+		"from end w/ entire output": {
+			offset: -12,
+			exp:    expected{output: "hello world\n", offset: 12},
+		},
+		"before start": {
+			offset: -13,
+			exp:    expected{output: "", offset: -13, err: ErrOffsetOutOfRange},
+		},
+		"from end of running job": {
+			offset:   -6,
+			appended: "goodbye\n",
+			exp:      expected{output: "world\ngoodbye\n", offset: 20},
+		},
+		"tail": {
+			tailBytes: 6,
+			exp:       expected{output: "world\n", offset: 12},
+		},
+		"tail larger than output": {
+			tailBytes: 64,
+			exp:       expected{output: "hello world\n", offset: 12},
+		},
+		"tail of running job": {
+			tailBytes: 6,
+			appended:  "goodbye\n",
+			exp:       expected{output: "world\ngoodbye\n", offset: 20},
+		},
+		"beyond end of running job": {
+			offset:   14,
+			appended: "goodbye\n",
+			exp:      expected{output: "odbye\n", offset: 20},
+		},
+		"from end of exited job": {
+			fromEnd: true,
+			exp:     expected{output: "", offset: 0},
+		},
+		"from end of running job w/ new output": {
+			fromEnd:  true,
+			appended: "goodbye\n",
+			exp:      expected{output: "goodbye\n", offset: 20},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			job := &Job{mutex: new(sync.RWMutex), ID: uuid.New(), status: Exited, outputStore: store}
+			if err := os.WriteFile(store.File(job.ID), []byte("hello world\n"), output.FileMode); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.appended != "" {
+				job.status = Running
+				go func() {
+					defer job.setStatus(Exited)
+					time.Sleep(100 * time.Millisecond)
+					fd, err := os.OpenFile(store.File(job.ID), os.O_APPEND|os.O_WRONLY, output.FileMode)
+					if err != nil {
+						return
+					}
+					defer fd.Close()
+					fd.WriteString(test.appended)
+				}()
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			stream := make(chan Chunk)
+			errc := make(chan error, 1)
+			options := []StreamOption{
+				WithOffset(test.offset),
+				WithTailBytes(test.tailBytes),
+			}
+			if test.fromEnd {
+				options = append(options, WithFromEnd())
+			}
+			go func() {
+				errc <- job.StreamOutput(ctx, stream, 4, options...)
+				close(stream)
+			}()
+
+			var (
+				b      []byte
+				offset = test.offset
+			)
+			for chunk := range stream {
+				b = append(b, chunk.Data...)
+				offset = chunk.Offset
+			}
+			if err := <-errc; !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+
+			if string(b) != test.exp.output {
+				t.Fatalf("unexpected output; actual: %q, expected: %q", b, test.exp.output)
+			}
+			if offset != test.exp.offset {
+				t.Fatalf("unexpected offset; actual: %d, expected: %d", offset, test.exp.offset)
+			}
+		})
+	}
+}
+
+func TestStreamOutputLargeChunk(t *testing.T) {
+	store := output.Store{Root: t.TempDir()}
+
+	job := &Job{mutex: new(sync.RWMutex), ID: uuid.New(), status: Running, outputStore: store}
+	fd, err := os.OpenFile(store.File(job.ID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, output.FileMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream := make(chan Chunk)
+	go func() {
+		_ = job.StreamOutput(ctx, stream, 1<<20)
+	}()
+
+	// A few bytes are streamed promptly, both before and after the stream has
+	// reached the end of the output.
+	const prompt = 500 * time.Millisecond
+	for _, output := range []string{"hello\n", "world\n"} {
+		if _, err := fd.Write([]byte(output)); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case <-time.After(prompt):
+			t.Fatalf("output not streamed within %v; output: %q", prompt, output)
+		case chunk := <-stream:
+			if string(chunk.Data) != output {
+				t.Fatalf("unexpected chunk; actual: %q, expected: %q", chunk.Data, output)
+			}
+		}
+
+		// Allow the stream to reach the end of the output.
+		time.Sleep(2 * outputFollowInterval)
+	}
+}
+
+func TestStreamOutputResume(t *testing.T) {
+	store := output.Store{Root: t.TempDir()}
+
+	job := &Job{mutex: new(sync.RWMutex), ID: uuid.New(), status: Running, outputStore: store}
+	fd, err := os.OpenFile(store.File(job.ID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, output.FileMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected bytes.Buffer
+	for i := 0; i < 32; i++ {
+		fmt.Fprintf(&expected, "line %d\n", i)
+	}
+
+	// Write the output gradually, so streams are interrupted mid-job.
+	go func() {
+		defer job.setStatus(Exited)
+		defer fd.Close()
+		b := expected.Bytes()
+		for len(b) > 0 {
+			n := 7
+			if n > len(b) {
+				n = len(b)
+			}
+			fd.Write(b[:n])
+			b = b[n:]
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var (
+		actual []byte
+		offset int64
+	)
+	for {
+		streamCtx, streamCancel := context.WithCancel(ctx)
+		stream := make(chan Chunk)
+		errc := make(chan error, 1)
+		go func() {
+			errc <- job.StreamOutput(streamCtx, stream, 16, WithOffset(offset))
+			close(stream)
+		}()
+
+		// Interrupt the stream after the first chunk received. The stream is not
+		// read further, so no chunk may be received after the interruption.
+		if chunk, ok := <-stream; ok {
+			actual = append(actual, chunk.Data...)
+			offset = chunk.Offset
+		}
+		streamCancel()
+
+		err := <-errc
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if !bytes.Equal(actual, expected.Bytes()) {
+		t.Fatalf("unexpected output; actual: %q, expected: %q", actual, expected.Bytes())
+	}
+	if offset != int64(expected.Len()) {
+		t.Fatalf("unexpected offset; actual: %d, expected: %d", offset, expected.Len())
+	}
+}
+
+func TestStreamOutputNoFollow(t *testing.T) {
+	store := output.Store{Root: t.TempDir()}
+
+	tests := map[string]struct {
+		status Status
+	}{
+		"running job": {status: Running},
+		"exited job":  {status: Exited},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			job := &Job{mutex: new(sync.RWMutex), ID: uuid.New(), status: test.status, outputStore: store}
+			if err := os.WriteFile(store.File(job.ID), []byte("hello world\n"), output.FileMode); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			stream := make(chan Chunk)
+			errc := make(chan error, 1)
+			go func() {
+				errc <- job.StreamOutput(ctx, stream, 128, WithFollow(false))
+				close(stream)
+			}()
+
+			var b []byte
+			for chunk := range stream {
+				b = append(b, chunk.Data...)
+			}
+			if err := <-errc; err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(b) != "hello world\n" {
+				t.Fatalf("unexpected output; actual: %q, expected: %q", b, "hello world\n")
+			}
+		})
+	}
+}
+
+func TestStreamOutputNotCreated(t *testing.T) {
+	type expected struct {
+		err error
+	}
+	tests := map[string]struct {
+		status Status
+		// startedAt is when the Job's command began executing. Zero if the
+		// command never executed.
+		startedAt time.Time
+		exp       expected
+	}{
+		"pending": {
+			status: Pending,
+			exp:    expected{err: ErrOutputNotReady},
+		},
+		"exited": {
+			status: Exited,
+			exp:    expected{},
+		},
+		"failed": {
+			status: Failed,
+			exp:    expected{},
+		},
+		"exited after output removed": {
+			status:    Exited,
+			startedAt: time.Now(),
+			exp:       expected{err: ErrOutputExpired},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			job := &Job{
+				mutex:       new(sync.RWMutex),
+				ID:          uuid.New(),
+				status:      test.status,
+				startedAt:   test.startedAt,
+				outputStore: output.Store{Root: t.TempDir()},
+			}
+
+			// The stream is unbuffered and never received from; no output may be
+			// streamed.
+			err := job.StreamOutput(context.Background(), make(chan Chunk), 128)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+		})
+	}
+}
+
+func isRoot() bool {
+	return os.Getegid() == 0
+}
@@ -7,11 +7,14 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/tjper/teleport/internal/jobworker/cgroup"
 	"github.com/tjper/teleport/internal/jobworker/output"
 	"github.com/tjper/teleport/internal/log"
+	"github.com/tjper/teleport/internal/metrics"
 
 	"github.com/google/uuid"
 	"golang.org/x/sys/unix"
@@ -20,6 +23,34 @@ import (
 // logger is an object for logging package events to stdout.
 var logger = log.New(os.Stdout, "job")
 
+// JobsEscalated counts the Jobs killed with SIGKILL after not exiting within
+// the grace period of a graceful stop, e.g. because they ignore SIGTERM.
+var JobsEscalated = metrics.NewCounter(
+	"jobworker_jobs_escalated_total",
+	"Jobs killed with SIGKILL after not exiting within the stop grace period.",
+)
+
+// JobsTotal counts the Jobs that have finished, by terminal status.
+var JobsTotal = metrics.NewCounterVec(
+	"jobworker_jobs_total",
+	"Jobs that have finished, by terminal status.",
+	"status",
+)
+
+// JobsRunning is the number of Jobs whose executable is running.
+var JobsRunning = metrics.NewGauge(
+	"jobworker_jobs_running",
+	"Jobs whose executable is running.",
+)
+
+// JobDuration observes the seconds between a Job's command starting and the
+// Job finishing. Jobs whose command never started are not observed.
+var JobDuration = metrics.NewHistogram(
+	"jobworker_job_duration_seconds",
+	"Seconds between a job's command starting and the job finishing.",
+	[]float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 600, 1800, 3600},
+)
+
 var (
 	// ErrServiceClosing indicates a StartJob call was made while the Service
 	// was closing down.
@@ -32,6 +63,30 @@ var (
 
 	// ErrJobNotFound indicates the Job is not accessible through the Service.
 	ErrJobNotFound = errors.New("job not found")
+
+	// ErrMaxJobs indicates a StartJob call was made while the maximum number of
+	// Jobs were running.
+	ErrMaxJobs = errors.New("max jobs running")
+
+	// ErrQuotaExceeded indicates a StartJob call was made while the Job's owner
+	// had the maximum number of Jobs per owner running.
+	ErrQuotaExceeded = errors.New("job quota exceeded")
+
+	// ErrJobNotRunning indicates a PauseJob call was made for a Job that was
+	// not running.
+	ErrJobNotRunning = errors.New("job not running")
+
+	// ErrJobNotPaused indicates a ResumeJob call was made for a Job that was
+	// not paused.
+	ErrJobNotPaused = errors.New("job not paused")
+
+	// ErrJobActive indicates a DeleteJob or RestartJob call was made for a Job
+	// that had not finished.
+	ErrJobActive = errors.New("job active")
+
+	// ErrEnvNotRecorded indicates a RestartJob call was made for a restored Job
+	// whose environment variables were not recorded. See WithStateDir.
+	ErrEnvNotRecorded = errors.New("job environment not recorded")
 )
 
 // ICgroupService specifies Service interactions with cgroup.
@@ -39,20 +94,116 @@ type ICgroupService interface {
 	CreateCgroup(...cgroup.CgroupOption) (*cgroup.Cgroup, error)
 	PlaceInCgroup(cgroup.Cgroup, int) error
 	RemoveCgroup(uuid.UUID) error
+	ReadStats(cgroup.Cgroup) (*cgroup.Stats, error)
+	ReadDetailedStats(cgroup.Cgroup) (*cgroup.DetailedStats, error)
+	Freeze(cgroup.Cgroup, bool) error
 }
 
 // NewService creates a new Service intance.
-func NewService(cgroups ICgroupService) (*Service, error) {
-	if err := os.MkdirAll(output.Root, output.FileMode); err != nil {
-		return nil, fmt.Errorf("mkdir job service output; path: %v, error: %w", output.Root, err)
+func NewService(cgroups ICgroupService, options ...ServiceOption) (*Service, error) {
+	// Open the current executable so Jobs re-execute the same binary, even if
+	// the file at its path is replaced or removed while the Service is running
+	// (e.g. during an upgrade).
+	path, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("fetch current exec; error: %w", err)
+	}
+	executable, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open current exec; path: %v, error: %w", path, err)
+	}
+
+	s := &Service{
+		mutex:      new(sync.RWMutex),
+		stateMutex: new(sync.Mutex),
+		evictMutex: new(sync.Mutex),
+		deletions:  newDeletions(),
+		healthy:    true,
+		jobs:       new(sync.Map),
+		jobCgroups: new(sync.Map),
+		cgroups:    cgroups,
+		executable: executable,
+		ownerJobs:  make(map[string]int),
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	if err := s.setup(); err != nil {
+		// Release the executable, and any deletions scheduled for restored
+		// Jobs, as the Service will not be closed.
+		s.deletions.close()
+		s.executable.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// setup creates the Service's output directory and restores the Jobs recorded
+// within its state directory, if configured.
+func (s *Service) setup() error {
+	outputDir := s.outputStore.Dir()
+	if err := os.MkdirAll(outputDir, output.FileMode); err != nil {
+		return fmt.Errorf("mkdir job service output; path: %v, error: %w", outputDir, err)
+	}
+
+	if s.stateDir != "" {
+		return s.restoreJobs()
 	}
+	return nil
+}
+
+// ServiceOption mutates the Service instance. This is typically used for
+// configuration with NewService.
+type ServiceOption func(*Service)
+
+// WithMaxJobs configures the Service instance to run at most max Jobs at
+// once. StartJob calls beyond max fail with ErrMaxJobs. If max is 0, the
+// number of Jobs is unlimited.
+func WithMaxJobs(max int) ServiceOption {
+	return func(s *Service) { s.maxJobs = max }
+}
+
+// WithPerOwnerLimit configures the Service instance to run at most limit Jobs
+// per owner at once. StartJob calls beyond limit fail with ErrQuotaExceeded.
+// If limit is 0, the number of Jobs per owner is unlimited.
+func WithPerOwnerLimit(limit int) ServiceOption {
+	return func(s *Service) { s.perOwnerLimit = limit }
+}
+
+// WithOutputRetention configures the Service instance to delete finished Jobs,
+// and their output, once retention has elapsed since they finished, see
+// Service.DeleteJob. If retention is 0, finished Jobs are retained until
+// deleted explicitly.
+func WithOutputRetention(retention time.Duration) ServiceOption {
+	return func(s *Service) { s.outputRetention = retention }
+}
+
+// WithMaxRetainedJobs configures the Service instance to retain at most max
+// finished Jobs. Once a Job finishes beyond max, the Jobs that finished
+// earliest are deleted, and their output purged, see Service.DeleteJob. If
+// max is 0 or negative, the number of finished Jobs retained is unlimited.
+func WithMaxRetainedJobs(max int) ServiceOption {
+	return func(s *Service) { s.maxRetainedJobs = max }
+}
 
-	return &Service{
-		mutex:   new(sync.RWMutex),
-		healthy: true,
-		jobs:    new(sync.Map),
-		cgroups: cgroups,
-	}, nil
+// WithStateDir configures the Service instance to record the metadata of each
+// Job within dir, and to restore the Jobs recorded by a previous Service. Jobs
+// that were active when the previous Service stopped are restored Stopped, as
+// their executables did not outlive it. The values of environment variables
+// are not recorded, as they may hold secrets, so restored Jobs whose command
+// set environment variables may not be restarted. If dir is empty, Jobs are
+// not recorded.
+func WithStateDir(dir string) ServiceOption {
+	return func(s *Service) { s.stateDir = dir }
+}
+
+// WithOutputDir configures the Service instance to write the output of Jobs
+// within dir, creating dir if it does not exist. If dir is empty, output is
+// written within output.DefaultRoot.
+func WithOutputDir(dir string) ServiceOption {
+	return func(s *Service) { s.outputStore = output.Store{Root: dir} }
 }
 
 // Service facilitates job interactions.
@@ -61,10 +212,48 @@ type Service struct {
 	// healthy indicates if Service is accepting to jobs to start.
 	healthy bool
 	// jobs is an mapping of Job.ID keys to *Job instances. The sync.Map type has
-	// been used because the data structure is always expanding and deletes never
-	// occur.
-	jobs    *sync.Map
-	cgroups ICgroupService
+	// been used because the data structure is mostly expanding; entries are
+	// only deleted when Jobs are deleted.
+	jobs *sync.Map
+	// jobCgroups is a mapping of Job.ID keys to the cgroup.Cgroup instances the
+	// Jobs are running within. Entries are deleted once a Job's cgroup is
+	// removed.
+	jobCgroups *sync.Map
+	cgroups    ICgroupService
+	// executable is the jobworker executable opened at Service creation. Jobs
+	// execute it by its /proc/<pid>/fd path.
+	executable *os.File
+	// maxJobs is the maximum number of Jobs that may be running at once. 0
+	// indicates no maximum.
+	maxJobs int
+	// activeJobs is the number of Jobs that have been started and have not
+	// yet stopped, exited, or failed.
+	activeJobs int
+	// perOwnerLimit is the maximum number of Jobs per owner that may be
+	// running at once. 0 indicates no maximum.
+	perOwnerLimit int
+	// ownerJobs is a mapping of Job.Owner keys to the number of active Jobs of
+	// the owner. Owners without active Jobs are deleted.
+	ownerJobs map[string]int
+	// outputRetention is the duration finished Jobs are retained before being
+	// deleted. 0 indicates finished Jobs are retained until deleted explicitly.
+	outputRetention time.Duration
+	// maxRetainedJobs is the maximum number of finished Jobs retained. 0 or
+	// less indicates no maximum.
+	maxRetainedJobs int
+	// evictMutex serializes deletions of finished Jobs beyond
+	// maxRetainedJobs, see evictJobs.
+	evictMutex *sync.Mutex
+	// deletions are the pending deletions of finished Jobs once
+	// outputRetention elapses, see scheduleDelete.
+	deletions *deletions
+	// stateDir is the directory Job metadata is recorded within. Empty if Job
+	// metadata is not recorded.
+	stateDir string
+	// stateMutex serializes writes of Job records, see saveJob.
+	stateMutex *sync.Mutex
+	// outputStore locates the output of Jobs started by the Service.
+	outputStore output.Store
 }
 
 // StartJob starts the job.
@@ -77,33 +266,73 @@ func (s *Service) StartJob(_ context.Context, job Job, options ...cgroup.CgroupO
 	if _, ok := s.jobs.Load(job.ID); ok {
 		return fmt.Errorf("%w; job: %v", ErrJobAlreadyStarted, job.ID)
 	}
+
+	if err := s.acquireJob(job.Owner); err != nil {
+		return err
+	}
+	// The Service manages its own copy of job, whose status must not be
+	// observed through the caller's copy.
+	job.statusSnapshot = newStatusSnapshot(job.status)
+	job.cgroupOptions = options
+	job.outputStore = s.outputStore
 	s.jobs.Store(job.ID, &job)
 
-	cgroup, err := s.cgroups.CreateCgroup(options...)
+	// A Job that fails to start is forgotten, and its resources released, as
+	// no goroutine waits on it.
+	jobCgroup, err := s.cgroups.CreateCgroup(options...)
 	if err != nil {
+		s.jobs.Delete(job.ID)
+		job.cleanup()
+		s.releaseJob(job.Owner)
 		return err
 	}
 
-	if err := job.start(); err != nil {
+	if err := job.start(s.executablePath()); err != nil {
+		s.jobs.Delete(job.ID)
+		job.cleanup()
+		s.releaseJob(job.Owner)
+		if err := s.cgroups.RemoveCgroup(jobCgroup.ID); err != nil {
+			logger.Errorf("%v; job: %v, cgroup: %v", err, job.ID, jobCgroup.ID)
+		}
 		return err
 	}
+	s.jobCgroups.Store(job.ID, *jobCgroup)
+	if s.stateDir != "" {
+		// Record the Job once its command is executing, so a restored Job
+		// retains when it started.
+		go func() {
+			<-job.started
+			if err := s.saveJob(&job); err != nil {
+				logger.Errorf("%v; job: %v", err, job.ID)
+			}
+		}()
+	}
 	go func() {
 		// Goroutine terminates when job is stopped or exits. This can occur
 		// because the job executable exits or is terminated. To cleanup all jobs
 		// see Service.Close.
 		defer job.cleanup()
 
-		if err := job.wait(); err != nil {
+		readStats := func() (*cgroup.Stats, error) { return s.cgroups.ReadStats(*jobCgroup) }
+		release := func() { s.releaseJob(job.Owner) }
+		if err := job.wait(readStats, release); err != nil {
 			logger.Errorf("%v; job: %v", err, job.ID)
 		}
 
-		if err := s.cgroups.RemoveCgroup(cgroup.ID); err != nil {
-			logger.Errorf("%v; job: %v, cgroup: %v", err, job.ID, cgroup.ID)
+		s.jobCgroups.Delete(job.ID)
+		if err := s.cgroups.RemoveCgroup(jobCgroup.ID); err != nil {
+			logger.Errorf("%v; job: %v, cgroup: %v", err, job.ID, jobCgroup.ID)
+		}
+
+		if err := s.saveJob(&job); err != nil {
+			logger.Errorf("%v; job: %v", err, job.ID)
 		}
+		s.scheduleDelete(&job)
+		s.evictJobs()
 	}()
 
 	// Place Job executable's process within Cgroup.
-	if err := s.cgroups.PlaceInCgroup(*cgroup, job.pid()); err != nil {
+	if err := s.cgroups.PlaceInCgroup(*jobCgroup, job.pid()); err != nil {
 		job.stop()
 		return err
 	}
@@ -113,29 +342,391 @@ func (s *Service) StartJob(_ context.Context, job Job, options ...cgroup.CgroupO
 		return err
 	}
 
+	// Return once the Job's command is executing, or, if the command failed to
+	// execute, once the Job has finished, so its status reflects the failure.
+	<-job.started
+	if job.Status() == Pending {
+		<-job.done
+	}
+
+	return nil
+}
+
+// acquireJob reserves one of the Service's running Jobs for owner. If the
+// maximum number of Jobs are running, ErrMaxJobs is returned. If the maximum
+// number of Jobs of owner are running, ErrQuotaExceeded is returned. Each
+// successful acquireJob call must be followed by a releaseJob call once the
+// Job is no longer running.
+func (s *Service) acquireJob(owner string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.maxJobs > 0 && s.activeJobs >= s.maxJobs {
+		return fmt.Errorf("%w; max: %d", ErrMaxJobs, s.maxJobs)
+	}
+	if s.perOwnerLimit > 0 && s.ownerJobs[owner] >= s.perOwnerLimit {
+		return fmt.Errorf("%w; owner: %s, limit: %d", ErrQuotaExceeded, owner, s.perOwnerLimit)
+	}
+	s.activeJobs++
+	s.ownerJobs[owner]++
 	return nil
 }
 
-// StopJob stops the Job associated with the passed job ID.
-func (s Service) StopJob(_ context.Context, id uuid.UUID) error {
+// releaseJob releases a running Job of owner reserved by acquireJob.
+func (s *Service) releaseJob(owner string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.activeJobs--
+	if s.ownerJobs[owner]--; s.ownerJobs[owner] <= 0 {
+		delete(s.ownerJobs, owner)
+	}
+}
+
+// StopJob stops the Job associated with the passed job ID. If grace is
+// non-zero, the Job is sent SIGTERM and given up to grace to exit before it is
+// killed. Otherwise, the Job is killed immediately. StopJob returns once the Job
+// has exited.
+func (s Service) StopJob(ctx context.Context, id uuid.UUID, grace time.Duration) error {
 	job, err := s.loadJob(id)
 	if err != nil {
 		return err
 	}
-	if job.Status() != Running {
+	status := job.Status()
+	if !status.active() {
 		return nil
 	}
+	// A paused Job's processes must be thawed to handle the signals stopping
+	// them.
+	if status == Paused {
+		if err := s.ResumeJob(ctx, id); err != nil && !errors.Is(err, ErrJobNotPaused) {
+			return err
+		}
+	}
+
+	if grace > 0 {
+		return job.stopGracefully(ctx, grace)
+	}
 
 	job.stop()
+	<-job.done
+
+	return nil
+}
+
+// PauseJob pauses the Job associated with the passed job ID by freezing its
+// cgroup; the Job's processes are suspended until ResumeJob is called. If the
+// Job is not running, ErrJobNotRunning is returned.
+func (s Service) PauseJob(_ context.Context, id uuid.UUID) error {
+	return s.freezeJob(id, true)
+}
+
+// ResumeJob resumes the Job associated with the passed job ID by thawing its
+// cgroup. If the Job is not paused, ErrJobNotPaused is returned.
+func (s Service) ResumeJob(_ context.Context, id uuid.UUID) error {
+	return s.freezeJob(id, false)
+}
+
+// freezeJob freezes the cgroup of the Job associated with id if frozen is true,
+// and sets the Job Paused. Otherwise, the cgroup is thawed and the Job is set
+// Running.
+func (s Service) freezeJob(id uuid.UUID, frozen bool) error {
+	job, err := s.loadJob(id)
+	if err != nil {
+		return err
+	}
+
+	from, to, errStatus := Running, Paused, ErrJobNotRunning
+	if !frozen {
+		from, to, errStatus = Paused, Running, ErrJobNotPaused
+	}
+
+	// Freezes are serialized, so a Job's cgroup is frozen if and only if the
+	// Job is Paused.
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	value, ok := s.jobCgroups.Load(id)
+	if !ok {
+		return fmt.Errorf("%w; job: %v", errStatus, id)
+	}
+	jobCgroup, ok := value.(cgroup.Cgroup)
+	if !ok {
+		return fmt.Errorf("type check job cgroup; job: %v", id)
+	}
+
+	// The status is transitioned prior to freezing, so the Job may not
+	// concurrently exit and be set Paused.
+	if !job.transitionStatus(from, to) {
+		return fmt.Errorf("%w; job: %v, status: %v", errStatus, id, job.Status())
+	}
+	if err := s.cgroups.Freeze(jobCgroup, frozen); err != nil {
+		job.transitionStatus(to, from)
+		return err
+	}
 
 	return nil
 }
 
+// WaitJob blocks until the Job associated with the passed job ID has stopped
+// or exited, or ctx is cancelled.
+func (s Service) WaitJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	job, err := s.loadJob(id)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-job.done:
+		return job, nil
+	}
+}
+
+// DeleteJob removes the finished Job associated with the passed job ID from
+// the Service, and purges the Job's output. Output being streamed is removed
+// once its streams complete. If the Job has not stopped, exited, or failed,
+// ErrJobActive is returned.
+func (s Service) DeleteJob(_ context.Context, id uuid.UUID) error {
+	job, err := s.loadJob(id)
+	if err != nil {
+		return err
+	}
+	// A terminal status is final, so the Job may not become active once
+	// checked.
+	if status := job.Status(); !status.terminal() {
+		return fmt.Errorf("%w; job: %v, status: %v", ErrJobActive, id, status)
+	}
+
+	// Only the caller removing the Job purges it.
+	if _, ok := s.jobs.LoadAndDelete(id); !ok {
+		return fmt.Errorf("delete job; job: %v, err: %w", id, ErrJobNotFound)
+	}
+	job.purge()
+	s.deletions.cancel(id)
+	if err := s.removeJob(id); err != nil {
+		logger.Errorf("%v; job: %v", err, id)
+	}
+
+	return nil
+}
+
+// evictJobs deletes the Jobs that finished earliest, until the Service holds
+// at most maxRetainedJobs finished Jobs. If the Service retains any number of
+// finished Jobs, evictJobs does nothing.
+func (s Service) evictJobs() {
+	if s.maxRetainedJobs <= 0 {
+		return
+	}
+
+	s.evictMutex.Lock()
+	defer s.evictMutex.Unlock()
+
+	var finished []*Job
+	s.jobs.Range(func(_, value interface{}) bool {
+		job, ok := value.(*Job)
+		if ok && job.Status().terminal() {
+			finished = append(finished, job)
+		}
+		return true
+	})
+	if len(finished) <= s.maxRetainedJobs {
+		return
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].FinishedAt().Before(finished[j].FinishedAt())
+	})
+	for _, job := range finished[:len(finished)-s.maxRetainedJobs] {
+		err := s.DeleteJob(context.Background(), job.ID)
+		if err != nil && !errors.Is(err, ErrJobNotFound) {
+			logger.Errorf("evicting job; job: %v, error: %v", job.ID, err)
+		}
+	}
+}
+
+// scheduleDelete deletes the finished Job once the Service's output retention
+// has elapsed since the Job finished. If the Service retains Jobs until they
+// are deleted explicitly, scheduleDelete does nothing.
+func (s Service) scheduleDelete(job *Job) {
+	if s.outputRetention == 0 {
+		return
+	}
+
+	retention := s.outputRetention
+	if finishedAt := job.FinishedAt(); !finishedAt.IsZero() {
+		retention -= time.Since(finishedAt)
+	}
+	s.deletions.schedule(job.ID, retention, func() {
+		err := s.DeleteJob(context.Background(), job.ID)
+		if err != nil && !errors.Is(err, ErrJobNotFound) {
+			logger.Errorf("deleting job; job: %v, error: %v", job.ID, err)
+		}
+	})
+}
+
+// newDeletions creates a deletions instance.
+func newDeletions() *deletions {
+	return &deletions{timers: make(map[uuid.UUID]*time.Timer)}
+}
+
+// deletions tracks the scheduled deletions of finished Jobs, so they may be
+// cancelled once the Job is deleted or the Service is closed.
+type deletions struct {
+	mutex  sync.Mutex
+	timers map[uuid.UUID]*time.Timer
+	// closed indicates the Service has been closed; no deletions are scheduled
+	// or run once closed.
+	closed bool
+}
+
+// schedule calls deleteJob once after has elapsed, unless the deletion of the Job
+// identified by id is cancelled first.
+func (d *deletions) schedule(id uuid.UUID, after time.Duration, deleteJob func()) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.closed {
+		return
+	}
+
+	d.timers[id] = time.AfterFunc(after, func() {
+		d.mutex.Lock()
+		if d.closed {
+			d.mutex.Unlock()
+			return
+		}
+		delete(d.timers, id)
+		d.mutex.Unlock()
+
+		deleteJob()
+	})
+}
+
+// cancel cancels the scheduled deletion of the Job identified by id, if any.
+func (d *deletions) cancel(id uuid.UUID) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if timer, ok := d.timers[id]; ok {
+		timer.Stop()
+		delete(d.timers, id)
+	}
+}
+
+// close cancels all scheduled deletions, and prevents further deletions from
+// being scheduled.
+func (d *deletions) close() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.closed = true
+	for id, timer := range d.timers {
+		timer.Stop()
+		delete(d.timers, id)
+	}
+}
+
+// RestartJob starts a new Job running the same command, with the same owner,
+// options, and limits, as the finished Job associated with the passed job ID.
+// The new Job is returned. If the Job has not stopped, exited, or failed,
+// ErrJobActive is returned. If the Job was restored, and its command's
+// environment was not recorded, ErrEnvNotRecorded is returned.
+func (s *Service) RestartJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	job, err := s.loadJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if status := job.Status(); !status.terminal() {
+		return nil, fmt.Errorf("%w; job: %v, status: %v", ErrJobActive, id, status)
+	}
+	if len(job.envKeys) > 0 {
+		return nil, fmt.Errorf("%w; job: %v, env keys: %v", ErrEnvNotRecorded, id, job.envKeys)
+	}
+
+	restarted, err := New(
+		job.Owner,
+		job.cmd,
+		WithTimeout(job.timeout),
+		WithMaxOutputBytes(job.maxOutputBytes),
+		WithKillOnMaxOutput(job.killOnMaxOutput),
+		WithOutputRotateBytes(job.outputRotateBytes),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.StartJob(ctx, *restarted, job.cgroupOptions...); err != nil {
+		return nil, err
+	}
+
+	return s.loadJob(restarted.ID)
+}
+
 // FetchJob retrieves the Job associated with the passed job ID.
 func (s Service) FetchJob(_ context.Context, id uuid.UUID) (*Job, error) {
 	return s.loadJob(id)
 }
 
+// FetchUsage retrieves the resource usage of the Job associated with the
+// passed job ID. If the Job is running, its current usage is read from its
+// cgroup. Otherwise, the usage captured when the Job finished is returned,
+// which is nil if it could not be captured.
+func (s Service) FetchUsage(_ context.Context, id uuid.UUID) (*cgroup.Stats, error) {
+	i, ok := s.jobCgroups.Load(id)
+	if !ok {
+		job, err := s.loadJob(id)
+		if err != nil {
+			return nil, err
+		}
+		return job.Usage(), nil
+	}
+
+	jobCgroup, ok := i.(cgroup.Cgroup)
+	if !ok {
+		return nil, fmt.Errorf("type check job cgroup; job: %v", id)
+	}
+
+	stats, err := s.cgroups.ReadStats(jobCgroup)
+	if errors.Is(err, cgroup.ErrCgroupNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read job usage; job: %v, error: %w", id, err)
+	}
+
+	return stats, nil
+}
+
+// FetchStats retrieves the pressure and usage statistics of the Job associated
+// with the passed job ID, read from its cgroup. Statistics are only available
+// while the Job is active; otherwise ErrJobNotRunning is returned.
+func (s Service) FetchStats(_ context.Context, id uuid.UUID) (*cgroup.DetailedStats, error) {
+	job, err := s.loadJob(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// A finished Job's cgroup is retained until its usage is captured, so the
+	// Job's status is checked rather than relying on the cgroup's absence.
+	i, ok := s.jobCgroups.Load(id)
+	if !ok || job.Status().terminal() {
+		return nil, fmt.Errorf("%w; job: %v", ErrJobNotRunning, id)
+	}
+
+	jobCgroup, ok := i.(cgroup.Cgroup)
+	if !ok {
+		return nil, fmt.Errorf("type check job cgroup; job: %v", id)
+	}
+
+	stats, err := s.cgroups.ReadDetailedStats(jobCgroup)
+	// The Job may have finished and its cgroup been removed since the cgroup
+	// was loaded.
+	if errors.Is(err, cgroup.ErrCgroupNotFound) && job.Status().terminal() {
+		return nil, fmt.Errorf("%w; job: %v", ErrJobNotRunning, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read job stats; job: %v, error: %w", id, err)
+	}
+
+	return stats, nil
+}
+
 // Close releases all Service resources. Close should always be called when
 // job.Service is no longer being used.
 func (s *Service) Close() error {
@@ -143,6 +734,9 @@ func (s *Service) Close() error {
 	s.healthy = false
 	s.mutex.Unlock()
 
+	// Finished Jobs are no longer deleted once the Service is closed.
+	s.deletions.close()
+
 	s.jobs.Range(func(key, value interface{}) bool {
 		i, ok := s.jobs.Load(key)
 		if !ok {
@@ -158,8 +752,13 @@ func (s *Service) Close() error {
 		return true
 	})
 
-	if err := unix.Rmdir(output.Root); err != nil {
-		return fmt.Errorf("rmdir job service output; path: %v, error: %w", output.Root, err)
+	if err := s.executable.Close(); err != nil {
+		return fmt.Errorf("close job service exec; error: %w", err)
+	}
+
+	outputDir := s.outputStore.Dir()
+	if err := unix.Rmdir(outputDir); err != nil {
+		return fmt.Errorf("rmdir job service output; path: %v, error: %w", outputDir, err)
 	}
 
 	return nil
@@ -179,6 +778,14 @@ func (s Service) loadJob(id uuid.UUID) (*Job, error) {
 	return job, nil
 }
 
+// executablePath retrieves a path to the Service's executable that remains
+// valid while the executable is open. The path is resolved through the
+// Service's pid rather than /proc/self, as the path is resolved by the forked
+// child, where the fd may be replaced by the child's ExtraFiles.
+func (s Service) executablePath() string {
+	return fmt.Sprintf("/proc/%d/fd/%d", os.Getpid(), s.executable.Fd())
+}
+
 func (s Service) isHealthy() bool {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
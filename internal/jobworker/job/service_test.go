@@ -0,0 +1,2328 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tjper/teleport/internal/jobworker"
+	"github.com/tjper/teleport/internal/jobworker/cgroup"
+	"github.com/tjper/teleport/internal/jobworker/output"
+	"github.com/tjper/teleport/internal/jobworker/reexec"
+
+	"github.com/google/uuid"
+)
+
+// TestMain allows the test binary to act as the jobworker reexec child. Job
+// executables re-execute the current binary with the reexec subcommand.
+func TestMain(m *testing.M) {
+	if os.Args[len(os.Args)-1] == jobworker.Reexec {
+		exitCode, err := reexec.Exec(context.Background())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "reexec; error: %s\n", err)
+		}
+		os.Exit(exitCode)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestStopJob(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	type expected struct {
+		status Status
+		reason StoppedReason
+		signal syscall.Signal
+		// suffix is the expected end of the Job's output.
+		suffix string
+		// escalated indicates the Job is expected to be killed after its grace
+		// period.
+		escalated bool
+	}
+	tests := map[string]struct {
+		cmd   reexec.Command
+		grace time.Duration
+		exp   expected
+	}{
+		"kill": {
+			cmd: reexec.Command{
+				Name: "bash",
+				Args: []string{"-c", `trap "echo cleanup; exit 0" TERM; echo ready; while true; do sleep 0.1; done`},
+			},
+			exp: expected{status: Stopped, reason: Manual, signal: syscall.SIGKILL, suffix: "ready\n"},
+		},
+		"graceful w/ SIGTERM trap": {
+			cmd: reexec.Command{
+				Name: "bash",
+				Args: []string{"-c", `trap "echo cleanup; exit 0" TERM; echo ready; while true; do sleep 0.1; done`},
+			},
+			grace: 5 * time.Second,
+			exp:   expected{status: Stopped, reason: Manual, suffix: "cleanup\n"},
+		},
+		"graceful reaches descendants": {
+			cmd: reexec.Command{
+				Name: "bash",
+				Args: []string{"-c", `trap "wait \$child; exit 0" TERM; bash -c 'trap "echo child cleanup; exit 0" TERM; echo ready; while true; do sleep 0.1; done' & child=$!; wait`},
+			},
+			grace: 5 * time.Second,
+			exp:   expected{status: Stopped, reason: Manual, suffix: "child cleanup\n"},
+		},
+		"graceful w/ SIGTERM ignored": {
+			cmd: reexec.Command{
+				Name: "bash",
+				Args: []string{"-c", `trap "" TERM; echo ready; while true; do sleep 0.1; done`},
+			},
+			grace: 200 * time.Millisecond,
+			exp: expected{
+				status:    Stopped,
+				reason:    Manual,
+				signal:    syscall.SIGKILL,
+				suffix:    "ready\n",
+				escalated: true,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := newTestService(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			job := startTestJob(ctx, t, service, test.cmd)
+			waitForOutput(ctx, t, job, "ready\n")
+
+			escalated := JobsEscalated.Value()
+			if err := service.StopJob(ctx, job.ID, test.grace); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				t.Fatal("job did not exit")
+			case <-job.done:
+			}
+
+			if job.Status() != test.exp.status {
+				t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), test.exp.status)
+			}
+			if job.StoppedReason() != test.exp.reason {
+				t.Fatalf("unexpected stopped reason; actual: %v, expected: %v", job.StoppedReason(), test.exp.reason)
+			}
+			if job.Signal() != test.exp.signal {
+				t.Fatalf("unexpected signal; actual: %v, expected: %v", job.Signal(), test.exp.signal)
+			}
+			if actual := JobsEscalated.Value() > escalated; actual != test.exp.escalated {
+				t.Fatalf("unexpected escalation; actual: %v, expected: %v", actual, test.exp.escalated)
+			}
+
+			b, err := os.ReadFile(job.outputStore.File(job.ID))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasSuffix(b, []byte(test.exp.suffix)) {
+				t.Fatalf("unexpected output; actual: %q, expected suffix: %q", b, test.exp.suffix)
+			}
+		})
+	}
+}
+
+func TestJobExit(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	type expected struct {
+		status   Status
+		exitCode int
+		signal   syscall.Signal
+		// setupError indicates the command is expected to fail setup.
+		setupError bool
+	}
+	tests := map[string]struct {
+		cmd reexec.Command
+		exp expected
+	}{
+		"exit 0": {
+			cmd: reexec.Command{Name: "true"},
+			exp: expected{status: Exited, exitCode: 0, signal: 0},
+		},
+		"exit 3": {
+			cmd: reexec.Command{Name: "bash", Args: []string{"-c", "exit 3"}},
+			exp: expected{status: Exited, exitCode: 3, signal: 0},
+		},
+		"SIGSEGV": {
+			cmd: reexec.Command{Name: "bash", Args: []string{"-c", "kill -SEGV $$"}},
+			exp: expected{status: Stopped, exitCode: noExit, signal: syscall.SIGSEGV},
+		},
+		"SIGTERM": {
+			cmd: reexec.Command{Name: "bash", Args: []string{"-c", "kill -TERM $$"}},
+			exp: expected{status: Stopped, exitCode: noExit, signal: syscall.SIGTERM},
+		},
+		"SIGKILL": {
+			cmd: reexec.Command{Name: "bash", Args: []string{"-c", "kill -KILL $$"}},
+			exp: expected{status: Stopped, exitCode: noExit, signal: syscall.SIGKILL},
+		},
+		"exit 100": {
+			cmd: reexec.Command{Name: "bash", Args: []string{"-c", "exit 100"}},
+			exp: expected{status: Exited, exitCode: reexec.CommandFailure, signal: 0},
+		},
+		"command not found": {
+			cmd: reexec.Command{Name: "definitely-not-a-binary"},
+			exp: expected{
+				status:     Failed,
+				exitCode:   noExit,
+				signal:     0,
+				setupError: true,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := newTestService(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			job := startTestJob(ctx, t, service, test.cmd)
+
+			select {
+			case <-ctx.Done():
+				t.Fatal("job did not exit")
+			case <-job.done:
+			}
+
+			if job.Status() != test.exp.status {
+				t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), test.exp.status)
+			}
+			if job.ExitCode() != test.exp.exitCode {
+				t.Fatalf("unexpected exit code; actual: %v, expected: %v", job.ExitCode(), test.exp.exitCode)
+			}
+			if job.Signal() != test.exp.signal {
+				t.Fatalf("unexpected signal; actual: %v, expected: %v", job.Signal(), test.exp.signal)
+			}
+			if setupError := job.SetupError() != ""; setupError != test.exp.setupError {
+				t.Fatalf("unexpected setup error; actual: %q, expected: %v", job.SetupError(), test.exp.setupError)
+			}
+		})
+	}
+}
+
+func TestJobOutOfMemory(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	type expected struct {
+		reason StoppedReason
+	}
+	tests := map[string]struct {
+		oomKills uint64
+		exp      expected
+	}{
+		"killed by oom killer": {
+			oomKills: 1,
+			exp:      expected{reason: OutOfMemory},
+		},
+		"killed by another process": {
+			oomKills: 0,
+			exp:      expected{reason: ""},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := newTestServiceWithCgroups(t, fakeCgroupService{
+				stats: &cgroup.Stats{OomKills: test.oomKills},
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			job := startTestJob(ctx, t, service, reexec.Command{Name: "bash", Args: []string{"-c", "kill -KILL $$"}})
+
+			select {
+			case <-ctx.Done():
+				t.Fatal("job did not exit")
+			case <-job.done:
+			}
+
+			if job.Status() != Stopped {
+				t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), Stopped)
+			}
+			if job.Signal() != syscall.SIGKILL {
+				t.Fatalf("unexpected signal; actual: %v, expected: %v", job.Signal(), syscall.SIGKILL)
+			}
+			if job.StoppedReason() != test.exp.reason {
+				t.Fatalf("unexpected reason; actual: %v, expected: %v", job.StoppedReason(), test.exp.reason)
+			}
+		})
+	}
+}
+
+func TestJobOomKilled(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	cgroups, err := cgroup.NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := cgroups.Cleanup(); err != nil {
+			t.Error(err)
+		}
+	})
+	service := newTestServiceWithCgroups(t, cgroups)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// tail buffers its input until a newline is read, exceeding the memory
+	// limit.
+	j, err := New("test_user", reexec.Command{Name: "bash", Args: []string{"-c", "head -c 256M /dev/zero | tail"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.StartJob(ctx, *j, cgroup.WithMemoryMax(16<<20)); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := service.WaitJob(ctx, j.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !job.OomKilled() {
+		t.Fatalf("unexpected oom killed; actual: %v, expected: %v", job.OomKilled(), true)
+	}
+	if job.Status() != Stopped {
+		t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), Stopped)
+	}
+	if job.StoppedReason() != OutOfMemory {
+		t.Fatalf("unexpected reason; actual: %v, expected: %v", job.StoppedReason(), OutOfMemory)
+	}
+}
+
+func TestJobOutputLimit(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	const limit = 1 << 20
+
+	type expected struct {
+		status Status
+		reason StoppedReason
+	}
+	tests := map[string]struct {
+		cmd  reexec.Command
+		kill bool
+		exp  expected
+	}{
+		"killed": {
+			cmd:  reexec.Command{Name: "yes"},
+			kill: true,
+			exp:  expected{status: Stopped, reason: OutputLimit},
+		},
+		"truncated": {
+			cmd: reexec.Command{Name: "head", Args: []string{"-c", "2M", "/dev/zero"}},
+			exp: expected{status: Exited},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := newTestService(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			j, err := New("test_user", test.cmd, WithMaxOutputBytes(limit), WithKillOnMaxOutput(test.kill))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := service.StartJob(ctx, *j); err != nil {
+				t.Fatal(err)
+			}
+
+			job, err := service.WaitJob(ctx, j.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if job.Status() != test.exp.status {
+				t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), test.exp.status)
+			}
+			if job.StoppedReason() != test.exp.reason {
+				t.Fatalf("unexpected reason; actual: %v, expected: %v", job.StoppedReason(), test.exp.reason)
+			}
+			if !job.OutputTruncated() {
+				t.Fatalf("unexpected output truncated; actual: %v, expected: %v", job.OutputTruncated(), true)
+			}
+			size, err := job.OutputSize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := int64(limit + len(output.TruncatedNotice)); size != expected {
+				t.Fatalf("unexpected output size; actual: %d, expected: %d", size, expected)
+			}
+		})
+	}
+}
+
+func TestJobTimeout(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	type expected struct {
+		status Status
+		reason StoppedReason
+	}
+	tests := map[string]struct {
+		cmd     reexec.Command
+		timeout time.Duration
+		exp     expected
+	}{
+		"timeout exceeded": {
+			cmd:     reexec.Command{Name: "sleep", Args: []string{"10"}},
+			timeout: 100 * time.Millisecond,
+			exp:     expected{status: Stopped, reason: Timeout},
+		},
+		"exits before timeout": {
+			cmd:     reexec.Command{Name: "true"},
+			timeout: 5 * time.Second,
+			exp:     expected{status: Exited, reason: ""},
+		},
+		"no timeout": {
+			cmd: reexec.Command{Name: "sleep", Args: []string{"0.2"}},
+			exp: expected{status: Exited, reason: ""},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := newTestService(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			job := startTestJob(ctx, t, service, test.cmd, WithTimeout(test.timeout))
+
+			select {
+			case <-ctx.Done():
+				t.Fatal("job did not exit")
+			case <-job.done:
+			}
+
+			if job.Status() != test.exp.status {
+				t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), test.exp.status)
+			}
+			if job.StoppedReason() != test.exp.reason {
+				t.Fatalf("unexpected stopped reason; actual: %v, expected: %v", job.StoppedReason(), test.exp.reason)
+			}
+		})
+	}
+}
+
+func TestProcessGroupKilled(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	tests := map[string]struct {
+		cmd reexec.Command
+		// stop indicates the Job is stopped, rather than left to exit.
+		stop bool
+	}{
+		"stopped": {
+			cmd:  reexec.Command{Name: "bash", Args: []string{"-c", "sleep 30 & echo $!; wait"}},
+			stop: true,
+		},
+		"exited w/ background process": {
+			cmd: reexec.Command{Name: "bash", Args: []string{"-c", "sleep 30 & echo $!"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := newTestService(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			job := startTestJob(ctx, t, service, test.cmd)
+			pid := waitForPid(ctx, t, job)
+
+			if test.stop {
+				if !processAlive(pid) {
+					t.Fatalf("expected background process alive; pid: %d", pid)
+				}
+				if err := service.StopJob(ctx, job.ID, 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+			<-job.done
+
+			for processAlive(pid) {
+				select {
+				case <-ctx.Done():
+					t.Fatalf("expected background process killed; pid: %d", pid)
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+		})
+	}
+}
+
+func TestIsolatePid(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestService(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{
+		Name:       "ps",
+		Args:       []string{"ax", "-o", "pid=,comm="},
+		IsolatePid: true,
+	})
+	if _, err := service.WaitJob(ctx, job.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.Status() != Exited {
+		t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), Exited)
+	}
+
+	// Only the Job's executable, the namespace's init process, and the Job's
+	// command are visible.
+	b, err := os.ReadFile(job.outputStore.File(job.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected processes; actual: %q", b)
+	}
+	if pid := strings.Fields(lines[0])[0]; pid != "1" {
+		t.Fatalf("unexpected init pid; actual: %v, expected: %v", pid, 1)
+	}
+	if comm := strings.Fields(lines[1])[1]; comm != "ps" {
+		t.Fatalf("unexpected command; actual: %v, expected: %v", comm, "ps")
+	}
+
+	// The Job's executable ran within its own PID namespace.
+	if job.pid() == 1 {
+		t.Fatalf("unexpected host pid; actual: %d", job.pid())
+	}
+}
+
+func TestIsolatePidKilled(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestService(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The background process is orphaned once bash exits, and is reparented to
+	// the namespace's init process rather than the host's.
+	job := startTestJob(ctx, t, service, reexec.Command{
+		Name:       "bash",
+		Args:       []string{"-c", "sleep 30 & echo $!"},
+		IsolatePid: true,
+	})
+	<-job.done
+
+	// Killing the namespace's init process kills every process within the
+	// namespace, so no process remains in the Job's process group.
+	for {
+		err := syscall.Kill(-job.pid(), 0)
+		if errors.Is(err, syscall.ESRCH) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("expected job processes killed; error: %v", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestIsolateNetwork(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestService(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{
+		Name:           "ip",
+		Args:           []string{"-o", "link"},
+		IsolateNetwork: true,
+	})
+	if _, err := service.WaitJob(ctx, job.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.Status() != Exited {
+		t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), Exited)
+	}
+
+	// Only the loopback interface is visible, and it is up, e.g.
+	// "1: lo: <LOOPBACK,UP,LOWER_UP> mtu 65536 ...".
+	b, err := os.ReadFile(job.outputStore.File(job.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("unexpected links; actual: %q", b)
+	}
+	if fields := strings.Fields(lines[0]); len(fields) < 3 || fields[1] != "lo:" || !strings.Contains(fields[2], "UP") {
+		t.Fatalf("unexpected link; actual: %q", lines[0])
+	}
+}
+
+func TestIsolateMount(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestService(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	job := startTestJob(ctx, t, service, reexec.Command{
+		Name:         "bash",
+		Args:         []string{"-c", fmt.Sprintf("mount -t tmpfs tmpfs %s && grep -c %s /proc/self/mountinfo", dir, dir)},
+		IsolateMount: true,
+	})
+	if _, err := service.WaitJob(ctx, job.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.Status() != Exited || job.ExitCode() != 0 {
+		t.Fatalf("unexpected exit; status: %v, exit code: %v", job.Status(), job.ExitCode())
+	}
+
+	// The mount is visible to the Job, but not to the host.
+	b, err := os.ReadFile(job.outputStore.File(job.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := strings.TrimSpace(string(b)); count != "1" {
+		t.Fatalf("unexpected job mounts; actual: %v, expected: %v", count, 1)
+	}
+	mountinfo, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(mountinfo), dir) {
+		t.Fatalf("unexpected host mount; dir: %v", dir)
+	}
+}
+
+func TestRunningAfterExecution(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestService(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{Name: "sleep", Args: []string{"10"}})
+	if status := job.Status(); status != Running {
+		t.Fatalf("unexpected status; actual: %v, expected: %v", status, Running)
+	}
+
+	// The Job's command is running as the sole child of the Job's executable.
+	// Children are listed per thread of the executable.
+	tasks, err := filepath.Glob(fmt.Sprintf("/proc/%d/task/*/children", job.pid()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	for _, task := range tasks {
+		children, err := os.ReadFile(task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields = append(fields, strings.Fields(string(children))...)
+	}
+	if len(fields) != 1 {
+		t.Fatalf("unexpected children; actual: %v", fields)
+	}
+	comm, err := os.ReadFile(fmt.Sprintf("/proc/%s/comm", fields[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := strings.TrimSpace(string(comm)); name != "sleep" {
+		t.Fatalf("unexpected command; actual: %v, expected: %v", name, "sleep")
+	}
+}
+
+func TestWatchStatus(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	type expected struct {
+		statuses []Status
+	}
+	tests := map[string]struct {
+		cmd reexec.Command
+		// exited indicates the Job should exit before it is watched.
+		exited bool
+		exp    expected
+	}{
+		"running": {
+			cmd: reexec.Command{Name: "sleep", Args: []string{"0.2"}},
+			exp: expected{statuses: []Status{Running, Exited}},
+		},
+		"exited": {
+			cmd:    reexec.Command{Name: "true"},
+			exited: true,
+			exp:    expected{statuses: []Status{Exited}},
+		},
+		"command not found": {
+			cmd:    reexec.Command{Name: "definitely-not-a-binary"},
+			exited: true,
+			exp:    expected{statuses: []Status{Failed}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := newTestService(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			job := startTestJob(ctx, t, service, test.cmd)
+			if test.exited {
+				<-job.done
+			}
+
+			stream := make(chan Status)
+			errc := make(chan error, 1)
+			go func() {
+				errc <- job.WatchStatus(ctx, stream)
+				close(stream)
+			}()
+
+			var statuses []Status
+			for status := range stream {
+				statuses = append(statuses, status)
+			}
+			if err := <-errc; err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(statuses, test.exp.statuses) {
+				t.Fatalf("unexpected statuses; actual: %v, expected: %v", statuses, test.exp.statuses)
+			}
+		})
+	}
+}
+
+func TestWatchStatusCancelled(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestService(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{Name: "sleep", Args: []string{"10"}})
+
+	watchCtx, watchCancel := context.WithCancel(ctx)
+	stream := make(chan Status, maxStatusChanges)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- job.WatchStatus(watchCtx, stream)
+	}()
+
+	if status := <-stream; status != Running {
+		t.Fatalf("unexpected status; actual: %v, expected: %v", status, Running)
+	}
+	watchCancel()
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("watch did not return")
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected error; actual: %v, expected: %v", err, context.Canceled)
+		}
+	}
+}
+
+func TestWaitJob(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	type expected struct {
+		status    Status
+		succeeded bool
+	}
+	tests := map[string]struct {
+		cmd reexec.Command
+		exp expected
+	}{
+		"exit 0": {
+			cmd: reexec.Command{Name: "bash", Args: []string{"-c", "sleep 0.1; exit 0"}},
+			exp: expected{status: Exited, succeeded: true},
+		},
+		"exit 3": {
+			cmd: reexec.Command{Name: "bash", Args: []string{"-c", "sleep 0.1; exit 3"}},
+			exp: expected{status: Exited, succeeded: false},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := newTestService(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			job := startTestJob(ctx, t, service, test.cmd)
+
+			waited, err := service.WaitJob(ctx, job.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if waited.Status() != test.exp.status {
+				t.Fatalf("unexpected status; actual: %v, expected: %v", waited.Status(), test.exp.status)
+			}
+			if waited.Succeeded() != test.exp.succeeded {
+				t.Fatalf("unexpected succeeded; actual: %v, expected: %v", waited.Succeeded(), test.exp.succeeded)
+			}
+		})
+	}
+}
+
+func TestDeleteJob(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	type expected struct {
+		err error
+	}
+	tests := map[string]struct {
+		cmd reexec.Command
+		// wait indicates the Job is waited upon prior to being purged.
+		wait bool
+		exp  expected
+	}{
+		"finished": {
+			cmd:  reexec.Command{Name: "echo", Args: []string{"hello"}},
+			wait: true,
+			exp:  expected{},
+		},
+		"running": {
+			cmd: reexec.Command{Name: "sleep", Args: []string{"10"}},
+			exp: expected{err: ErrJobActive},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := newTestService(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			job := startTestJob(ctx, t, service, test.cmd)
+			if test.wait {
+				if _, err := service.WaitJob(ctx, job.ID); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			err := service.DeleteJob(ctx, job.ID)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if err != nil {
+				return
+			}
+
+			if _, err := service.FetchJob(ctx, job.ID); !errors.Is(err, ErrJobNotFound) {
+				t.Fatalf("unexpected fetch error; actual: %v, expected: %v", err, ErrJobNotFound)
+			}
+			if _, err := os.Stat(job.outputStore.File(job.ID)); !errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("expected output to be removed; error: %v", err)
+			}
+			if err := job.StreamOutput(ctx, make(chan Chunk), 128); !errors.Is(err, ErrOutputPurged) {
+				t.Fatalf("unexpected stream error; actual: %v, expected: %v", err, ErrOutputPurged)
+			}
+		})
+	}
+}
+
+func TestDeleteJobActiveStream(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestService(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{Name: "echo", Args: []string{"hello"}})
+	if _, err := service.WaitJob(ctx, job.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The stream is not received from until the Job is purged, holding the
+	// stream active.
+	stream := make(chan Chunk)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- job.StreamOutput(ctx, stream, 128)
+		close(stream)
+	}()
+	// Allow the stream to begin.
+	time.Sleep(100 * time.Millisecond)
+
+	if err := service.DeleteJob(ctx, job.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(job.outputStore.File(job.ID)); err != nil {
+		t.Fatalf("expected output to remain while streamed; error: %v", err)
+	}
+
+	var b []byte
+	for chunk := range stream {
+		b = append(b, chunk.Data...)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "hello\n"; string(b) != expected {
+		t.Fatalf("unexpected output; actual: %q, expected: %q", b, expected)
+	}
+
+	if _, err := os.Stat(job.outputStore.File(job.ID)); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected output to be removed; error: %v", err)
+	}
+}
+
+func TestOutputRetention(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestServiceWithCgroups(t, fakeCgroupService{}, WithOutputRetention(100*time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{Name: "echo", Args: []string{"hello"}})
+	if _, err := service.WaitJob(ctx, job.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(job.outputStore.File(job.ID)); err != nil {
+		t.Fatalf("expected output to be retained; error: %v", err)
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		_, err := service.FetchJob(ctx, job.ID)
+		if errors.Is(err, ErrJobNotFound) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("job not purged after retention")
+		case <-ticker.C:
+		}
+	}
+	if _, err := os.Stat(job.outputStore.File(job.ID)); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected output to be removed; error: %v", err)
+	}
+}
+
+func TestMaxRetainedJobs(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestServiceWithCgroups(t, fakeCgroupService{}, WithMaxRetainedJobs(2))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var jobs []*Job
+	for i := 0; i < 3; i++ {
+		job := startTestJob(ctx, t, service, reexec.Command{Name: "echo", Args: []string{"hello"}})
+		if _, err := service.WaitJob(ctx, job.ID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	// The Job that finished earliest is purged once the third Job finishes.
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		_, err := service.FetchJob(ctx, jobs[0].ID)
+		if errors.Is(err, ErrJobNotFound) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("job not purged beyond max retained jobs")
+		case <-ticker.C:
+		}
+	}
+	if _, err := os.Stat(jobs[0].outputStore.File(jobs[0].ID)); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected output to be removed; error: %v", err)
+	}
+
+	for _, job := range jobs[1:] {
+		if _, err := service.FetchJob(ctx, job.ID); err != nil {
+			t.Fatalf("expected job to be retained; job: %v, error: %v", job.ID, err)
+		}
+	}
+}
+
+func TestDeletions(t *testing.T) {
+	type expected struct {
+		deleted bool
+	}
+	tests := map[string]struct {
+		cancel func(*deletions, uuid.UUID)
+		exp    expected
+	}{
+		"scheduled": {
+			cancel: func(*deletions, uuid.UUID) {},
+			exp:    expected{deleted: true},
+		},
+		"cancelled": {
+			cancel: func(d *deletions, id uuid.UUID) { d.cancel(id) },
+			exp:    expected{deleted: false},
+		},
+		"closed": {
+			cancel: func(d *deletions, _ uuid.UUID) { d.close() },
+			exp:    expected{deleted: false},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := newDeletions()
+			id := uuid.New()
+
+			deleted := make(chan struct{})
+			d.schedule(id, 50*time.Millisecond, func() { close(deleted) })
+			test.cancel(d, id)
+
+			select {
+			case <-deleted:
+				if !test.exp.deleted {
+					t.Fatal("unexpected deletion")
+				}
+			case <-time.After(200 * time.Millisecond):
+				if test.exp.deleted {
+					t.Fatal("expected deletion")
+				}
+			}
+		})
+	}
+}
+
+func TestMaxRetainedJobsNegative(t *testing.T) {
+	service := newTestServiceWithCgroups(t, fakeCgroupService{}, WithMaxRetainedJobs(-1))
+
+	job := record{ID: uuid.New(), Status: Exited, FinishedAt: time.Now()}.restore()
+	service.jobs.Store(job.ID, job)
+
+	// A negative maximum retains any number of finished Jobs.
+	service.evictJobs()
+	if _, err := service.FetchJob(context.Background(), job.ID); err != nil {
+		t.Fatalf("expected job to be retained; error: %v", err)
+	}
+}
+
+func TestRestartJob(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	type expected struct {
+		err error
+	}
+	tests := map[string]struct {
+		cmd reexec.Command
+		// wait indicates the Job is waited upon prior to being restarted.
+		wait bool
+		exp  expected
+	}{
+		"finished": {
+			cmd:  reexec.Command{Name: "echo", Args: []string{"hello"}},
+			wait: true,
+			exp:  expected{},
+		},
+		"running": {
+			cmd: reexec.Command{Name: "sleep", Args: []string{"10"}},
+			exp: expected{err: ErrJobActive},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := newTestService(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			j, err := New("test_user", test.cmd, WithMaxOutputBytes(1024))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := service.StartJob(ctx, *j, cgroup.WithMemory(1<<20), cgroup.WithPidsMax(10)); err != nil {
+				t.Fatal(err)
+			}
+			job, err := service.FetchJob(ctx, j.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.wait {
+				if _, err := service.WaitJob(ctx, job.ID); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			restarted, err := service.RestartJob(ctx, job.ID)
+			if !errors.Is(err, test.exp.err) {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if err != nil {
+				return
+			}
+
+			if restarted.ID == job.ID {
+				t.Fatalf("expected new job ID; ID: %v", restarted.ID)
+			}
+			if restarted.Owner != job.Owner {
+				t.Fatalf("unexpected owner; actual: %s, expected: %s", restarted.Owner, job.Owner)
+			}
+			if !reflect.DeepEqual(restarted.Command(), job.Command()) {
+				t.Fatalf("unexpected command; actual: %+v, expected: %+v", restarted.Command(), job.Command())
+			}
+			if !reflect.DeepEqual(restarted.Limits(), job.Limits()) {
+				t.Fatalf("unexpected limits; actual: %+v, expected: %+v", restarted.Limits(), job.Limits())
+			}
+			if restarted.MaxOutputBytes() != job.MaxOutputBytes() {
+				t.Fatalf("unexpected max output bytes; actual: %d, expected: %d", restarted.MaxOutputBytes(), job.MaxOutputBytes())
+			}
+
+			if _, err := service.WaitJob(ctx, restarted.ID); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			waitForOutput(ctx, t, restarted, "hello\n")
+		})
+	}
+}
+
+func TestStartJobAfterExecutableRenamed(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestService(t)
+
+	// Move the executable the Service was created with, simulating an upgrade
+	// replacing the jobworker binary.
+	path, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := path + ".moved"
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.Rename(moved, path); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{Name: "echo", Args: []string{"hello"}})
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("job did not exit")
+	case <-job.done:
+	}
+
+	if job.Status() != Exited || job.ExitCode() != 0 {
+		t.Fatalf("unexpected exit; status: %v, exit code: %v", job.Status(), job.ExitCode())
+	}
+}
+
+func TestStreamOutputAfterStart(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestService(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Stream output immediately after starting, likely before the Job's
+	// executable has created the output.
+	job := startTestJob(ctx, t, service, reexec.Command{Name: "echo", Args: []string{"hello"}})
+
+	stream := make(chan Chunk)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- job.StreamOutput(ctx, stream, 128)
+		close(stream)
+	}()
+
+	var b []byte
+	for chunk := range stream {
+		b = append(b, chunk.Data...)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := "hello\n"; string(b) != expected {
+		t.Fatalf("unexpected output; actual: %q, expected: %q", b, expected)
+	}
+}
+
+func TestStreamOutputAfterExit(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestService(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{Name: "echo", Args: []string{"hello"}})
+	if _, err := service.WaitJob(ctx, job.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The Job's output outlives the Job's other resources.
+	stream := make(chan Chunk, 1)
+	if err := job.StreamOutput(ctx, stream, 128); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if chunk := <-stream; string(chunk.Data) != "hello\n" {
+		t.Fatalf("unexpected output; actual: %q, expected: %q", chunk.Data, "hello\n")
+	}
+
+	// Output removed from the host is reported as expired, rather than as
+	// empty.
+	if err := os.Remove(job.outputStore.File(job.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := job.StreamOutput(ctx, make(chan Chunk), 128); !errors.Is(err, ErrOutputExpired) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, ErrOutputExpired)
+	}
+}
+
+func TestStreamOutputRotated(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	// The output of 1 through 1000 is 3893 bytes, rotated across 39 segments.
+	var expected bytes.Buffer
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintln(&expected, i)
+	}
+
+	tests := map[string]struct {
+		options []StreamOption
+		// wait indicates streaming begins once the Job has exited, rather than
+		// following the output as it is rotated.
+		wait bool
+		exp  []byte
+	}{
+		"follow": {
+			exp: expected.Bytes(),
+		},
+		"exited": {
+			wait: true,
+			exp:  expected.Bytes(),
+		},
+		"offset": {
+			options: []StreamOption{WithOffset(250)},
+			wait:    true,
+			exp:     expected.Bytes()[250:],
+		},
+		"tail": {
+			options: []StreamOption{WithTailBytes(150)},
+			wait:    true,
+			exp:     expected.Bytes()[expected.Len()-150:],
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := newTestService(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			job := startTestJob(
+				ctx,
+				t,
+				service,
+				reexec.Command{Name: "bash", Args: []string{"-c", "for i in $(seq 1 1000); do echo $i; done"}},
+				WithOutputRotateBytes(100),
+			)
+			if test.wait {
+				if _, err := service.WaitJob(ctx, job.ID); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			stream := make(chan Chunk)
+			errc := make(chan error, 1)
+			go func() {
+				errc <- job.StreamOutput(ctx, stream, 64, test.options...)
+				close(stream)
+			}()
+
+			var actual []byte
+			for chunk := range stream {
+				actual = append(actual, chunk.Data...)
+			}
+			if err := <-errc; err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(actual, test.exp) {
+				t.Fatalf("unexpected output; actual: %d bytes, expected: %d bytes", len(actual), len(test.exp))
+			}
+
+			if _, err := os.Stat(job.outputStore.Segment(job.ID, 38)); err != nil {
+				t.Fatalf("expected rotated segment; error: %v", err)
+			}
+			if size, err := job.OutputSize(); err != nil || size != int64(expected.Len()) {
+				t.Fatalf("unexpected output size; actual: %v, expected: %v, error: %v", size, expected.Len(), err)
+			}
+		})
+	}
+}
+
+func TestOutputDir(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	dir := t.TempDir()
+	service := newTestServiceWithCgroups(t, fakeCgroupService{}, WithOutputDir(dir))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{Name: "echo", Args: []string{"hello"}})
+	if _, err := service.WaitJob(ctx, job.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, job.ID.String()+".log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello\n" {
+		t.Fatalf("unexpected output; actual: %q, expected: %q", b, "hello\n")
+	}
+}
+
+func TestStateDir(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	dir := t.TempDir()
+	// The restored Service shares the first Service's output directory.
+	outputDir := t.TempDir()
+	service := newTestServiceWithCgroups(t, fakeCgroupService{}, WithStateDir(dir), WithOutputDir(outputDir))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	j, err := New("test_user", reexec.Command{Name: "echo", Args: []string{"hello"}}, WithMaxOutputBytes(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.StartJob(ctx, *j, cgroup.WithPidsMax(10)); err != nil {
+		t.Fatal(err)
+	}
+	exited, err := service.FetchJob(ctx, j.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	running := startTestJob(ctx, t, service, reexec.Command{Name: "sleep", Args: []string{"10"}})
+	// The running Job is recorded once its command is executing.
+	waitForRecord(ctx, t, filepath.Join(dir, running.ID.String()+recordExt), Running)
+
+	// The finished Job is recorded once its resources are released.
+	waitForRecord(ctx, t, filepath.Join(dir, exited.ID.String()+recordExt), Exited)
+
+	// A malformed record is skipped rather than failing the Service.
+	if err := os.WriteFile(filepath.Join(dir, uuid.New().String()+recordExt), []byte("{"), stateFileMode); err != nil {
+		t.Fatal(err)
+	}
+
+	// A Service restored from dir, as if the first Service had been restarted
+	// while running was active.
+	restored := newTestServiceWithCgroups(t, fakeCgroupService{}, WithStateDir(dir), WithOutputDir(outputDir))
+
+	type expected struct {
+		status    Status
+		exitCode  int
+		cmd       reexec.Command
+		startedAt time.Time
+	}
+	tests := map[string]struct {
+		id  uuid.UUID
+		exp expected
+	}{
+		"exited": {
+			id:  exited.ID,
+			exp: expected{status: Exited, exitCode: 0, cmd: exited.Command(), startedAt: exited.StartedAt()},
+		},
+		"running": {
+			id:  running.ID,
+			exp: expected{status: Stopped, exitCode: -1, cmd: running.Command(), startedAt: running.StartedAt()},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			job, err := restored.FetchJob(ctx, test.id)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if job.Status() != test.exp.status {
+				t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), test.exp.status)
+			}
+			if job.ExitCode() != test.exp.exitCode {
+				t.Fatalf("unexpected exit code; actual: %v, expected: %v", job.ExitCode(), test.exp.exitCode)
+			}
+			if !reflect.DeepEqual(job.Command(), test.exp.cmd) {
+				t.Fatalf("unexpected command; actual: %+v, expected: %+v", job.Command(), test.exp.cmd)
+			}
+			if !job.StartedAt().Equal(test.exp.startedAt) {
+				t.Fatalf("unexpected started at; actual: %v, expected: %v", job.StartedAt(), test.exp.startedAt)
+			}
+			waitForRecord(ctx, t, filepath.Join(dir, test.id.String()+recordExt), test.exp.status)
+		})
+	}
+
+	job, err := restored.FetchJob(ctx, exited.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Limits().PidsMax != 10 || job.MaxOutputBytes() != 1024 {
+		t.Fatalf("unexpected limits; limits: %+v, max output bytes: %d", job.Limits(), job.MaxOutputBytes())
+	}
+
+	// The output of a restored Job may be streamed.
+	stream := make(chan Chunk, 1)
+	if err := job.StreamOutput(ctx, stream, 128); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if chunk := <-stream; string(chunk.Data) != "hello\n" {
+		t.Fatalf("unexpected output; actual: %q, expected: %q", chunk.Data, "hello\n")
+	}
+
+	// Deleting a restored Job removes its record.
+	if err := restored.DeleteJob(ctx, exited.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, exited.ID.String()+recordExt)); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected record to be removed; error: %v", err)
+	}
+
+	// Stop the first Service's running Job, and wait for its final record, so
+	// the record is not written while dir is removed.
+	if err := service.StopJob(ctx, running.ID, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForRecordFunc(ctx, t, filepath.Join(dir, running.ID.String()+recordExt), func(r record) bool {
+		return !r.FinishedAt.IsZero()
+	})
+}
+
+func TestStateDirEnv(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	dir := t.TempDir()
+	service := newTestServiceWithCgroups(t, fakeCgroupService{}, WithStateDir(dir))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{Name: "true", Env: []string{"SECRET=hunter2"}})
+	path := filepath.Join(dir, job.ID.String()+recordExt)
+	waitForRecord(ctx, t, path, Exited)
+
+	// Only the keys of the command's environment are recorded.
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("hunter2")) {
+		t.Fatalf("unexpected env value in record; record: %s", b)
+	}
+	var r record
+	if err := json.Unmarshal(b, &r); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.EnvKeys, []string{"SECRET"}) {
+		t.Fatalf("unexpected env keys; actual: %v, expected: %v", r.EnvKeys, []string{"SECRET"})
+	}
+
+	// The restored Job's environment is unknown, so it may not be restarted.
+	restored := newTestServiceWithCgroups(t, fakeCgroupService{}, WithStateDir(dir))
+	if _, err := restored.RestartJob(ctx, job.ID); !errors.Is(err, ErrEnvNotRecorded) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, ErrEnvNotRecorded)
+	}
+
+	// The restored Job retains its env keys, so they survive being recorded
+	// again.
+	restoredJob, err := restored.FetchJob(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := newRecord(restoredJob).EnvKeys; !reflect.DeepEqual(keys, []string{"SECRET"}) {
+		t.Fatalf("unexpected env keys; actual: %v, expected: %v", keys, []string{"SECRET"})
+	}
+}
+
+func TestFetchUsage(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestService(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{Name: "bash", Args: []string{"-c", "echo ready; sleep 10"}})
+	waitForOutput(ctx, t, job, "ready\n")
+
+	stats, err := service.FetchUsage(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(stats, &fakeStats) {
+		t.Fatalf("unexpected running stats; actual: %+v, expected: %+v", stats, &fakeStats)
+	}
+
+	if err := service.StopJob(ctx, job.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	<-job.done
+
+	// The Job's usage is captured before the Job's cgroup is removed.
+	if !reflect.DeepEqual(job.Usage(), &fakeStats) {
+		t.Fatalf("unexpected captured usage; actual: %+v, expected: %+v", job.Usage(), &fakeStats)
+	}
+
+	// Once the Job's cgroup is removed, the captured usage is retrieved.
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if _, ok := service.jobCgroups.Load(job.ID); !ok {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("job cgroup not removed")
+		case <-ticker.C:
+		}
+	}
+
+	stats, err = service.FetchUsage(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(stats, &fakeStats) {
+		t.Fatalf("unexpected exited stats; actual: %+v, expected: %+v", stats, &fakeStats)
+	}
+}
+
+func TestFetchStats(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestService(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{Name: "bash", Args: []string{"-c", "echo ready; sleep 10"}})
+	waitForOutput(ctx, t, job, "ready\n")
+
+	stats, err := service.FetchStats(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(stats, &fakeDetailedStats) {
+		t.Fatalf("unexpected stats; actual: %+v, expected: %+v", stats, &fakeDetailedStats)
+	}
+
+	if err := service.StopJob(ctx, job.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	<-job.done
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if _, ok := service.jobCgroups.Load(job.ID); !ok {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("job cgroup not removed")
+		case <-ticker.C:
+		}
+	}
+
+	_, err = service.FetchStats(ctx, job.ID)
+	if !errors.Is(err, ErrJobNotRunning) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, ErrJobNotRunning)
+	}
+
+	_, err = service.FetchStats(ctx, uuid.New())
+	if !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, ErrJobNotFound)
+	}
+}
+
+func TestServiceWithoutCgroups(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestServiceWithCgroups(t, cgroup.NoopService{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{Name: "bash", Args: []string{"-c", "echo ready; sleep 10"}})
+	waitForOutput(ctx, t, job, "ready\n")
+
+	if job.Status() != Running {
+		t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), Running)
+	}
+
+	if err := service.StopJob(ctx, job.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	<-job.done
+
+	if job.Status() != Stopped {
+		t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), Stopped)
+	}
+	if job.StoppedReason() != Manual {
+		t.Fatalf("unexpected stopped reason; actual: %v, expected: %v", job.StoppedReason(), Manual)
+	}
+	// Without cgroups, no usage is captured.
+	if job.Usage() != nil {
+		t.Fatalf("unexpected usage; actual: %+v, expected: %v", job.Usage(), nil)
+	}
+
+	stream := make(chan Chunk)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- job.StreamOutput(ctx, stream, 128)
+		close(stream)
+	}()
+
+	var b []byte
+	for chunk := range stream {
+		b = append(b, chunk.Data...)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "ready\n"; string(b) != expected {
+		t.Fatalf("unexpected output; actual: %q, expected: %q", b, expected)
+	}
+}
+
+func TestStreamOutputConcurrent(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	const streams = 8
+
+	service := newTestService(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{
+		Name: "bash",
+		Args: []string{"-c", "for i in $(seq 1 20); do echo line $i; sleep 0.05; done"},
+	})
+
+	var expected bytes.Buffer
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&expected, "line %d\n", i)
+	}
+
+	var wg sync.WaitGroup
+	outputs := make([][]byte, streams)
+	errs := make([]error, streams)
+	for i := 0; i < streams; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			stream := make(chan Chunk)
+			errc := make(chan error, 1)
+			go func() {
+				errc <- job.StreamOutput(ctx, stream, 16)
+				close(stream)
+			}()
+
+			for chunk := range stream {
+				outputs[i] = append(outputs[i], chunk.Data...)
+				// Odd streams are slow listeners.
+				if i%2 == 1 {
+					time.Sleep(20 * time.Millisecond)
+				}
+			}
+			errs[i] = <-errc
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < streams; i++ {
+		if errs[i] != nil {
+			t.Fatalf("unexpected error; stream: %d, error: %v", i, errs[i])
+		}
+		if !bytes.Equal(outputs[i], expected.Bytes()) {
+			t.Fatalf("unexpected output; stream: %d, actual: %q, expected: %q", i, outputs[i], expected.Bytes())
+		}
+	}
+}
+
+func TestStartJobCallerStatus(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	type expected struct {
+		status Status
+	}
+	tests := map[string]struct {
+		cmd reexec.Command
+		exp expected
+	}{
+		"running": {
+			cmd: reexec.Command{Name: "sleep", Args: []string{"10"}},
+			exp: expected{status: Running},
+		},
+		"command not found": {
+			cmd: reexec.Command{Name: "definitely-not-a-binary"},
+			exp: expected{status: Failed},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := newTestService(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			j, err := New("test_user", test.cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := service.StartJob(ctx, *j); err != nil {
+				t.Fatal(err)
+			}
+			job, err := service.FetchJob(ctx, j.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// StartJob starts a copy of the caller's Job; the caller's Job is
+			// unchanged.
+			if status := j.Status(); status != Pending {
+				t.Fatalf("unexpected caller status; actual: %v, expected: %v", status, Pending)
+			}
+			// StartJob returns once the started Job's status reflects whether its
+			// command executed.
+			if status := job.Status(); status != test.exp.status {
+				t.Fatalf("unexpected status; actual: %v, expected: %v", status, test.exp.status)
+			}
+		})
+	}
+}
+
+func TestPauseResumeJob(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestService(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{Name: "sleep", Args: []string{"10"}})
+
+	if err := service.ResumeJob(ctx, job.ID); !errors.Is(err, ErrJobNotPaused) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, ErrJobNotPaused)
+	}
+	if err := service.PauseJob(ctx, job.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.Status() != Paused {
+		t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), Paused)
+	}
+	if err := service.PauseJob(ctx, job.ID); !errors.Is(err, ErrJobNotRunning) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, ErrJobNotRunning)
+	}
+	if err := service.ResumeJob(ctx, job.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.Status() != Running {
+		t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), Running)
+	}
+
+	// A paused Job is resumed before it is stopped.
+	if err := service.PauseJob(ctx, job.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.StopJob(ctx, job.ID, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.Status() != Stopped {
+		t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), Stopped)
+	}
+
+	if err := service.PauseJob(ctx, job.ID); !errors.Is(err, ErrJobNotRunning) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, ErrJobNotRunning)
+	}
+}
+
+func TestPauseJobFreezeError(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	errFreeze := errors.New("freeze failed")
+	service := newTestServiceWithCgroups(t, fakeCgroupService{freezeErr: errFreeze})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{Name: "sleep", Args: []string{"10"}})
+
+	if err := service.PauseJob(ctx, job.ID); !errors.Is(err, errFreeze) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, errFreeze)
+	}
+	if job.Status() != Running {
+		t.Fatalf("unexpected status; actual: %v, expected: %v", job.Status(), Running)
+	}
+}
+
+func TestPauseJobCgroup(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	cgroups, err := cgroup.NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := cgroups.Cleanup(); err != nil {
+			t.Error(err)
+		}
+	})
+	service := newTestServiceWithCgroups(t, cgroups)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := startTestJob(ctx, t, service, reexec.Command{
+		Name: "bash",
+		Args: []string{"-c", "yes > /dev/null & while true; do echo tick; sleep 0.05; done"},
+	})
+	waitForOutput(ctx, t, job, "tick\n")
+
+	if err := service.PauseJob(ctx, job.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Allow the freeze to complete.
+	time.Sleep(200 * time.Millisecond)
+
+	pausedUsage, err := service.FetchUsage(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pausedSize, err := job.OutputSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	usage, err := service.FetchUsage(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.CpuUsageUsec != pausedUsage.CpuUsageUsec {
+		t.Fatalf("unexpected cpu usage while paused; actual: %d, expected: %d", usage.CpuUsageUsec, pausedUsage.CpuUsageUsec)
+	}
+	size, err := job.OutputSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != pausedSize {
+		t.Fatalf("unexpected output size while paused; actual: %d, expected: %d", size, pausedSize)
+	}
+
+	if err := service.ResumeJob(ctx, job.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+
+	size, err = job.OutputSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size <= pausedSize {
+		t.Fatalf("output did not continue after resume; actual: %d, paused: %d", size, pausedSize)
+	}
+}
+
+func TestMaxJobs(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	const (
+		maxJobs = 3
+		starts  = 10
+	)
+	service := newTestServiceWithCgroups(t, fakeCgroupService{}, WithMaxJobs(maxJobs))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		started []*Job
+		limited int
+	)
+	for i := 0; i < starts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			j, err := New("test_user", reexec.Command{Name: "sleep", Args: []string{"30"}})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			err = service.StartJob(ctx, *j)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			switch {
+			case errors.Is(err, ErrMaxJobs):
+				limited++
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			default:
+				job, err := service.FetchJob(ctx, j.ID)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				started = append(started, job)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(started) != maxJobs {
+		t.Fatalf("unexpected started jobs; actual: %v, expected: %v", len(started), maxJobs)
+	}
+	if limited != starts-maxJobs {
+		t.Fatalf("unexpected limited jobs; actual: %v, expected: %v", limited, starts-maxJobs)
+	}
+
+	// Once a Job stops, another may start.
+	if err := service.StopJob(ctx, started[0].ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	<-started[0].done
+	startTestJob(ctx, t, service, reexec.Command{Name: "sleep", Args: []string{"30"}})
+}
+
+func TestPerOwnerLimit(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	service := newTestServiceWithCgroups(t, fakeCgroupService{}, WithPerOwnerLimit(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := func(owner string) (*Job, error) {
+		j, err := New(owner, reexec.Command{Name: "sleep", Args: []string{"30"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := service.StartJob(ctx, *j); err != nil {
+			return nil, err
+		}
+		return service.FetchJob(ctx, j.ID)
+	}
+
+	alpha, err := start("alpha_user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := start("alpha_user"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, ErrQuotaExceeded)
+	}
+	// Each owner has their own quota.
+	if _, err := start("beta_user"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Once a Job stops, its owner may start another.
+	if err := service.StopJob(ctx, alpha.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	<-alpha.done
+	if _, err := start("alpha_user"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestMaxJobsReleasedOnError(t *testing.T) {
+	if !isRoot() {
+		t.Skip("must be root to run")
+	}
+
+	errPlace := errors.New("place failed")
+	service := newTestServiceWithCgroups(t, fakeCgroupService{placeErr: errPlace}, WithMaxJobs(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	j, err := New("test_user", reexec.Command{Name: "sleep", Args: []string{"30"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.StartJob(ctx, *j); !errors.Is(err, errPlace) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, errPlace)
+	}
+
+	job, err := service.FetchJob(ctx, j.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-job.done
+
+	// The Job stopped before it ran, so its reservation is released.
+	service.mutex.RLock()
+	active, owners := service.activeJobs, len(service.ownerJobs)
+	service.mutex.RUnlock()
+	if active != 0 {
+		t.Fatalf("unexpected active jobs; actual: %v, expected: %v", active, 0)
+	}
+	if owners != 0 {
+		t.Fatalf("unexpected owners with active jobs; actual: %v, expected: %v", owners, 0)
+	}
+}
+
+func TestStartJobCreateCgroupError(t *testing.T) {
+	dir := t.TempDir()
+	errCreate := errors.New("create failed")
+	service := newTestServiceWithCgroups(t, fakeCgroupService{createErr: errCreate}, WithStateDir(dir))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	j, err := New("test_user", reexec.Command{Name: "sleep", Args: []string{"30"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.StartJob(ctx, *j); !errors.Is(err, errCreate) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, errCreate)
+	}
+
+	// The Job failed to start, so it is neither retained nor recorded.
+	if _, err := service.FetchJob(ctx, j.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("unexpected error; actual: %v, expected: %v", err, ErrJobNotFound)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("unexpected records; actual: %v, expected: %v", len(entries), 0)
+	}
+
+	service.mutex.RLock()
+	active := service.activeJobs
+	service.mutex.RUnlock()
+	if active != 0 {
+		t.Fatalf("unexpected active jobs; actual: %v, expected: %v", active, 0)
+	}
+}
+
+// newTestService creates a Service that does not interact with cgroups.
+func newTestService(t *testing.T) *Service {
+	return newTestServiceWithCgroups(t, fakeCgroupService{})
+}
+
+// newTestServiceWithCgroups creates a Service utilizing cgroups and configured
+// by options. Job output is written within a temporary directory. Jobs started
+// by the Service are stopped and their output removed when the test
+// completes.
+func newTestServiceWithCgroups(t *testing.T, cgroups ICgroupService, options ...ServiceOption) *Service {
+	options = append([]ServiceOption{WithOutputDir(t.TempDir())}, options...)
+	service, err := NewService(cgroups, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		service.jobs.Range(func(key, value interface{}) bool {
+			job, ok := value.(*Job)
+			if !ok {
+				return true
+			}
+			// Kill the Job's process group, so commands outliving the Job's
+			// executable are not leaked by the test.
+			if job.exec != nil && job.exec.Process != nil {
+				syscall.Kill(-job.pid(), syscall.SIGKILL)
+			}
+			job.stop()
+			<-job.done
+			job.outputStore.Remove(job.ID)
+			return true
+		})
+	})
+
+	return service
+}
+
+// startTestJob starts a Job running cmd and returns the Job managed by
+// service.
+func startTestJob(
+	ctx context.Context,
+	t *testing.T,
+	service *Service,
+	cmd reexec.Command,
+	options ...JobOption,
+) *Job {
+	j, err := New("test_user", cmd, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.StartJob(ctx, *j); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := service.FetchJob(ctx, j.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return job
+}
+
+// waitForPid blocks until the output of job holds a line, and returns the
+// line as a pid.
+func waitForPid(ctx context.Context, t *testing.T, job *Job) int {
+	for {
+		b, err := os.ReadFile(job.outputStore.File(job.ID))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			t.Fatal(err)
+		}
+		if line := string(b); strings.HasSuffix(line, "\n") {
+			pid, err := strconv.Atoi(strings.TrimSpace(line))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return pid
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatalf("pid not output; job: %v", job.ID)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// processAlive checks if the process identified by pid is running. Zombie
+// processes, terminated but not yet reaped, are not running.
+func processAlive(pid int) bool {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	// The state follows the parenthesized command name, e.g. "42 (sleep) S".
+	stat := string(b)
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+// waitForOutput blocks until the output of job begins with prefix.
+func waitForOutput(ctx context.Context, t *testing.T, job *Job, prefix string) {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		b, err := os.ReadFile(job.outputStore.File(job.ID))
+		if err == nil && bytes.HasPrefix(b, []byte(prefix)) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatalf("output not observed; expected prefix: %q", prefix)
+		case <-ticker.C:
+		}
+	}
+}
+
+// waitForRecord blocks until the record at path holds status.
+func waitForRecord(ctx context.Context, t *testing.T, path string, status Status) {
+	waitForRecordFunc(ctx, t, path, func(r record) bool { return r.Status == status })
+}
+
+// waitForRecordFunc blocks until the record at path satisfies ok.
+func waitForRecordFunc(ctx context.Context, t *testing.T, path string, ok func(record) bool) {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		var r record
+		if b, err := os.ReadFile(path); err == nil && json.Unmarshal(b, &r) == nil && ok(r) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatalf("expected record not observed; path: %s", path)
+		case <-ticker.C:
+		}
+	}
+}
+
+// fakeCgroupService is an ICgroupService that does not interact with cgroups.
+type fakeCgroupService struct {
+	// stats are the stats reported for all cgroups. If nil, fakeStats are
+	// reported.
+	stats *cgroup.Stats
+	// createErr is the error returned by CreateCgroup.
+	createErr error
+	// placeErr is the error returned by PlaceInCgroup.
+	placeErr error
+	// freezeErr is the error returned by Freeze.
+	freezeErr error
+}
+
+func (s fakeCgroupService) CreateCgroup(...cgroup.CgroupOption) (*cgroup.Cgroup, error) {
+	if s.createErr != nil {
+		return nil, s.createErr
+	}
+	return &cgroup.Cgroup{ID: uuid.New()}, nil
+}
+
+func (s fakeCgroupService) PlaceInCgroup(cgroup.Cgroup, int) error {
+	return s.placeErr
+}
+
+func (s fakeCgroupService) Freeze(cgroup.Cgroup, bool) error {
+	return s.freezeErr
+}
+
+func (fakeCgroupService) RemoveCgroup(uuid.UUID) error {
+	return nil
+}
+
+func (s fakeCgroupService) ReadStats(cgroup.Cgroup) (*cgroup.Stats, error) {
+	if s.stats != nil {
+		return s.stats, nil
+	}
+	return &fakeStats, nil
+}
+
+func (fakeCgroupService) ReadDetailedStats(cgroup.Cgroup) (*cgroup.DetailedStats, error) {
+	return &fakeDetailedStats, nil
+}
+
+// fakeDetailedStats are the detailed stats reported by fakeCgroupService for
+// all cgroups.
+var fakeDetailedStats = cgroup.DetailedStats{CpuStat: map[string]uint64{"usage_usec": 1500}}
+
+// fakeStats are the stats reported by fakeCgroupService for all cgroups.
+var fakeStats = cgroup.Stats{MemoryCurrent: 4096, CpuUsageUsec: 1500}
@@ -0,0 +1,237 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tjper/teleport/internal/jobworker/cgroup"
+	"github.com/tjper/teleport/internal/jobworker/reexec"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// stateDirMode is the FileMode of the state directory. Records hold
+	// commands, so are only readable by the owner.
+	stateDirMode = 0700
+	// stateFileMode is the FileMode of each record within the state directory.
+	stateFileMode = 0600
+	// recordExt is the file extension of records within the state directory.
+	recordExt = ".json"
+)
+
+// record is the metadata of a Job persisted within the Service's state
+// directory. See WithStateDir. The values of the command's environment
+// variables may hold secrets, so only their keys are recorded.
+type record struct {
+	ID              uuid.UUID      `json:"id"`
+	Owner           string         `json:"owner"`
+	Cmd             reexec.Command `json:"cmd"`
+	EnvKeys         []string       `json:"envKeys,omitempty"`
+	Status          Status         `json:"status"`
+	ExitCode        int            `json:"exitCode"`
+	Signal          syscall.Signal `json:"signal,omitempty"`
+	StoppedReason   StoppedReason  `json:"stoppedReason,omitempty"`
+	SetupError      string         `json:"setupError,omitempty"`
+	StartedAt       time.Time      `json:"startedAt"`
+	FinishedAt      time.Time      `json:"finishedAt"`
+	Usage           *cgroup.Stats  `json:"usage,omitempty"`
+	Timeout         time.Duration  `json:"timeout,omitempty"`
+	MaxOutputBytes  int64          `json:"maxOutputBytes,omitempty"`
+	KillOnMaxOutput bool           `json:"killOnMaxOutput,omitempty"`
+	RotateBytes     int64          `json:"rotateBytes,omitempty"`
+	Limits          cgroup.Cgroup  `json:"limits"`
+}
+
+// newRecord creates a record of the Job's current metadata.
+func newRecord(j *Job) record {
+	limits := j.Limits()
+
+	// A restored Job's command has no environment, only the keys recorded by
+	// the previous Service.
+	cmd := j.cmd
+	cmd.Env = nil
+	envKeys := j.envKeys
+	for _, env := range j.cmd.Env {
+		envKeys = append(envKeys, strings.SplitN(env, "=", 2)[0])
+	}
+
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	return record{
+		ID:              j.ID,
+		Owner:           j.Owner,
+		Cmd:             cmd,
+		EnvKeys:         envKeys,
+		Status:          j.status,
+		ExitCode:        j.exitCode,
+		Signal:          j.signal,
+		StoppedReason:   j.stoppedReason,
+		SetupError:      j.setupError,
+		StartedAt:       j.startedAt,
+		FinishedAt:      j.finishedAt,
+		Usage:           j.usage,
+		Timeout:         j.timeout,
+		MaxOutputBytes:  j.maxOutputBytes,
+		KillOnMaxOutput: j.killOnMaxOutput,
+		RotateBytes:     j.outputRotateBytes,
+		Limits:          limits,
+	}
+}
+
+// restore creates a finished Job from the record. The Job's executable is not
+// running; Jobs recorded as active are restored Stopped. The Job's command has
+// no environment, as the values were not recorded.
+func (r record) restore() *Job {
+	status := r.Status
+	if !status.terminal() {
+		status = Stopped
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	close(done)
+	started := make(chan struct{})
+	close(started)
+
+	limits := r.Limits
+	return &Job{
+		mutex:             new(sync.RWMutex),
+		ID:                r.ID,
+		Owner:             r.Owner,
+		cmd:               r.Cmd,
+		envKeys:           r.EnvKeys,
+		status:            status,
+		statusSnapshot:    newStatusSnapshot(status),
+		exitCode:          r.ExitCode,
+		signal:            r.Signal,
+		startedAt:         r.StartedAt,
+		finishedAt:        r.FinishedAt,
+		stoppedReason:     r.StoppedReason,
+		setupError:        r.SetupError,
+		usage:             r.Usage,
+		timeout:           r.Timeout,
+		maxOutputBytes:    r.MaxOutputBytes,
+		killOnMaxOutput:   r.KillOnMaxOutput,
+		outputRotateBytes: r.RotateBytes,
+		cgroupOptions: []cgroup.CgroupOption{
+			cgroup.WithMemory(limits.Memory),
+			cgroup.WithMemoryMax(limits.MemoryMax),
+			cgroup.WithCpus(limits.Cpus),
+			cgroup.WithDiskWriteBps(limits.DiskWriteBps),
+			cgroup.WithDiskReadBps(limits.DiskReadBps),
+			cgroup.WithCpuWeight(limits.CpuWeight),
+			cgroup.WithPidsMax(limits.PidsMax),
+			cgroup.WithCpuSet(limits.CpuSet),
+		},
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    done,
+		started: started,
+	}
+}
+
+// recordPath is the path of the record of the Job identified by id.
+func (s Service) recordPath(id uuid.UUID) string {
+	return filepath.Join(s.stateDir, id.String()+recordExt)
+}
+
+// saveJob records the Job's current metadata within the Service's state
+// directory. If the Service has no state directory, saveJob does nothing.
+func (s Service) saveJob(j *Job) error {
+	if s.stateDir == "" {
+		return nil
+	}
+
+	// The record is created while holding stateMutex, so a record of the Job's
+	// earlier metadata never replaces a later one.
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+
+	b, err := json.Marshal(newRecord(j))
+	if err != nil {
+		return fmt.Errorf("marshal job record; job: %v, error: %w", j.ID, err)
+	}
+
+	// The record is written to a temporary file and renamed, so a partially
+	// written record is never loaded.
+	path := s.recordPath(j.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, stateFileMode); err != nil {
+		return fmt.Errorf("write job record; path: %v, error: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("rename job record; path: %v, error: %w", path, err)
+	}
+
+	return nil
+}
+
+// removeJob removes the record of the Job identified by id from the Service's
+// state directory. If the Service has no state directory, removeJob does
+// nothing.
+func (s Service) removeJob(id uuid.UUID) error {
+	if s.stateDir == "" {
+		return nil
+	}
+
+	err := os.Remove(s.recordPath(id))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove job record; job: %v, error: %w", id, err)
+	}
+	return nil
+}
+
+// restoreJobs loads the Jobs recorded within the Service's state directory,
+// creating the directory if it does not exist. Jobs recorded as active, whose
+// executables did not outlive the previous Service, are restored Stopped and
+// recorded as such.
+func (s *Service) restoreJobs() error {
+	if err := os.MkdirAll(s.stateDir, stateDirMode); err != nil {
+		return fmt.Errorf("mkdir job service state; path: %v, error: %w", s.stateDir, err)
+	}
+
+	entries, err := os.ReadDir(s.stateDir)
+	if err != nil {
+		return fmt.Errorf("read job service state; path: %v, error: %w", s.stateDir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), recordExt) {
+			continue
+		}
+		path := filepath.Join(s.stateDir, entry.Name())
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read job record; path: %v, error: %w", path, err)
+		}
+		var r record
+		if err := json.Unmarshal(b, &r); err != nil {
+			logger.Warnf("skipping malformed job record; path: %v, error: %v", path, err)
+			continue
+		}
+
+		job := r.restore()
+		job.outputStore = s.outputStore
+		if job.status != r.Status {
+			if err := s.saveJob(job); err != nil {
+				return err
+			}
+		}
+		s.jobs.Store(job.ID, job)
+		s.scheduleDelete(job)
+	}
+	s.evictJobs()
+
+	return nil
+}
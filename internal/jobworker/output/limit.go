@@ -0,0 +1,62 @@
+package output
+
+import "io"
+
+// TruncatedNotice is appended to output once it is truncated, so clients
+// reading the output observe the truncation. Output that has been truncated is
+// larger than its limit by len(TruncatedNotice).
+const TruncatedNotice = "\n[jobworker: output truncated]\n"
+
+// NewLimitedWriter creates a LimitedWriter instance. At most limit bytes are
+// written to w.
+func NewLimitedWriter(w io.Writer, limit int64) *LimitedWriter {
+	return &LimitedWriter{w: w, remaining: limit, exceeded: make(chan struct{})}
+}
+
+// LimitedWriter writes to an underlying io.Writer until a limit is reached.
+// Once the limit is reached, TruncatedNotice is written and further writes are
+// discarded. Writes beyond the limit report success, so the writer's source is
+// not interrupted. LimitedWriter is not thread-safe.
+type LimitedWriter struct {
+	w         io.Writer
+	remaining int64
+	truncated bool
+	// exceeded is closed once output is truncated.
+	exceeded chan struct{}
+}
+
+// Write writes p to the underlying io.Writer, up to the remaining limit.
+func (l *LimitedWriter) Write(p []byte) (int, error) {
+	if l.truncated {
+		return len(p), nil
+	}
+
+	if int64(len(p)) <= l.remaining {
+		n, err := l.w.Write(p)
+		l.remaining -= int64(n)
+		return n, err
+	}
+
+	n, err := l.w.Write(p[:l.remaining])
+	l.remaining -= int64(n)
+	if err != nil {
+		return n, err
+	}
+	l.truncated = true
+	close(l.exceeded)
+	if _, err := io.WriteString(l.w, TruncatedNotice); err != nil {
+		return n, err
+	}
+	return len(p), nil
+}
+
+// Exceeded retrieves a channel that is closed once output has been discarded.
+// Unlike Truncated, Exceeded may be used concurrently with Write.
+func (l *LimitedWriter) Exceeded() <-chan struct{} {
+	return l.exceeded
+}
+
+// Truncated indicates output has been discarded.
+func (l *LimitedWriter) Truncated() bool {
+	return l.truncated
+}
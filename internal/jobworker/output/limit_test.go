@@ -0,0 +1,67 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLimitedWriter(t *testing.T) {
+	type expected struct {
+		output    string
+		truncated bool
+	}
+	tests := map[string]struct {
+		limit  int64
+		writes []string
+		exp    expected
+	}{
+		"under limit": {
+			limit:  16,
+			writes: []string{"hello ", "world\n"},
+			exp:    expected{output: "hello world\n"},
+		},
+		"at limit": {
+			limit:  12,
+			writes: []string{"hello ", "world\n"},
+			exp:    expected{output: "hello world\n"},
+		},
+		"beyond limit": {
+			limit:  8,
+			writes: []string{"hello ", "world\n"},
+			exp:    expected{output: "hello wo" + TruncatedNotice, truncated: true},
+		},
+		"writes after truncation": {
+			limit:  4,
+			writes: []string{"hello ", "world\n", "goodbye\n"},
+			exp:    expected{output: "hell" + TruncatedNotice, truncated: true},
+		},
+		"beyond limit at boundary": {
+			limit:  6,
+			writes: []string{"hello ", "world\n"},
+			exp:    expected{output: "hello " + TruncatedNotice, truncated: true},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			w := NewLimitedWriter(&b, test.limit)
+			for _, write := range test.writes {
+				n, err := w.Write([]byte(write))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if n != len(write) {
+					t.Fatalf("unexpected bytes written; actual: %d, expected: %d", n, len(write))
+				}
+			}
+
+			if b.String() != test.exp.output {
+				t.Fatalf("unexpected output; actual: %q, expected: %q", b.String(), test.exp.output)
+			}
+			if w.Truncated() != test.exp.truncated {
+				t.Fatalf("unexpected truncated; actual: %v, expected: %v", w.Truncated(), test.exp.truncated)
+			}
+		})
+	}
+}
@@ -7,14 +7,29 @@ import (
 )
 
 const (
-	// Root is the default jobworker log output root directory.
-	Root = "/var/log/jobworker"
+	// DefaultRoot is the default jobworker log output root directory.
+	DefaultRoot = "/var/log/jobworker"
 	// FileMode is the default FileMode for log output resources.
 	FileMode = 0644
 )
 
+// Store locates jobworker log output within a root directory.
+type Store struct {
+	// Root is the directory log output is written within. If empty,
+	// DefaultRoot is used.
+	Root string
+}
+
+// Dir retrieves the directory log output is written within.
+func (s Store) Dir() string {
+	if s.Root == "" {
+		return DefaultRoot
+	}
+	return s.Root
+}
+
 // File returns the standard jobworker log file location based on the passed
 // id.
-func File(id fmt.Stringer) string {
-	return path.Join(Root, fmt.Sprintf("%s.log", id.String()))
+func (s Store) File(id fmt.Stringer) string {
+	return path.Join(s.Dir(), fmt.Sprintf("%s.log", id.String()))
 }
@@ -0,0 +1,166 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// errUnsupportedWhence indicates Reader.Seek was passed a whence other than
+// io.SeekStart.
+var errUnsupportedWhence = errors.New("unsupported whence")
+
+// Open opens the log output identified by id for reading across all of its
+// segments, see Segment. If the output does not exist, an error wrapping
+// fs.ErrNotExist is returned.
+func (s Store) Open(id fmt.Stringer) (*Reader, error) {
+	fd, err := os.Open(s.Segment(id, 0))
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{store: s, id: id, fd: fd}, nil
+}
+
+// Reader reads log output across its segments in order. Once the end of a
+// segment is reached, reading continues with the next segment if it has been
+// created. Reader follows output being written: reading the last segment to
+// its end returns io.EOF, and further reads return output written since.
+type Reader struct {
+	// mutex protects the Reader's fields, so the Reader may be closed while
+	// being read.
+	mutex sync.Mutex
+	store Store
+	id    fmt.Stringer
+	fd    *os.File
+	// segment is the number of the segment being read.
+	segment int
+	// skip is the number of bytes to discard before reading, when the Reader
+	// has been seeked beyond the end of the output.
+	skip   int64
+	closed bool
+}
+
+// Read reads output into b.
+func (r *Reader) Read(b []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for r.skip > 0 {
+		discard := make([]byte, min64(r.skip, int64(len(b))))
+		n, err := r.read(discard)
+		r.skip -= int64(n)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return r.read(b)
+}
+
+// read reads output into b, continuing to the next segment once the current
+// segment has been read. The Reader's mutex must be held.
+func (r *Reader) read(b []byte) (int, error) {
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+
+	for {
+		n, err := r.fd.Read(b)
+		if n > 0 || !errors.Is(err, io.EOF) {
+			return n, err
+		}
+
+		// The next segment is created once the current segment is full. Output
+		// may have been written to the current segment since it was read, so it
+		// is read once more prior to continuing.
+		next, err := os.Open(r.store.Segment(r.id, r.segment+1))
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, fmt.Errorf("open output segment; error: %w", err)
+		}
+		if n, err := r.fd.Read(b); n > 0 || !errors.Is(err, io.EOF) {
+			next.Close()
+			return n, err
+		}
+
+		r.fd.Close()
+		r.fd = next
+		r.segment++
+	}
+}
+
+// Seek sets the offset of the next Read to offset bytes from the start of the
+// output; only io.SeekStart is supported as whence. An offset beyond the end
+// of the output is reached once enough output has been written.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekStart {
+		return 0, fmt.Errorf("%w; whence: %d", errUnsupportedWhence, whence)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+
+	start := offset
+
+	for n := 0; ; n++ {
+		fd, err := os.Open(r.store.Segment(r.id, n))
+		if n > 0 && errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("open output segment; error: %w", err)
+		}
+		info, err := fd.Stat()
+		if err != nil {
+			fd.Close()
+			return 0, fmt.Errorf("stat output segment; error: %w", err)
+		}
+
+		r.fd.Close()
+		r.fd = fd
+		r.segment = n
+
+		if offset <= info.Size() {
+			r.skip = 0
+			if _, err := fd.Seek(offset, io.SeekStart); err != nil {
+				return 0, err
+			}
+			return start, nil
+		}
+		offset -= info.Size()
+	}
+
+	// The offset lies beyond the end of the output. Output is discarded until
+	// it is reached.
+	if _, err := r.fd.Seek(0, io.SeekEnd); err != nil {
+		return 0, err
+	}
+	r.skip = offset
+	return start, nil
+}
+
+// Close closes the Reader. If a Read is in progress, Close waits for it to
+// return.
+func (r *Reader) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.fd.Close()
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,129 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// Segment returns the location of the nth segment of the log output
+// identified by id. Output rotated by a RotatingWriter is spread across
+// segments; the first segment, 0, is File(id), and later segments are suffixed
+// with their number, e.g. "<id>.log.1".
+func (s Store) Segment(id fmt.Stringer, n int) string {
+	if n == 0 {
+		return s.File(id)
+	}
+	return fmt.Sprintf("%s.%d", s.File(id), n)
+}
+
+// NewRotatingWriter creates a RotatingWriter instance. fd is the first segment
+// of the output identified by id, and is owned by the caller. Each segment
+// holds at most limit bytes.
+func (s Store) NewRotatingWriter(fd *os.File, id fmt.Stringer, limit int64) *RotatingWriter {
+	return &RotatingWriter{store: s, first: fd, fd: fd, id: id, limit: limit}
+}
+
+// RotatingWriter writes output across segments of at most limit bytes. Once a
+// segment is full, writes roll over to the next segment, see Segment. Each
+// segment except the last is filled to exactly limit bytes, and the next
+// segment is created only once the previous segment is full. RotatingWriter
+// is not thread-safe.
+type RotatingWriter struct {
+	store     Store
+	first, fd *os.File
+	id        fmt.Stringer
+	limit     int64
+	// segment is the number of the segment being written.
+	segment int
+	// written is the number of bytes written to the segment.
+	written int64
+}
+
+// Write writes p to the current segment, rolling over to further segments as
+// each fills.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		if w.written == w.limit {
+			if err := w.rotate(); err != nil {
+				return n, err
+			}
+		}
+
+		b := p
+		if remaining := w.limit - w.written; int64(len(b)) > remaining {
+			b = b[:remaining]
+		}
+		m, err := w.fd.Write(b)
+		n += m
+		w.written += int64(m)
+		if err != nil {
+			return n, err
+		}
+		p = p[m:]
+	}
+	return n, nil
+}
+
+// rotate creates the next segment and writes to it from now on.
+func (w *RotatingWriter) rotate() error {
+	path := w.store.Segment(w.id, w.segment+1)
+	fd, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, FileMode)
+	if err != nil {
+		return fmt.Errorf("open output segment; path: %s, error: %w", path, err)
+	}
+	if err := w.Close(); err != nil {
+		fd.Close()
+		return err
+	}
+
+	w.fd = fd
+	w.segment++
+	w.written = 0
+	return nil
+}
+
+// Close closes the segment being written, unless it is the first segment,
+// which is owned by the RotatingWriter's caller.
+func (w *RotatingWriter) Close() error {
+	if w.fd == w.first {
+		return nil
+	}
+	if err := w.fd.Close(); err != nil {
+		return fmt.Errorf("close output segment; error: %w", err)
+	}
+	return nil
+}
+
+// Size retrieves the size in bytes of the log output identified by id, across
+// all of its segments. If the output does not exist, an error wrapping
+// fs.ErrNotExist is returned.
+func (s Store) Size(id fmt.Stringer) (int64, error) {
+	var size int64
+	for n := 0; ; n++ {
+		info, err := os.Stat(s.Segment(id, n))
+		if n > 0 && errors.Is(err, fs.ErrNotExist) {
+			return size, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		size += info.Size()
+	}
+}
+
+// Remove removes all segments of the log output identified by id. If the
+// output does not exist, an error wrapping fs.ErrNotExist is returned.
+func (s Store) Remove(id fmt.Stringer) error {
+	for n := 0; ; n++ {
+		err := os.Remove(s.Segment(id, n))
+		if n > 0 && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRotatingWriter(t *testing.T) {
+	type expected struct {
+		segments []string
+	}
+	tests := map[string]struct {
+		limit  int64
+		writes []string
+		exp    expected
+	}{
+		"under limit": {
+			limit:  16,
+			writes: []string{"hello ", "world\n"},
+			exp:    expected{segments: []string{"hello world\n"}},
+		},
+		"at limit": {
+			limit:  12,
+			writes: []string{"hello ", "world\n"},
+			exp:    expected{segments: []string{"hello world\n"}},
+		},
+		"write spans segments": {
+			limit:  4,
+			writes: []string{"hello ", "world\n"},
+			exp:    expected{segments: []string{"hell", "o wo", "rld\n"}},
+		},
+		"write fills segments": {
+			limit:  6,
+			writes: []string{"hello ", "world\n"},
+			exp:    expected{segments: []string{"hello ", "world\n"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			store, id := newTestOutput(t)
+			fd, err := os.OpenFile(store.File(id), os.O_WRONLY, FileMode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fd.Close()
+
+			w := store.NewRotatingWriter(fd, id, test.limit)
+			for _, write := range test.writes {
+				n, err := w.Write([]byte(write))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if n != len(write) {
+					t.Fatalf("unexpected write; actual: %v, expected: %v", n, len(write))
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for n, expected := range test.exp.segments {
+				b, err := os.ReadFile(store.Segment(id, n))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(b) != expected {
+					t.Fatalf("unexpected segment %d; actual: %q, expected: %q", n, b, expected)
+				}
+			}
+			if _, err := os.Stat(store.Segment(id, len(test.exp.segments))); !os.IsNotExist(err) {
+				t.Fatalf("unexpected segment %d; error: %v", len(test.exp.segments), err)
+			}
+		})
+	}
+}
+
+func TestReader(t *testing.T) {
+	const content = "abcdefghijklmnopqrstuvwxyz"
+
+	type expected struct {
+		output string
+	}
+	tests := map[string]struct {
+		offset int64
+		exp    expected
+	}{
+		"start": {
+			offset: 0,
+			exp:    expected{output: content},
+		},
+		"within segment": {
+			offset: 2,
+			exp:    expected{output: content[2:]},
+		},
+		"segment boundary": {
+			offset: 5,
+			exp:    expected{output: content[5:]},
+		},
+		"later segment": {
+			offset: 12,
+			exp:    expected{output: content[12:]},
+		},
+		"end": {
+			offset: int64(len(content)),
+			exp:    expected{output: ""},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			store, id := newTestOutput(t)
+			writeTestOutput(t, store, id, 5, content)
+
+			size, err := store.Size(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size != int64(len(content)) {
+				t.Fatalf("unexpected size; actual: %v, expected: %v", size, len(content))
+			}
+
+			r, err := store.Open(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			if _, err := r.Seek(test.offset, io.SeekStart); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// A small buffer reads each segment in several reads.
+			var output bytes.Buffer
+			if _, err := io.CopyBuffer(struct{ io.Writer }{&output}, r, make([]byte, 3)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output.String() != test.exp.output {
+				t.Fatalf("unexpected output; actual: %q, expected: %q", output.String(), test.exp.output)
+			}
+		})
+	}
+}
+
+func TestReaderFollow(t *testing.T) {
+	store, id := newTestOutput(t)
+	fd, err := os.OpenFile(store.File(id), os.O_WRONLY, FileMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	w := store.NewRotatingWriter(fd, id, 4)
+	defer w.Close()
+
+	r, err := store.Open(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// Seeking beyond the end of the output is reached once the output is
+	// written, even across segments that do not yet exist.
+	if _, err := r.Seek(6, io.SeekStart); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b := make([]byte, 16)
+	if n, err := r.Read(b); n != 0 || err != io.EOF {
+		t.Fatalf("unexpected read; n: %v, error: %v", n, err)
+	}
+
+	if _, err := w.Write([]byte("hello ")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := r.Read(b); n != 0 || err != io.EOF {
+		t.Fatalf("unexpected read; n: %v, error: %v", n, err)
+	}
+
+	if _, err := w.Write([]byte("world\n")); err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	if _, err := io.CopyBuffer(struct{ io.Writer }{&output}, r, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.String() != "world\n" {
+		t.Fatalf("unexpected output; actual: %q, expected: %q", output.String(), "world\n")
+	}
+}
+
+// newTestOutput creates an empty output within a temporary Store.
+func newTestOutput(t *testing.T) (Store, uuid.UUID) {
+	store := Store{Root: t.TempDir()}
+	id := uuid.New()
+	if err := os.WriteFile(store.File(id), nil, FileMode); err != nil {
+		t.Fatal(err)
+	}
+	return store, id
+}
+
+// writeTestOutput writes content to the output identified by id, rotated at
+// limit bytes.
+func writeTestOutput(t *testing.T, store Store, id uuid.UUID, limit int64, content string) {
+	fd, err := os.OpenFile(store.File(id), os.O_WRONLY, FileMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	w := store.NewRotatingWriter(fd, id, limit)
+	defer w.Close()
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+}
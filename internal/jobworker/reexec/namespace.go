@@ -0,0 +1,81 @@
+package reexec
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// loopback is the name of the loopback network interface.
+const loopback = "lo"
+
+// Cloneflags are the namespace flags the jobworker child is cloned with to
+// isolate the command. A PID namespace is paired with a mount namespace, so the
+// child may mount a procfs reflecting the PID namespace.
+func (c Command) Cloneflags() uintptr {
+	var flags uintptr
+	if c.IsolatePid || c.IsolateMount {
+		flags |= unix.CLONE_NEWNS
+	}
+	if c.IsolatePid {
+		flags |= unix.CLONE_NEWPID
+	}
+	if c.IsolateNetwork {
+		flags |= unix.CLONE_NEWNET
+	}
+	return flags
+}
+
+// setupNamespaces prepares the namespaces the child was cloned into, see
+// Command.Cloneflags, before the command is executed.
+func setupNamespaces(cmd Command) error {
+	if cmd.IsolatePid || cmd.IsolateMount {
+		// Mounts made within the child's mount namespace must not propagate to
+		// the host.
+		if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+			return fmt.Errorf("remount root private; error: %w", err)
+		}
+	}
+
+	if cmd.IsolatePid {
+		// The host's procfs lists all host processes; mount a procfs listing
+		// only the processes of the child's PID namespace.
+		flags := uintptr(unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC)
+		if err := unix.Mount("proc", "/proc", "proc", flags, ""); err != nil {
+			return fmt.Errorf("mount proc; error: %w", err)
+		}
+	}
+
+	if cmd.IsolateNetwork {
+		// A new network namespace holds only the loopback interface, which is
+		// down.
+		if err := linkUp(loopback); err != nil {
+			return fmt.Errorf("set loopback up; error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// linkUp sets the network interface name up.
+func linkUp(name string) error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("open socket; error: %w", err)
+	}
+	defer unix.Close(fd)
+
+	ifreq, err := unix.NewIfreq(name)
+	if err != nil {
+		return fmt.Errorf("new ifreq; interface: %s, error: %w", name, err)
+	}
+	if err := unix.IoctlIfreq(fd, unix.SIOCGIFFLAGS, ifreq); err != nil {
+		return fmt.Errorf("get interface flags; interface: %s, error: %w", name, err)
+	}
+	ifreq.SetUint16(ifreq.Uint16() | unix.IFF_UP)
+	if err := unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifreq); err != nil {
+		return fmt.Errorf("set interface flags; interface: %s, error: %w", name, err)
+	}
+
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"syscall"
 	"time"
 
@@ -30,6 +31,12 @@ var (
 	// ErrContinuePipeNotFound indicates that the parent process did not properly
 	// configure the continue pipe and pass it to the child process.
 	ErrContinuePipeNotFound = errors.New("continue pipe not found")
+	// ErrStatusPipeNotFound indicates that the parent process did not properly
+	// configure the status pipe and pass it to the child process.
+	ErrStatusPipeNotFound = errors.New("status pipe not found")
+	// ErrStartedPipeNotFound indicates that the parent process did not properly
+	// configure the started pipe and pass it to the child process.
+	ErrStartedPipeNotFound = errors.New("started pipe not found")
 )
 
 var (
@@ -54,6 +61,42 @@ type Job struct {
 	ID uuid.UUID
 	// Cmd is the arbitrary command to run as part of this Job.
 	Cmd Command
+	// MaxOutputBytes is the maximum number of bytes of output the command may
+	// write. Output beyond the limit is discarded. A zeroed value indicates no
+	// limit.
+	MaxOutputBytes int64
+	// KillOnMaxOutput indicates the command is killed once its output exceeds
+	// MaxOutputBytes, rather than continuing with its output discarded.
+	KillOnMaxOutput bool
+	// RotateBytes is the size of each segment of the command's output, see
+	// output.RotatingWriter. A zeroed value indicates the output is not
+	// rotated.
+	RotateBytes int64
+	// OutputDir is the directory the command's output is written within, see
+	// output.Store. If empty, output is written within output.DefaultRoot.
+	OutputDir string
+}
+
+// Exit is the exit state of a Job's command. The child passes Exit to the
+// parent once the command has exited, or once the child fails to setup the
+// command.
+type Exit struct {
+	// Code is the exit code of the command. If the command was terminated by a
+	// signal, Code is -1. If the child failed to setup the command, Code is
+	// CommandFailure.
+	Code int `json:"exitCode"`
+	// Signaled indicates the command was terminated by a signal.
+	Signaled bool `json:"signaled"`
+	// Signal is the signal that terminated the command. If the command was not
+	// terminated by a signal, Signal is 0.
+	Signal syscall.Signal `json:"signal"`
+	// SetupError describes why the child failed to setup the command, e.g. the
+	// command's executable was not found. If the command was setup, SetupError
+	// is empty.
+	SetupError string `json:"setupError,omitempty"`
+	// OutputLimited indicates the command was killed once its output exceeded
+	// the Job's MaxOutputBytes. See Job.KillOnMaxOutput.
+	OutputLimited bool `json:"outputLimited,omitempty"`
 }
 
 // Command represents a shell command.
@@ -62,6 +105,31 @@ type Command struct {
 	Name string
 	// Args are the arguments of the command.
 	Args []string
+	// Env are environment variables, in "key=value" form, set for the command
+	// in addition to the current process's environment.
+	Env []string
+	// Credential is the user and group the command is run as. If nil, the
+	// command is run as the current process's user and group.
+	Credential *Credential
+	// IsolatePid indicates the command is run within a new PID namespace, so
+	// it may not see or signal other processes on the host. The child is the
+	// namespace's init process; once the child exits, all processes within the
+	// namespace are killed.
+	IsolatePid bool
+	// IsolateMount indicates the command is run within a new mount namespace,
+	// so mounts made by the command do not propagate to the host.
+	IsolateMount bool
+	// IsolateNetwork indicates the command is run within a new network
+	// namespace, holding only a loopback interface.
+	IsolateNetwork bool
+}
+
+// Credential identifies the user and group a command is run as.
+type Credential struct {
+	// Uid is the user ID the command is run as.
+	Uid uint32
+	// Gid is the group ID the command is run as.
+	Gid uint32
 }
 
 // Exec utilizes the piped data from the parent process to build and run a
@@ -79,19 +147,54 @@ func Exec(ctx context.Context) (int, error) {
 		return CommandFailure, ErrContinuePipeNotFound
 	}
 
+	// Parent process has set the /proc/self/fd/5 to the status pipe writer. It
+	// is closed on exec so the parent observes EOF once this process exits,
+	// regardless of the grandchild.
+	statusfd := os.NewFile(uintptr(5), "/proc/self/fd/5")
+	if statusfd == nil {
+		return CommandFailure, ErrStatusPipeNotFound
+	}
+	syscall.CloseOnExec(int(statusfd.Fd()))
+	defer statusfd.Close()
+
+	// Parent process has set the /proc/self/fd/6 to the started pipe writer. A
+	// byte is written once the command is executing. Like the status pipe, it
+	// is closed on exec.
+	startedfd := os.NewFile(uintptr(6), "/proc/self/fd/6")
+	if startedfd == nil {
+		return CommandFailure, ErrStartedPipeNotFound
+	}
+	syscall.CloseOnExec(int(startedfd.Fd()))
+	defer startedfd.Close()
+
+	// setupFailure reports a failure to setup the command to the parent.
+	setupFailure := func(err error) (int, error) {
+		exit := Exit{Code: CommandFailure, SetupError: err.Error()}
+		if werr := writeExit(statusfd, exit); werr != nil {
+			logger.Errorf("reporting setup failure; error: %v", werr)
+		}
+		return CommandFailure, err
+	}
+
 	var buf bytes.Buffer
 	if _, err := buf.ReadFrom(cmdfd); err != nil {
-		return CommandFailure, fmt.Errorf("reexec read cmd in pipe; error: %w", err)
+		return setupFailure(fmt.Errorf("reexec read cmd in pipe; error: %w", err))
 	}
 	var job Job
 	if err := json.Unmarshal(buf.Bytes(), &job); err != nil {
-		return CommandFailure, fmt.Errorf("reexec unmarshal job; error: %w", err)
+		return setupFailure(fmt.Errorf("reexec unmarshal job; error: %w", err))
+	}
+	logger.Debugf("received job; job: %s, cmd: %s", job.ID, job.Cmd.Name)
+
+	if err := setupNamespaces(job.Cmd); err != nil {
+		return setupFailure(fmt.Errorf("reexec setup namespaces; error: %w", err))
 	}
 
 	// Create log file for stdout and stderr output.
-	outfd, err := os.OpenFile(output.File(job.ID), os.O_CREATE|os.O_WRONLY, output.FileMode)
+	store := output.Store{Root: job.OutputDir}
+	outfd, err := os.OpenFile(store.File(job.ID), os.O_CREATE|os.O_WRONLY, output.FileMode)
 	if err != nil {
-		return CommandFailure, fmt.Errorf("reexec open output file; error: %w", err)
+		return setupFailure(fmt.Errorf("reexec open output file; error: %w", err))
 	}
 	defer func() {
 		if err := outfd.Close(); err != nil {
@@ -101,9 +204,50 @@ func Exec(ctx context.Context) (int, error) {
 
 	// Build command to be run on host system.
 	cmd := exec.Command(job.Cmd.Name, job.Cmd.Args...)
+	cmd.Env = append(os.Environ(), job.Cmd.Env...)
 	cmd.Stdout = outfd
 	cmd.Stderr = outfd
 
+	if cred := job.Cmd.Credential; cred != nil {
+		// The output is owned by the command's user, so the command may reopen
+		// it, e.g. through /proc/self/fd.
+		if err := outfd.Chown(int(cred.Uid), int(cred.Gid)); err != nil {
+			return setupFailure(fmt.Errorf("reexec chown output file; error: %w", err))
+		}
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Credential: &syscall.Credential{Uid: cred.Uid, Gid: cred.Gid},
+		}
+		logger.Debugf("running command as; job: %s, uid: %d, gid: %d", job.ID, cred.Uid, cred.Gid)
+	}
+
+	// Rotated or limited output is copied from a pipe, so output may be split
+	// across segments, and output beyond the limit may be discarded.
+	var w io.Writer = outfd
+	if job.RotateBytes > 0 {
+		rotating := store.NewRotatingWriter(outfd, job.ID, job.RotateBytes)
+		defer func() {
+			if err := rotating.Close(); err != nil {
+				logger.Errorf("closing rotating output; error: %s", err)
+			}
+		}()
+		w = rotating
+	}
+	var limited *output.LimitedWriter
+	if job.MaxOutputBytes > 0 {
+		limited = output.NewLimitedWriter(w, job.MaxOutputBytes)
+		w = limited
+	}
+	var piped *pipedOutput
+	if job.RotateBytes > 0 || job.MaxOutputBytes > 0 {
+		piped, err = newPipedOutput(w)
+		if err != nil {
+			return setupFailure(fmt.Errorf("reexec pipe output; error: %w", err))
+		}
+		defer piped.close()
+		cmd.Stdout = piped.w
+		cmd.Stderr = piped.w
+	}
+
 	// Wait for continue signal from parent process. This will be sent once
 	// process has been placed in the appropriate cgroup. If the continue signal
 	// is not retrieved within 10 seconds of waiting, cancel.
@@ -111,15 +255,149 @@ func Exec(ctx context.Context) (int, error) {
 	defer cancel()
 
 	if err := waitForContinue(ctx, contfd); err != nil {
-		return CommandFailure, fmt.Errorf("reexec wait for continue; error: %w", err)
+		return setupFailure(fmt.Errorf("reexec wait for continue; error: %w", err))
+	}
+	logger.Debugf("received continue; job: %s", job.ID)
+
+	// The parent gracefully stops a Job by sending SIGTERM to the Job's process
+	// group, which includes the grandchild. Catch SIGTERM so this process
+	// outlives the grandchild and reports its exit.
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+
+	err = cmd.Start()
+	// The command holds its own copy of the piped output's writer.
+	if piped != nil {
+		piped.started()
+	}
+	if err != nil {
+		// Record the failure in the output so clients streaming it see why the
+		// command did not run.
+		if _, werr := fmt.Fprintln(outfd, err); werr != nil {
+			logger.Errorf("writing setup failure to output; error: %v", werr)
+		}
+		return setupFailure(fmt.Errorf("start grandchild; error: %w", err))
 	}
 
-	if err := cmd.Start(); err != nil {
-		return CommandFailure, fmt.Errorf("start grandchild; error: %w", err)
+	// Inform the parent the command is executing.
+	logger.Debugf("started command; job: %s, pid: %d", job.ID, cmd.Process.Pid)
+	if _, err := startedfd.Write([]byte{1}); err != nil {
+		logger.Errorf("writing started pipe; error: %v", err)
+	}
+	if err := startedfd.Close(); err != nil {
+		logger.Errorf("closing started pipe; error: %v", err)
+	}
+
+	waited := make(chan struct{})
+	outputLimited := make(chan bool, 1)
+	if limited != nil && job.KillOnMaxOutput {
+		go killOnExceeded(cmd.Process, limited, waited, outputLimited)
+	} else {
+		outputLimited <- false
 	}
 
 	err = cmd.Wait()
-	return exitCode(err), nil
+	close(waited)
+	if piped != nil {
+		piped.drain()
+	}
+	sig := exitSignal(err)
+	exit := Exit{
+		Code:          exitCode(err),
+		Signaled:      sig != 0,
+		Signal:        sig,
+		OutputLimited: <-outputLimited,
+	}
+
+	if err := writeExit(statusfd, exit); err != nil {
+		return exit.Code, err
+	}
+
+	return exit.Code, nil
+}
+
+// killOnExceeded kills process once the limited output is exceeded, unless
+// waited is closed first. Whether process was killed is sent on killed.
+func killOnExceeded(process *os.Process, limited *output.LimitedWriter, waited <-chan struct{}, killed chan<- bool) {
+	select {
+	case <-waited:
+		killed <- false
+	case <-limited.Exceeded():
+		logger.Debugf("output limit exceeded, killing command; pid: %d", process.Pid)
+		if err := process.Kill(); err != nil {
+			logger.Errorf("killing command; error: %v", err)
+		}
+		killed <- true
+	}
+}
+
+// pipedOutput copies a command's output to a writer. The command writes to w,
+// the writer of a pipe.
+type pipedOutput struct {
+	r, w *os.File
+	// copied is closed once the command's output has been copied.
+	copied chan struct{}
+}
+
+// newPipedOutput creates a pipedOutput instance copying to dst.
+func newPipedOutput(dst io.Writer) (*pipedOutput, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("output pipe; error: %w", err)
+	}
+
+	o := &pipedOutput{r: r, w: w, copied: make(chan struct{})}
+	go func() {
+		defer close(o.copied)
+		if _, err := io.Copy(dst, r); err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+			logger.Errorf("copying output; error: %v", err)
+		}
+	}()
+	return o, nil
+}
+
+// started closes this process's copy of the pipe writer, once the command
+// has been started with its own.
+func (o *pipedOutput) started() {
+	if err := o.w.Close(); err != nil {
+		logger.Errorf("closing output pipe writer; error: %v", err)
+	}
+}
+
+// drain waits for the command's output to be copied. Processes the command
+// left running may hold the pipe writer open, so drain waits at most
+// outputDrainTimeout.
+func (o *pipedOutput) drain() {
+	if err := o.r.SetReadDeadline(time.Now().Add(outputDrainTimeout)); err != nil {
+		logger.Errorf("setting output pipe deadline; error: %v", err)
+	}
+	<-o.copied
+}
+
+// close closes the pipe. The pipe writer has been closed already, unless the
+// command was not started.
+func (o *pipedOutput) close() {
+	_ = o.w.Close()
+	if err := o.r.Close(); err != nil {
+		logger.Errorf("closing output pipe reader; error: %v", err)
+	}
+}
+
+// outputDrainTimeout is the maximum duration piped output is copied for
+// once the command has exited.
+const outputDrainTimeout = time.Second
+
+// writeExit writes exit to the status pipe w.
+func writeExit(w io.Writer, exit Exit) error {
+	b, err := json.Marshal(exit)
+	if err != nil {
+		return fmt.Errorf("reexec marshal exit; error: %w", err)
+	}
+	if _, err := w.Write(b); err != nil {
+		return fmt.Errorf("reexec write status pipe; error: %w", err)
+	}
+	return nil
 }
 
 func exitCode(err error) int {
@@ -138,6 +416,20 @@ func exitCode(err error) int {
 	return CommandFailure
 }
 
+// exitSignal retrieves the signal that terminated a command from its Wait
+// error. If the command was not terminated by a signal, 0 is returned.
+func exitSignal(err error) syscall.Signal {
+	exitError := new(exec.ExitError)
+	if !errors.As(err, &exitError) {
+		return 0
+	}
+	status, ok := exitError.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return 0
+	}
+	return status.Signal()
+}
+
 // waitForContinue waits for EOF to be received from fd. The parent process
 // will close fd's writer when this process may continue.
 func waitForContinue(ctx context.Context, fd io.Reader) error {
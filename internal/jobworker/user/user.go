@@ -3,6 +3,7 @@ package user
 
 import (
 	"context"
+	"crypto/x509"
 
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/peer"
@@ -14,18 +15,41 @@ type Service struct{}
 // User extracts the user from the passed context if it exists. The
 // ok return value indicates if the user has been found on the context.
 func (s Service) User(ctx context.Context) (user string, ok bool) {
-	peer, ok := peer.FromContext(ctx)
+	cert, ok := certificate(ctx)
 	if !ok {
 		return "", false
 	}
+	return cert.Subject.CommonName, true
+}
+
+// Roles extracts the roles of the user from the passed context if the user
+// exists. A user's roles are the Organization and OrganizationalUnit names of
+// its certificate's subject, so CAs constraining either may issue roles. The
+// ok return value indicates if the user has been found on the context.
+func (s Service) Roles(ctx context.Context) (roles []string, ok bool) {
+	cert, ok := certificate(ctx)
+	if !ok {
+		return nil, false
+	}
+	roles = append(roles, cert.Subject.Organization...)
+	roles = append(roles, cert.Subject.OrganizationalUnit...)
+	return roles, true
+}
+
+// certificate extracts the user's certificate, the leaf of the peer's first
+// verified chain, from the passed context if it exists.
+func certificate(ctx context.Context) (*x509.Certificate, bool) {
+	peer, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
 	tlsInfo, ok := peer.AuthInfo.(credentials.TLSInfo)
 	if !ok {
-		return "", false
+		return nil, false
 	}
 	if len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
-		return "", false
+		return nil, false
 	}
 
-	user = tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
-	return user, true
+	return tlsInfo.State.VerifiedChains[0][0], true
 }
@@ -0,0 +1,149 @@
+package user
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func TestUser(t *testing.T) {
+	type expected struct {
+		user string
+		ok   bool
+	}
+	tests := map[string]struct {
+		ctx context.Context
+		exp expected
+	}{
+		"verified chain": {
+			ctx: peerContext(credentials.TLSInfo{
+				State: tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{
+						{
+							{Subject: pkix.Name{CommonName: "alpha_user"}},
+							{Subject: pkix.Name{CommonName: "ca"}},
+						},
+					},
+				},
+			}),
+			exp: expected{user: "alpha_user", ok: true},
+		},
+		"no verified chains": {
+			ctx: peerContext(credentials.TLSInfo{}),
+			exp: expected{user: "", ok: false},
+		},
+		"empty verified chain": {
+			ctx: peerContext(credentials.TLSInfo{
+				State: tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{{}},
+				},
+			}),
+			exp: expected{user: "", ok: false},
+		},
+		"no tls info": {
+			ctx: peer.NewContext(context.Background(), &peer.Peer{}),
+			exp: expected{user: "", ok: false},
+		},
+		"no peer": {
+			ctx: context.Background(),
+			exp: expected{user: "", ok: false},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			user, ok := Service{}.User(test.ctx)
+			if ok != test.exp.ok {
+				t.Fatalf("unexpected ok; actual: %v, expected: %v", ok, test.exp.ok)
+			}
+			if user != test.exp.user {
+				t.Fatalf("unexpected user; actual: %v, expected: %v", user, test.exp.user)
+			}
+		})
+	}
+}
+
+func TestRoles(t *testing.T) {
+	type expected struct {
+		roles []string
+		ok    bool
+	}
+	tests := map[string]struct {
+		ctx context.Context
+		exp expected
+	}{
+		"organizations": {
+			ctx: peerContext(credentials.TLSInfo{
+				State: tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{
+						{
+							{Subject: pkix.Name{CommonName: "alpha_user", Organization: []string{"admin", "read-only"}}},
+							{Subject: pkix.Name{CommonName: "ca", Organization: []string{"ca_org"}}},
+						},
+					},
+				},
+			}),
+			exp: expected{roles: []string{"admin", "read-only"}, ok: true},
+		},
+		"organizational units": {
+			ctx: peerContext(credentials.TLSInfo{
+				State: tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{
+						{{Subject: pkix.Name{CommonName: "alpha_user", OrganizationalUnit: []string{"read-only"}}}},
+					},
+				},
+			}),
+			exp: expected{roles: []string{"read-only"}, ok: true},
+		},
+		"organizations and organizational units": {
+			ctx: peerContext(credentials.TLSInfo{
+				State: tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{
+						{{Subject: pkix.Name{
+							CommonName:         "alpha_user",
+							Organization:       []string{"admin"},
+							OrganizationalUnit: []string{"read-only"},
+						}}},
+					},
+				},
+			}),
+			exp: expected{roles: []string{"admin", "read-only"}, ok: true},
+		},
+		"no organizations": {
+			ctx: peerContext(credentials.TLSInfo{
+				State: tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{
+						{{Subject: pkix.Name{CommonName: "alpha_user"}}},
+					},
+				},
+			}),
+			exp: expected{roles: nil, ok: true},
+		},
+		"no peer": {
+			ctx: context.Background(),
+			exp: expected{roles: nil, ok: false},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			roles, ok := Service{}.Roles(test.ctx)
+			if ok != test.exp.ok {
+				t.Fatalf("unexpected ok; actual: %v, expected: %v", ok, test.exp.ok)
+			}
+			if !reflect.DeepEqual(roles, test.exp.roles) {
+				t.Fatalf("unexpected roles; actual: %v, expected: %v", roles, test.exp.roles)
+			}
+		})
+	}
+}
+
+func peerContext(info credentials.TLSInfo) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: info})
+}
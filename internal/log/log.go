@@ -1,21 +1,88 @@
 package log
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"runtime"
 	"strings"
+	"sync/atomic"
 )
 
-// New creates a Logger instance.
+// Level is the severity threshold of a Logger. Messages less severe than the
+// Level are not printed.
+type Level int32
+
+const (
+	// ErrorLevel prints error messages only.
+	ErrorLevel Level = iota + 1
+	// WarnLevel prints error and warn messages.
+	WarnLevel
+	// InfoLevel prints error, warn, and info messages.
+	InfoLevel
+	// DebugLevel prints all messages.
+	DebugLevel
+)
+
+// ErrUnknownLevel indicates a level name is not recognized.
+var ErrUnknownLevel = errors.New("unknown log level")
+
+// ParseLevel parses the name of a Level, e.g. "warn". Parsing is case
+// insensitive.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "error":
+		return ErrorLevel, nil
+	case "warn":
+		return WarnLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "debug":
+		return DebugLevel, nil
+	}
+	return 0, fmt.Errorf("%w; level: %q", ErrUnknownLevel, name)
+}
+
+// String returns the name of the Level.
+func (lvl Level) String() string {
+	switch lvl {
+	case ErrorLevel:
+		return "error"
+	case WarnLevel:
+		return "warn"
+	case InfoLevel:
+		return "info"
+	case DebugLevel:
+		return "debug"
+	}
+	return fmt.Sprintf("Level(%d)", int32(lvl))
+}
+
+// level is the Level of Loggers created with New. Accessed atomically.
+var level = int32(InfoLevel)
+
+// SetLevel sets the Level of all Loggers created with New.
+func SetLevel(lvl Level) {
+	atomic.StoreInt32(&level, int32(lvl))
+}
+
+// New creates a Logger instance. The Logger's Level is set with SetLevel,
+// defaulting to InfoLevel.
 func New(w io.Writer, prefix string) *Logger {
+	return NewWithLevel(w, prefix, 0)
+}
+
+// NewWithLevel creates a Logger instance with the specified Level, regardless
+// of SetLevel.
+func NewWithLevel(w io.Writer, prefix string, lvl Level) *Logger {
 	return &Logger{
-		log.New(
+		Logger: log.New(
 			w,
 			prefix,
 			log.Ldate|log.Ltime|log.Lmicroseconds|log.LUTC|log.Lmsgprefix,
 		),
+		level: lvl,
 	}
 }
 
@@ -24,24 +91,49 @@ func New(w io.Writer, prefix string) *Logger {
 // is thread-safe; it guarantees to serialize access to the Writer.
 type Logger struct {
 	*log.Logger
+
+	// level is the Logger's Level. If zero, the Level set with SetLevel is
+	// used.
+	level Level
 }
 
 // Errorf prints an error log-level message.
 func (l Logger) Errorf(msg string, args ...interface{}) {
-	file, line := caller(2)
-	l.Printf("[ERROR] %s:%d --- %s", file, line, fmt.Sprintf(msg, args...))
+	l.output(ErrorLevel, "[ERROR]", msg, args...)
 }
 
 // Warnf prints a warn log-level message.
 func (l Logger) Warnf(msg string, args ...interface{}) {
-	file, line := caller(2)
-	l.Printf("[WARN] %s:%d --- %s", file, line, fmt.Sprintf(msg, args...))
+	l.output(WarnLevel, "[WARN]", msg, args...)
 }
 
 // Infof prints an info log-level message.
 func (l Logger) Infof(msg string, args ...interface{}) {
-	file, line := caller(2)
-	l.Printf("[INFO] %s:%d --- %s", file, line, fmt.Sprintf(msg, args...))
+	l.output(InfoLevel, "[INFO]", msg, args...)
+}
+
+// Debugf prints a debug log-level message.
+func (l Logger) Debugf(msg string, args ...interface{}) {
+	l.output(DebugLevel, "[DEBUG]", msg, args...)
+}
+
+// enabled indicates messages of the specified Level are printed.
+func (l Logger) enabled(lvl Level) bool {
+	threshold := l.level
+	if threshold == 0 {
+		threshold = Level(atomic.LoadInt32(&level))
+	}
+	return lvl <= threshold
+}
+
+// output prints a message of the specified Level, if enabled. output must be
+// called directly by the exported logging methods, so the caller is reported.
+func (l Logger) output(lvl Level, tag string, msg string, args ...interface{}) {
+	if !l.enabled(lvl) {
+		return
+	}
+	file, line := caller(3)
+	l.Printf("%s %s:%d --- %s", tag, file, line, fmt.Sprintf(msg, args...))
 }
 
 func caller(depth int) (string, int) {
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLevel(t *testing.T) {
+	type expected struct {
+		printed []string
+	}
+	tests := map[string]struct {
+		level Level
+		exp   expected
+	}{
+		"error": {
+			level: ErrorLevel,
+			exp:   expected{printed: []string{"[ERROR]"}},
+		},
+		"warn": {
+			level: WarnLevel,
+			exp:   expected{printed: []string{"[ERROR]", "[WARN]"}},
+		},
+		"info": {
+			level: InfoLevel,
+			exp:   expected{printed: []string{"[ERROR]", "[WARN]", "[INFO]"}},
+		},
+		"debug": {
+			level: DebugLevel,
+			exp:   expected{printed: []string{"[ERROR]", "[WARN]", "[INFO]", "[DEBUG]"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			logger := NewWithLevel(&b, "test", test.level)
+			logger.Errorf("message")
+			logger.Warnf("message")
+			logger.Infof("message")
+			logger.Debugf("message")
+
+			lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+			if len(lines) != len(test.exp.printed) {
+				t.Fatalf("unexpected lines; actual: %q, expected: %v", lines, test.exp.printed)
+			}
+			for i, tag := range test.exp.printed {
+				if !strings.Contains(lines[i], tag+" internal/log/log_test.go:") {
+					t.Fatalf("unexpected line; actual: %q, expected tag: %s", lines[i], tag)
+				}
+			}
+		})
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	defer SetLevel(InfoLevel)
+
+	var b bytes.Buffer
+	logger := New(&b, "test")
+
+	logger.Debugf("message")
+	if b.Len() != 0 {
+		t.Fatalf("unexpected debug message at default level; actual: %q", b.String())
+	}
+
+	SetLevel(DebugLevel)
+	logger.Debugf("message")
+	if !strings.Contains(b.String(), "[DEBUG]") {
+		t.Fatalf("unexpected output after SetLevel; actual: %q", b.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	type expected struct {
+		level Level
+		err   bool
+	}
+	tests := map[string]struct {
+		name string
+		exp  expected
+	}{
+		"error":      {name: "error", exp: expected{level: ErrorLevel}},
+		"warn":       {name: "warn", exp: expected{level: WarnLevel}},
+		"info":       {name: "INFO", exp: expected{level: InfoLevel}},
+		"debug":      {name: "Debug", exp: expected{level: DebugLevel}},
+		"unknown":    {name: "verbose", exp: expected{err: true}},
+		"empty name": {name: "", exp: expected{err: true}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			level, err := ParseLevel(test.name)
+			if (err != nil) != test.exp.err {
+				t.Fatalf("unexpected error; actual: %v, expected: %v", err, test.exp.err)
+			}
+			if level != test.exp.level {
+				t.Fatalf("unexpected level; actual: %v, expected: %v", level, test.exp.level)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+// Package metrics provides types for recording jobworker metrics, and exposing
+// them in the Prometheus text format.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Metric is a metric that may be exposed by Handler.
+type Metric interface {
+	// write writes the metric to b in the Prometheus text format.
+	write(b *bytes.Buffer)
+}
+
+// Handler creates an http.Handler exposing metrics in the Prometheus text
+// format.
+func Handler(metrics ...Metric) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		var b bytes.Buffer
+		for _, metric := range metrics {
+			metric.write(&b)
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if _, err := w.Write(b.Bytes()); err != nil {
+			return
+		}
+	})
+}
+
+// NewCounter creates a Counter instance.
+func NewCounter(name, help string) *Counter {
+	return &Counter{name: name, help: help}
+}
+
+// Counter is a metric that only increases. Counter is thread-safe.
+type Counter struct {
+	name  string
+	help  string
+	value uint64
+}
+
+// Inc increments the Counter by one.
+func (c *Counter) Inc() {
+	atomic.AddUint64(&c.value, 1)
+}
+
+// Value retrieves the Counter's current value.
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.value)
+}
+
+// Name retrieves the Counter's name.
+func (c *Counter) Name() string {
+	return c.name
+}
+
+// Help retrieves the Counter's description.
+func (c *Counter) Help() string {
+	return c.help
+}
+
+func (c *Counter) write(b *bytes.Buffer) {
+	writeHeader(b, c.name, c.help, "counter")
+	fmt.Fprintf(b, "%s %d\n", c.name, c.Value())
+}
+
+// NewCounterVec creates a CounterVec instance. label is the name of the label
+// distinguishing the CounterVec's Counters.
+func NewCounterVec(name, help, label string) *CounterVec {
+	return &CounterVec{
+		mutex:    new(sync.Mutex),
+		name:     name,
+		help:     help,
+		label:    label,
+		counters: make(map[string]*Counter),
+	}
+}
+
+// CounterVec is a set of Counters sharing a name, distinguished by the value
+// of a label. CounterVec is thread-safe.
+type CounterVec struct {
+	mutex *sync.Mutex
+	name  string
+	help  string
+	label string
+	// counters is a mapping of label values to Counters.
+	counters map[string]*Counter
+}
+
+// With retrieves the Counter of the label value, creating it if necessary.
+func (v *CounterVec) With(value string) *Counter {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	counter, ok := v.counters[value]
+	if !ok {
+		counter = NewCounter(v.name, v.help)
+		v.counters[value] = counter
+	}
+	return counter
+}
+
+func (v *CounterVec) write(b *bytes.Buffer) {
+	v.mutex.Lock()
+	values := make([]string, 0, len(v.counters))
+	for value := range v.counters {
+		values = append(values, value)
+	}
+	v.mutex.Unlock()
+	sort.Strings(values)
+
+	writeHeader(b, v.name, v.help, "counter")
+	for _, value := range values {
+		fmt.Fprintf(b, "%s{%s=%q} %d\n", v.name, v.label, value, v.With(value).Value())
+	}
+}
+
+// NewGauge creates a Gauge instance.
+func NewGauge(name, help string) *Gauge {
+	return &Gauge{name: name, help: help}
+}
+
+// Gauge is a metric that may increase and decrease. Gauge is thread-safe.
+type Gauge struct {
+	name  string
+	help  string
+	value int64
+}
+
+// Inc increments the Gauge by one.
+func (g *Gauge) Inc() {
+	atomic.AddInt64(&g.value, 1)
+}
+
+// Dec decrements the Gauge by one.
+func (g *Gauge) Dec() {
+	atomic.AddInt64(&g.value, -1)
+}
+
+// Value retrieves the Gauge's current value.
+func (g *Gauge) Value() int64 {
+	return atomic.LoadInt64(&g.value)
+}
+
+func (g *Gauge) write(b *bytes.Buffer) {
+	writeHeader(b, g.name, g.help, "gauge")
+	fmt.Fprintf(b, "%s %d\n", g.name, g.Value())
+}
+
+// NewHistogram creates a Histogram instance. buckets are the upper bounds of
+// the Histogram's buckets, in increasing order.
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	return &Histogram{
+		mutex:   new(sync.Mutex),
+		name:    name,
+		help:    help,
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+// Histogram is a metric that counts observations in buckets. Histogram is
+// thread-safe.
+type Histogram struct {
+	mutex   *sync.Mutex
+	name    string
+	help    string
+	buckets []float64
+	// counts are the number of observations less than or equal to each bucket's
+	// upper bound.
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe records the observation value.
+func (h *Histogram) Observe(value float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// Count retrieves the number of observations recorded.
+func (h *Histogram) Count() uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.count
+}
+
+func (h *Histogram) write(b *bytes.Buffer) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	writeHeader(b, h.name, h.help, "histogram")
+	for i, bound := range h.buckets {
+		fmt.Fprintf(b, "%s_bucket{le=%q} %d\n", h.name, formatFloat(bound), h.counts[i])
+	}
+	fmt.Fprintf(b, "%s_bucket{le=%q} %d\n", h.name, formatFloat(math.Inf(1)), h.count)
+	fmt.Fprintf(b, "%s_sum %s\n", h.name, formatFloat(h.sum))
+	fmt.Fprintf(b, "%s_count %d\n", h.name, h.count)
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric to b.
+func writeHeader(b *bytes.Buffer, name, help, typ string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, typ)
+}
+
+// formatFloat formats f as a Prometheus text format value.
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
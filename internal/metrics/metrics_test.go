@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	type expected struct {
+		body string
+	}
+	tests := map[string]struct {
+		metric func() Metric
+		exp    expected
+	}{
+		"counter": {
+			metric: func() Metric {
+				counter := NewCounter("test_total", "Test counter.")
+				counter.Inc()
+				counter.Inc()
+				return counter
+			},
+			exp: expected{body: "# HELP test_total Test counter.\n" +
+				"# TYPE test_total counter\n" +
+				"test_total 2\n"},
+		},
+		"counter vec": {
+			metric: func() Metric {
+				vec := NewCounterVec("test_total", "Test counter vec.", "status")
+				vec.With("stopped").Inc()
+				vec.With("exited").Inc()
+				vec.With("exited").Inc()
+				return vec
+			},
+			exp: expected{body: "# HELP test_total Test counter vec.\n" +
+				"# TYPE test_total counter\n" +
+				"test_total{status=\"exited\"} 2\n" +
+				"test_total{status=\"stopped\"} 1\n"},
+		},
+		"gauge": {
+			metric: func() Metric {
+				gauge := NewGauge("test_running", "Test gauge.")
+				gauge.Inc()
+				gauge.Inc()
+				gauge.Dec()
+				return gauge
+			},
+			exp: expected{body: "# HELP test_running Test gauge.\n" +
+				"# TYPE test_running gauge\n" +
+				"test_running 1\n"},
+		},
+		"histogram": {
+			metric: func() Metric {
+				histogram := NewHistogram("test_seconds", "Test histogram.", []float64{1, 5})
+				histogram.Observe(0.5)
+				histogram.Observe(2)
+				histogram.Observe(10)
+				return histogram
+			},
+			exp: expected{body: "# HELP test_seconds Test histogram.\n" +
+				"# TYPE test_seconds histogram\n" +
+				"test_seconds_bucket{le=\"1\"} 1\n" +
+				"test_seconds_bucket{le=\"5\"} 2\n" +
+				"test_seconds_bucket{le=\"+Inf\"} 3\n" +
+				"test_seconds_sum 12.5\n" +
+				"test_seconds_count 3\n"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Handler(test.metric()).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+			body, err := io.ReadAll(rec.Body)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(body) != test.exp.body {
+				t.Fatalf("unexpected body; actual: %q, expected: %q", body, test.exp.body)
+			}
+		})
+	}
+}
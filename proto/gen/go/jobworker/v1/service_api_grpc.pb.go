@@ -19,8 +19,15 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type JobWorkerServiceClient interface {
 	Start(ctx context.Context, in *StartRequest, opts ...grpc.CallOption) (*StartResponse, error)
+	Restart(ctx context.Context, in *RestartRequest, opts ...grpc.CallOption) (*StartResponse, error)
 	Stop(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (*StopResponse, error)
+	Pause(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*PauseResponse, error)
+	Resume(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*ResumeResponse, error)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
 	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+	StatusWatch(ctx context.Context, in *StatusWatchRequest, opts ...grpc.CallOption) (JobWorkerService_StatusWatchClient, error)
+	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+	Wait(ctx context.Context, in *WaitRequest, opts ...grpc.CallOption) (*WaitResponse, error)
 	Output(ctx context.Context, in *OutputRequest, opts ...grpc.CallOption) (JobWorkerService_OutputClient, error)
 }
 
@@ -41,6 +48,15 @@ func (c *jobWorkerServiceClient) Start(ctx context.Context, in *StartRequest, op
 	return out, nil
 }
 
+func (c *jobWorkerServiceClient) Restart(ctx context.Context, in *RestartRequest, opts ...grpc.CallOption) (*StartResponse, error) {
+	out := new(StartResponse)
+	err := c.cc.Invoke(ctx, "/jobworker.v1.JobWorkerService/Restart", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *jobWorkerServiceClient) Stop(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (*StopResponse, error) {
 	out := new(StopResponse)
 	err := c.cc.Invoke(ctx, "/jobworker.v1.JobWorkerService/Stop", in, out, opts...)
@@ -50,6 +66,33 @@ func (c *jobWorkerServiceClient) Stop(ctx context.Context, in *StopRequest, opts
 	return out, nil
 }
 
+func (c *jobWorkerServiceClient) Pause(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*PauseResponse, error) {
+	out := new(PauseResponse)
+	err := c.cc.Invoke(ctx, "/jobworker.v1.JobWorkerService/Pause", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobWorkerServiceClient) Resume(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*ResumeResponse, error) {
+	out := new(ResumeResponse)
+	err := c.cc.Invoke(ctx, "/jobworker.v1.JobWorkerService/Resume", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobWorkerServiceClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, "/jobworker.v1.JobWorkerService/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *jobWorkerServiceClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error) {
 	out := new(StatusResponse)
 	err := c.cc.Invoke(ctx, "/jobworker.v1.JobWorkerService/Status", in, out, opts...)
@@ -59,8 +102,58 @@ func (c *jobWorkerServiceClient) Status(ctx context.Context, in *StatusRequest,
 	return out, nil
 }
 
+func (c *jobWorkerServiceClient) StatusWatch(ctx context.Context, in *StatusWatchRequest, opts ...grpc.CallOption) (JobWorkerService_StatusWatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &JobWorkerService_ServiceDesc.Streams[0], "/jobworker.v1.JobWorkerService/StatusWatch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &jobWorkerServiceStatusWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type JobWorkerService_StatusWatchClient interface {
+	Recv() (*StatusResponse, error)
+	grpc.ClientStream
+}
+
+type jobWorkerServiceStatusWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *jobWorkerServiceStatusWatchClient) Recv() (*StatusResponse, error) {
+	m := new(StatusResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *jobWorkerServiceClient) Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, "/jobworker.v1.JobWorkerService/Stats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobWorkerServiceClient) Wait(ctx context.Context, in *WaitRequest, opts ...grpc.CallOption) (*WaitResponse, error) {
+	out := new(WaitResponse)
+	err := c.cc.Invoke(ctx, "/jobworker.v1.JobWorkerService/Wait", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *jobWorkerServiceClient) Output(ctx context.Context, in *OutputRequest, opts ...grpc.CallOption) (JobWorkerService_OutputClient, error) {
-	stream, err := c.cc.NewStream(ctx, &JobWorkerService_ServiceDesc.Streams[0], "/jobworker.v1.JobWorkerService/Output", opts...)
+	stream, err := c.cc.NewStream(ctx, &JobWorkerService_ServiceDesc.Streams[1], "/jobworker.v1.JobWorkerService/Output", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -96,8 +189,15 @@ func (x *jobWorkerServiceOutputClient) Recv() (*OutputResponse, error) {
 // for forward compatibility
 type JobWorkerServiceServer interface {
 	Start(context.Context, *StartRequest) (*StartResponse, error)
+	Restart(context.Context, *RestartRequest) (*StartResponse, error)
 	Stop(context.Context, *StopRequest) (*StopResponse, error)
+	Pause(context.Context, *PauseRequest) (*PauseResponse, error)
+	Resume(context.Context, *ResumeRequest) (*ResumeResponse, error)
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
 	Status(context.Context, *StatusRequest) (*StatusResponse, error)
+	StatusWatch(*StatusWatchRequest, JobWorkerService_StatusWatchServer) error
+	Stats(context.Context, *StatsRequest) (*StatsResponse, error)
+	Wait(context.Context, *WaitRequest) (*WaitResponse, error)
 	Output(*OutputRequest, JobWorkerService_OutputServer) error
 }
 
@@ -108,12 +208,33 @@ type UnimplementedJobWorkerServiceServer struct {
 func (UnimplementedJobWorkerServiceServer) Start(context.Context, *StartRequest) (*StartResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Start not implemented")
 }
+func (UnimplementedJobWorkerServiceServer) Restart(context.Context, *RestartRequest) (*StartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Restart not implemented")
+}
 func (UnimplementedJobWorkerServiceServer) Stop(context.Context, *StopRequest) (*StopResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Stop not implemented")
 }
+func (UnimplementedJobWorkerServiceServer) Pause(context.Context, *PauseRequest) (*PauseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Pause not implemented")
+}
+func (UnimplementedJobWorkerServiceServer) Resume(context.Context, *ResumeRequest) (*ResumeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resume not implemented")
+}
+func (UnimplementedJobWorkerServiceServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
 func (UnimplementedJobWorkerServiceServer) Status(context.Context, *StatusRequest) (*StatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
 }
+func (UnimplementedJobWorkerServiceServer) StatusWatch(*StatusWatchRequest, JobWorkerService_StatusWatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method StatusWatch not implemented")
+}
+func (UnimplementedJobWorkerServiceServer) Stats(context.Context, *StatsRequest) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
+func (UnimplementedJobWorkerServiceServer) Wait(context.Context, *WaitRequest) (*WaitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Wait not implemented")
+}
 func (UnimplementedJobWorkerServiceServer) Output(*OutputRequest, JobWorkerService_OutputServer) error {
 	return status.Errorf(codes.Unimplemented, "method Output not implemented")
 }
@@ -147,6 +268,24 @@ func _JobWorkerService_Start_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _JobWorkerService_Restart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobWorkerServiceServer).Restart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/jobworker.v1.JobWorkerService/Restart",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobWorkerServiceServer).Restart(ctx, req.(*RestartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _JobWorkerService_Stop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(StopRequest)
 	if err := dec(in); err != nil {
@@ -165,6 +304,60 @@ func _JobWorkerService_Stop_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _JobWorkerService_Pause_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobWorkerServiceServer).Pause(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/jobworker.v1.JobWorkerService/Pause",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobWorkerServiceServer).Pause(ctx, req.(*PauseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobWorkerService_Resume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobWorkerServiceServer).Resume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/jobworker.v1.JobWorkerService/Resume",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobWorkerServiceServer).Resume(ctx, req.(*ResumeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobWorkerService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobWorkerServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/jobworker.v1.JobWorkerService/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobWorkerServiceServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _JobWorkerService_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(StatusRequest)
 	if err := dec(in); err != nil {
@@ -183,6 +376,63 @@ func _JobWorkerService_Status_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _JobWorkerService_StatusWatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StatusWatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(JobWorkerServiceServer).StatusWatch(m, &jobWorkerServiceStatusWatchServer{stream})
+}
+
+type JobWorkerService_StatusWatchServer interface {
+	Send(*StatusResponse) error
+	grpc.ServerStream
+}
+
+type jobWorkerServiceStatusWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *jobWorkerServiceStatusWatchServer) Send(m *StatusResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _JobWorkerService_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobWorkerServiceServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/jobworker.v1.JobWorkerService/Stats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobWorkerServiceServer).Stats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobWorkerService_Wait_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobWorkerServiceServer).Wait(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/jobworker.v1.JobWorkerService/Wait",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobWorkerServiceServer).Wait(ctx, req.(*WaitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _JobWorkerService_Output_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(OutputRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -215,16 +465,45 @@ var JobWorkerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Start",
 			Handler:    _JobWorkerService_Start_Handler,
 		},
+		{
+			MethodName: "Restart",
+			Handler:    _JobWorkerService_Restart_Handler,
+		},
 		{
 			MethodName: "Stop",
 			Handler:    _JobWorkerService_Stop_Handler,
 		},
+		{
+			MethodName: "Pause",
+			Handler:    _JobWorkerService_Pause_Handler,
+		},
+		{
+			MethodName: "Resume",
+			Handler:    _JobWorkerService_Resume_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _JobWorkerService_Delete_Handler,
+		},
 		{
 			MethodName: "Status",
 			Handler:    _JobWorkerService_Status_Handler,
 		},
+		{
+			MethodName: "Stats",
+			Handler:    _JobWorkerService_Stats_Handler,
+		},
+		{
+			MethodName: "Wait",
+			Handler:    _JobWorkerService_Wait_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StatusWatch",
+			Handler:       _JobWorkerService_StatusWatch_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "Output",
 			Handler:       _JobWorkerService_Output_Handler,
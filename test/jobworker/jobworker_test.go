@@ -5,6 +5,9 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,11 +17,15 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 )
 
-var port = flag.Int("port", 8080, "port jobworker API is serving content on")
+var (
+	port       = flag.Int("port", 8080, "port jobworker API is serving content on")
+	serverName = flag.String("server_name", "localhost", "name the jobworker API's certificate is issued for")
+)
 
 func TestAuthentication(t *testing.T) {
 	flag.Parse()
@@ -48,7 +55,12 @@ func TestAuthentication(t *testing.T) {
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			config, err := encrypt.NewClientTLSConfig(test.clientCert, test.clientKey, test.caCert)
+			config, err := encrypt.NewClientTLSConfig(
+				test.clientCert,
+				test.clientKey,
+				test.caCert,
+				encrypt.WithServerName(*serverName),
+			)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 				return
@@ -76,7 +88,10 @@ func TestAuthentication(t *testing.T) {
 func TestStart(t *testing.T) {
 	type expected struct {
 		resp *pb.StartResponse
-		code codes.Code
+		// statuses are the statuses the started job may be reported with, as a
+		// short-lived job may have already exited.
+		statuses []pb.Status
+		code     codes.Code
 	}
 	tests := map[string]struct {
 		req *pb.StartRequest
@@ -90,10 +105,10 @@ func TestStart(t *testing.T) {
 			exp: expected{
 				resp: &pb.StartResponse{
 					Command: &pb.Command{Name: "ls"},
-					Status:  &pb.StatusDetail{Status: pb.Status_STATUS_PENDING, ExitCode: -1},
 					Limits:  &pb.Limits{},
 				},
-				code: codes.OK,
+				statuses: []pb.Status{pb.Status_STATUS_RUNNING, pb.Status_STATUS_EXITED},
+				code:     codes.OK,
 			},
 		},
 		"ls -la": {
@@ -104,10 +119,10 @@ func TestStart(t *testing.T) {
 			exp: expected{
 				resp: &pb.StartResponse{
 					Command: &pb.Command{Name: "ls", Args: []string{"-la"}},
-					Status:  &pb.StatusDetail{Status: pb.Status_STATUS_PENDING, ExitCode: -1},
 					Limits:  &pb.Limits{},
 				},
-				code: codes.OK,
+				statuses: []pb.Status{pb.Status_STATUS_RUNNING, pb.Status_STATUS_EXITED},
+				code:     codes.OK,
 			},
 		},
 		"ls w/ limits": {
@@ -115,25 +130,62 @@ func TestStart(t *testing.T) {
 				Command: &pb.Command{Name: "ls"},
 				Limits: &pb.Limits{
 					Memory:       100000,
-					Cpus:         1.5,
+					Cpus:         0.5,
 					DiskWriteBps: 10000,
 					DiskReadBps:  10000,
+					PidsMax:      64,
 				},
 			},
 			exp: expected{
 				resp: &pb.StartResponse{
 					Command: &pb.Command{Name: "ls"},
-					Status:  &pb.StatusDetail{Status: pb.Status_STATUS_PENDING, ExitCode: -1},
 					Limits: &pb.Limits{
 						Memory:       100000,
-						Cpus:         1.5,
+						Cpus:         0.5,
 						DiskWriteBps: 10000,
 						DiskReadBps:  10000,
+						PidsMax:      64,
 					},
 				},
-				code: codes.OK,
+				statuses: []pb.Status{pb.Status_STATUS_RUNNING, pb.Status_STATUS_EXITED},
+				code:     codes.OK,
 			},
 		},
+		"ls w/ cpu weight": {
+			req: &pb.StartRequest{
+				Command: &pb.Command{Name: "ls"},
+				Limits:  &pb.Limits{CpuWeight: 50},
+			},
+			exp: expected{
+				resp: &pb.StartResponse{
+					Command: &pb.Command{Name: "ls"},
+					Limits:  &pb.Limits{CpuWeight: 50},
+				},
+				statuses: []pb.Status{pb.Status_STATUS_RUNNING, pb.Status_STATUS_EXITED},
+				code:     codes.OK,
+			},
+		},
+		"command not found": {
+			req: &pb.StartRequest{
+				Command: &pb.Command{Name: "definitely-not-a-binary"},
+				Limits:  &pb.Limits{},
+			},
+			exp: expected{
+				resp: &pb.StartResponse{
+					Command: &pb.Command{Name: "definitely-not-a-binary"},
+					Limits:  &pb.Limits{},
+				},
+				statuses: []pb.Status{pb.Status_STATUS_FAILED},
+				code:     codes.OK,
+			},
+		},
+		"ls w/ cpu weight out of range": {
+			req: &pb.StartRequest{
+				Command: &pb.Command{Name: "ls"},
+				Limits:  &pb.Limits{CpuWeight: 10001},
+			},
+			exp: expected{code: codes.InvalidArgument},
+		},
 	}
 
 	for name, test := range tests {
@@ -148,12 +200,20 @@ func TestStart(t *testing.T) {
 			if status.Code(err) != test.exp.code {
 				t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), test.exp.code)
 			}
+			if err != nil {
+				return
+			}
 
 			if len(resp.JobId) == 0 {
 				t.Fatal("expected JobId in response")
 			}
 			resp.JobId = ""
 
+			if !containsStatus(test.exp.statuses, resp.Status.GetStatus()) {
+				t.Fatalf("unexpected status; actual: %v, expected one of: %v", resp.Status.GetStatus(), test.exp.statuses)
+			}
+			resp.Status = nil
+
 			if !proto.Equal(resp, test.exp.resp) {
 				t.Fatalf("unexpected response; actual: %v, expected: %v", resp, test.exp.resp)
 			}
@@ -161,6 +221,73 @@ func TestStart(t *testing.T) {
 	}
 }
 
+func TestStartCredential(t *testing.T) {
+	type expected struct {
+		code   codes.Code
+		output string
+	}
+	tests := map[string]struct {
+		credential *pb.Credential
+		exp        expected
+	}{
+		"unprivileged": {
+			credential: &pb.Credential{Uid: 65534, Gid: 65534},
+			exp:        expected{code: codes.OK, output: "65534\n65534\n"},
+		},
+		"root": {
+			credential: &pb.Credential{Uid: 0, Gid: 0},
+			exp:        expected{code: codes.PermissionDenied},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			suite := setup(t)
+			defer suite.close(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			startResp, err := suite.client.Start(ctx, &pb.StartRequest{
+				Command: &pb.Command{
+					Name:       "bash",
+					Args:       []string{"-c", "id -u; id -g"},
+					Credential: test.credential,
+				},
+				Limits: &pb.Limits{},
+			})
+			if status.Code(err) != test.exp.code {
+				t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), test.exp.code)
+			}
+			if err != nil {
+				return
+			}
+
+			if _, err := suite.client.Wait(ctx, &pb.WaitRequest{JobId: startResp.JobId, RequireSuccess: true}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			stream, err := suite.client.Output(ctx, &pb.OutputRequest{JobId: startResp.JobId})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var b []byte
+			for {
+				resp, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				b = append(b, resp.Output...)
+			}
+			if string(b) != test.exp.output {
+				t.Fatalf("unexpected output; actual: %q, expected: %q", b, test.exp.output)
+			}
+		})
+	}
+}
+
 func TestStatus(t *testing.T) {
 	type expected struct {
 		resp *pb.StatusResponse
@@ -184,6 +311,23 @@ func TestStatus(t *testing.T) {
 				code: codes.OK,
 			},
 		},
+		"command not found": {
+			start: &pb.StartRequest{
+				Command: &pb.Command{Name: "definitely-not-a-binary"},
+				Limits:  &pb.Limits{},
+			},
+			wait: 200 * time.Millisecond,
+			exp: expected{
+				resp: &pb.StatusResponse{
+					Status: &pb.StatusDetail{
+						Status:     pb.Status_STATUS_FAILED,
+						ExitCode:   -1,
+						SetupError: `start grandchild; error: exec: "definitely-not-a-binary": executable file not found in $PATH`,
+					},
+				},
+				code: codes.OK,
+			},
+		},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -204,6 +348,17 @@ func TestStatus(t *testing.T) {
 			if status.Code(err) != test.exp.code {
 				t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), test.exp.code)
 			}
+
+			// A job whose command failed to execute never started running.
+			startedAt, finishedAt := resp.Status.StartedAt, resp.Status.FinishedAt
+			if failed := resp.Status.Status == pb.Status_STATUS_FAILED; (startedAt == nil) != failed || finishedAt == nil {
+				t.Fatalf("expected timestamps; started at: %v, finished at: %v", startedAt, finishedAt)
+			}
+			if startedAt != nil && finishedAt.AsTime().Before(startedAt.AsTime()) {
+				t.Fatalf("finished before started; started at: %v, finished at: %v", startedAt.AsTime(), finishedAt.AsTime())
+			}
+			resp.Status.StartedAt, resp.Status.FinishedAt = nil, nil
+
 			if !proto.Equal(resp, test.exp.resp) {
 				t.Fatalf("unexpected response; actual: %v, expected: %v", resp, test.exp.resp)
 			}
@@ -211,6 +366,54 @@ func TestStatus(t *testing.T) {
 	}
 }
 
+func TestRestart(t *testing.T) {
+	suite := setup(t)
+	defer suite.close(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := &pb.StartRequest{
+		Command: &pb.Command{Name: "bash", Args: []string{"-c", "echo $GREETING"}, Env: map[string]string{"GREETING": "hello"}},
+		Limits:  &pb.Limits{PidsMax: 64, MaxOutputBytes: 1024},
+	}
+	startResp, err := suite.client.Start(ctx, start)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := suite.client.Wait(ctx, &pb.WaitRequest{JobId: startResp.JobId}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	restartResp, err := suite.client.Restart(ctx, &pb.RestartRequest{JobId: startResp.JobId})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restartResp.JobId == startResp.JobId {
+		t.Fatalf("expected new job ID; job ID: %s", restartResp.JobId)
+	}
+	if !proto.Equal(restartResp.Command, start.Command) {
+		t.Fatalf("unexpected command; actual: %v, expected: %v", restartResp.Command, start.Command)
+	}
+	if !proto.Equal(restartResp.Limits, start.Limits) {
+		t.Fatalf("unexpected limits; actual: %v, expected: %v", restartResp.Limits, start.Limits)
+	}
+
+	// A running job may not be restarted.
+	sleepResp, err := suite.client.Start(ctx, &pb.StartRequest{
+		Command: &pb.Command{Name: "sleep", Args: []string{"10"}},
+		Limits:  &pb.Limits{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = suite.client.Restart(ctx, &pb.RestartRequest{JobId: sleepResp.JobId})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), codes.FailedPrecondition)
+	}
+}
+
 func TestStop(t *testing.T) {
 	type expected struct {
 		code codes.Code
@@ -258,12 +461,663 @@ func TestStop(t *testing.T) {
 	}
 }
 
+func TestPauseResume(t *testing.T) {
+	suite := setup(t)
+	defer suite.close(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	startResp, err := suite.client.Start(ctx, &pb.StartRequest{
+		Command: &pb.Command{Name: "sleep", Args: []string{"10"}},
+		Limits:  &pb.Limits{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	id := startResp.JobId
+
+	type expected struct {
+		code   codes.Code
+		status pb.Status
+	}
+	steps := []struct {
+		name string
+		call func() error
+		exp  expected
+	}{
+		{
+			name: "resume running",
+			call: func() error { _, err := suite.client.Resume(ctx, &pb.ResumeRequest{JobId: id}); return err },
+			exp:  expected{code: codes.FailedPrecondition, status: pb.Status_STATUS_RUNNING},
+		},
+		{
+			name: "pause",
+			call: func() error { _, err := suite.client.Pause(ctx, &pb.PauseRequest{JobId: id}); return err },
+			exp:  expected{code: codes.OK, status: pb.Status_STATUS_PAUSED},
+		},
+		{
+			name: "pause paused",
+			call: func() error { _, err := suite.client.Pause(ctx, &pb.PauseRequest{JobId: id}); return err },
+			exp:  expected{code: codes.FailedPrecondition, status: pb.Status_STATUS_PAUSED},
+		},
+		{
+			name: "resume",
+			call: func() error { _, err := suite.client.Resume(ctx, &pb.ResumeRequest{JobId: id}); return err },
+			exp:  expected{code: codes.OK, status: pb.Status_STATUS_RUNNING},
+		},
+		{
+			name: "pause again",
+			call: func() error { _, err := suite.client.Pause(ctx, &pb.PauseRequest{JobId: id}); return err },
+			exp:  expected{code: codes.OK, status: pb.Status_STATUS_PAUSED},
+		},
+		{
+			name: "stop paused",
+			call: func() error { _, err := suite.client.Stop(ctx, &pb.StopRequest{JobId: id}); return err },
+			exp:  expected{code: codes.OK, status: pb.Status_STATUS_STOPPED},
+		},
+		{
+			name: "pause stopped",
+			call: func() error { _, err := suite.client.Pause(ctx, &pb.PauseRequest{JobId: id}); return err },
+			exp:  expected{code: codes.FailedPrecondition, status: pb.Status_STATUS_STOPPED},
+		},
+	}
+	for _, step := range steps {
+		if err := step.call(); status.Code(err) != step.exp.code {
+			t.Fatalf("%s: unexpected code; actual: %v, expected: %v", step.name, status.Code(err), step.exp.code)
+		}
+
+		statusResp, err := suite.client.Status(ctx, &pb.StatusRequest{JobId: id})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", step.name, err)
+		}
+		if statusResp.Status.Status != step.exp.status {
+			t.Fatalf("%s: unexpected status; actual: %s, expected: %s", step.name, statusResp.Status.Status, step.exp.status)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	suite := setup(t)
+	defer suite.close(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	startResp, err := suite.client.Start(ctx, &pb.StartRequest{
+		Command: &pb.Command{Name: "sleep", Args: []string{"10"}},
+		Limits:  &pb.Limits{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	id := startResp.JobId
+
+	steps := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{
+			name: "delete running",
+			call: func() error { _, err := suite.client.Delete(ctx, &pb.DeleteRequest{JobId: id}); return err },
+			code: codes.FailedPrecondition,
+		},
+		{
+			name: "stop",
+			call: func() error { _, err := suite.client.Stop(ctx, &pb.StopRequest{JobId: id}); return err },
+			code: codes.OK,
+		},
+		{
+			name: "delete",
+			call: func() error { _, err := suite.client.Delete(ctx, &pb.DeleteRequest{JobId: id}); return err },
+			code: codes.OK,
+		},
+		{
+			name: "status deleted",
+			call: func() error { _, err := suite.client.Status(ctx, &pb.StatusRequest{JobId: id}); return err },
+			code: codes.NotFound,
+		},
+		{
+			name: "delete deleted",
+			call: func() error { _, err := suite.client.Delete(ctx, &pb.DeleteRequest{JobId: id}); return err },
+			code: codes.NotFound,
+		},
+	}
+	for _, step := range steps {
+		if err := step.call(); status.Code(err) != step.code {
+			t.Fatalf("%s: unexpected code; actual: %v, expected: %v", step.name, status.Code(err), step.code)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	suite := setup(t)
+	defer suite.close(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	startResp, err := suite.client.Start(ctx, &pb.StartRequest{
+		Command: &pb.Command{Name: "sleep", Args: []string{"10"}},
+		Limits:  &pb.Limits{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	id := startResp.JobId
+
+	steps := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{
+			name: "stats running",
+			call: func() error { _, err := suite.client.Stats(ctx, &pb.StatsRequest{JobId: id}); return err },
+			code: codes.OK,
+		},
+		{
+			name: "stop",
+			call: func() error { _, err := suite.client.Stop(ctx, &pb.StopRequest{JobId: id}); return err },
+			code: codes.OK,
+		},
+		{
+			name: "stats stopped",
+			call: func() error { _, err := suite.client.Stats(ctx, &pb.StatsRequest{JobId: id}); return err },
+			code: codes.FailedPrecondition,
+		},
+	}
+	for _, step := range steps {
+		if err := step.call(); status.Code(err) != step.code {
+			t.Fatalf("%s: unexpected code; actual: %v, expected: %v", step.name, status.Code(err), step.code)
+		}
+	}
+}
+
+func TestStatusWatch(t *testing.T) {
+	type expected struct {
+		statuses []pb.Status
+	}
+	tests := map[string]struct {
+		start *pb.StartRequest
+		wait  time.Duration
+		exp   expected
+	}{
+		"running": {
+			start: &pb.StartRequest{
+				Command: &pb.Command{Name: "sleep", Args: []string{"0.5"}},
+				Limits:  &pb.Limits{},
+			},
+			exp: expected{
+				statuses: []pb.Status{pb.Status_STATUS_RUNNING, pb.Status_STATUS_EXITED},
+			},
+		},
+		"exited": {
+			start: &pb.StartRequest{
+				Command: &pb.Command{Name: "ls"},
+				Limits:  &pb.Limits{},
+			},
+			wait: 200 * time.Millisecond,
+			exp: expected{
+				statuses: []pb.Status{pb.Status_STATUS_EXITED},
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			suite := setup(t)
+			defer suite.close(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			startResp, err := suite.client.Start(ctx, test.start)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			time.Sleep(test.wait)
+
+			stream, err := suite.client.StatusWatch(ctx, &pb.StatusWatchRequest{JobId: startResp.JobId})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var statuses []pb.Status
+			for {
+				resp, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				statuses = append(statuses, resp.Status.Status)
+			}
+
+			if !reflect.DeepEqual(statuses, test.exp.statuses) {
+				t.Fatalf("unexpected statuses; actual: %v, expected: %v", statuses, test.exp.statuses)
+			}
+		})
+	}
+}
+
+func TestWait(t *testing.T) {
+	type expected struct {
+		status   pb.Status
+		exitCode int32
+		code     codes.Code
+	}
+	tests := map[string]struct {
+		start          *pb.StartRequest
+		requireSuccess bool
+		// timeout is the deadline of the wait. If zero, the wait is not limited.
+		timeout time.Duration
+		exp     expected
+	}{
+		"exit 0": {
+			start: &pb.StartRequest{
+				Command: &pb.Command{Name: "bash", Args: []string{"-c", "sleep 0.1; exit 0"}},
+				Limits:  &pb.Limits{},
+			},
+			exp: expected{status: pb.Status_STATUS_EXITED, exitCode: 0, code: codes.OK},
+		},
+		"exit 3": {
+			start: &pb.StartRequest{
+				Command: &pb.Command{Name: "bash", Args: []string{"-c", "sleep 0.1; exit 3"}},
+				Limits:  &pb.Limits{},
+			},
+			exp: expected{status: pb.Status_STATUS_EXITED, exitCode: 3, code: codes.OK},
+		},
+		"exit 0 w/ require success": {
+			start: &pb.StartRequest{
+				Command: &pb.Command{Name: "bash", Args: []string{"-c", "sleep 0.1; exit 0"}},
+				Limits:  &pb.Limits{},
+			},
+			requireSuccess: true,
+			exp:            expected{status: pb.Status_STATUS_EXITED, exitCode: 0, code: codes.OK},
+		},
+		"exit 3 w/ require success": {
+			start: &pb.StartRequest{
+				Command: &pb.Command{Name: "bash", Args: []string{"-c", "sleep 0.1; exit 3"}},
+				Limits:  &pb.Limits{},
+			},
+			requireSuccess: true,
+			exp:            expected{code: codes.Aborted},
+		},
+		"sleep 10 w/ deadline": {
+			start: &pb.StartRequest{
+				Command: &pb.Command{Name: "sleep", Args: []string{"10"}},
+				Limits:  &pb.Limits{},
+			},
+			timeout: 500 * time.Millisecond,
+			exp:     expected{code: codes.DeadlineExceeded},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			suite := setup(t)
+			defer suite.close(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			startResp, err := suite.client.Start(ctx, test.start)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			waitCtx := ctx
+			if test.timeout > 0 {
+				var waitCancel context.CancelFunc
+				waitCtx, waitCancel = context.WithTimeout(ctx, test.timeout)
+				defer waitCancel()
+			}
+
+			resp, err := suite.client.Wait(waitCtx, &pb.WaitRequest{
+				JobId:          startResp.JobId,
+				RequireSuccess: test.requireSuccess,
+			})
+			if status.Code(err) != test.exp.code {
+				t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), test.exp.code)
+			}
+			if err != nil {
+				return
+			}
+
+			if resp.Status.Status != test.exp.status {
+				t.Fatalf("unexpected status; actual: %s, expected: %s", resp.Status.Status, test.exp.status)
+			}
+			if resp.Status.ExitCode != test.exp.exitCode {
+				t.Fatalf("unexpected exit code; actual: %d, expected: %d", resp.Status.ExitCode, test.exp.exitCode)
+			}
+		})
+	}
+}
+
+func TestOutputTrailer(t *testing.T) {
+	type expected struct {
+		streamed string
+		size     string
+	}
+	tests := map[string]struct {
+		cmd    *pb.Command
+		output *pb.OutputRequest
+		exp    expected
+	}{
+		"complete": {
+			cmd:    &pb.Command{Name: "bash", Args: []string{"-c", "echo hello world"}},
+			output: &pb.OutputRequest{},
+			exp:    expected{streamed: "12", size: "12"},
+		},
+		"missed output": {
+			cmd:    &pb.Command{Name: "bash", Args: []string{"-c", "echo hello world"}},
+			output: &pb.OutputRequest{TailBytes: 6},
+			exp:    expected{streamed: "6", size: "12"},
+		},
+		"running w/o follow": {
+			cmd:    &pb.Command{Name: "bash", Args: []string{"-c", "echo hello world; sleep 1"}},
+			output: &pb.OutputRequest{Follow: proto.Bool(false)},
+			exp:    expected{streamed: "12", size: ""},
+		},
+		"from end": {
+			cmd:    &pb.Command{Name: "bash", Args: []string{"-c", "echo hello world; sleep 1; echo goodbye"}},
+			output: &pb.OutputRequest{FromEnd: true},
+			exp:    expected{streamed: "8", size: "20"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			suite := setup(t)
+			defer suite.close(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			startResp, err := suite.client.Start(ctx, &pb.StartRequest{Command: test.cmd, Limits: &pb.Limits{}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Allow the job to write its output.
+			time.Sleep(200 * time.Millisecond)
+
+			test.output.JobId = startResp.JobId
+			stream, err := suite.client.Output(ctx, test.output)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for {
+				_, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			trailer := stream.Trailer()
+			if streamed := strings.Join(trailer.Get("output-streamed-bytes"), ""); streamed != test.exp.streamed {
+				t.Fatalf("unexpected streamed bytes; actual: %q, expected: %q", streamed, test.exp.streamed)
+			}
+			if size := strings.Join(trailer.Get("output-size"), ""); size != test.exp.size {
+				t.Fatalf("unexpected output size; actual: %q, expected: %q", size, test.exp.size)
+			}
+		})
+	}
+}
+
+func TestOutputChunkSize(t *testing.T) {
+	type expected struct {
+		code codes.Code
+		// maxChunk is the maximum output bytes expected in a response.
+		maxChunk int
+	}
+	tests := map[string]struct {
+		chunkSize uint32
+		exp       expected
+	}{
+		"default": {
+			exp: expected{code: codes.OK, maxChunk: 3000},
+		},
+		"1KiB": {
+			chunkSize: 1 << 10,
+			exp:       expected{code: codes.OK, maxChunk: 1 << 10},
+		},
+		"below min": {
+			chunkSize: 512,
+			exp:       expected{code: codes.InvalidArgument},
+		},
+		"above max": {
+			chunkSize: 2 << 20,
+			exp:       expected{code: codes.InvalidArgument},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			suite := setup(t)
+			defer suite.close(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			startResp, err := suite.client.Start(ctx, &pb.StartRequest{
+				Command: &pb.Command{Name: "head", Args: []string{"-c", "3000", "/dev/zero"}},
+				Limits:  &pb.Limits{},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Allow the job to write its output.
+			time.Sleep(200 * time.Millisecond)
+
+			stream, err := suite.client.Output(ctx, &pb.OutputRequest{JobId: startResp.JobId, ChunkSize: test.chunkSize})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var total, max int
+			for {
+				resp, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if status.Code(err) != test.exp.code {
+					t.Fatalf("unexpected code; actual: %v, expected: %v", status.Code(err), test.exp.code)
+				}
+				if err != nil {
+					return
+				}
+				total += len(resp.Output)
+				if len(resp.Output) > max {
+					max = len(resp.Output)
+				}
+			}
+
+			if test.exp.code != codes.OK {
+				t.Fatalf("unexpected code; actual: %v, expected: %v", codes.OK, test.exp.code)
+			}
+			if total != 3000 {
+				t.Fatalf("unexpected output bytes; actual: %d, expected: %d", total, 3000)
+			}
+			if max > test.exp.maxChunk {
+				t.Fatalf("unexpected chunk size; actual: %d, expected at most: %d", max, test.exp.maxChunk)
+			}
+		})
+	}
+}
+
+func TestOutputSnapshot(t *testing.T) {
+	suite := setup(t)
+	defer suite.close(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	startResp, err := suite.client.Start(ctx, &pb.StartRequest{
+		Command: &pb.Command{Name: "bash", Args: []string{"-c", "echo hello world; sleep 10"}},
+		Limits:  &pb.Limits{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		if _, err := suite.client.Stop(ctx, &pb.StopRequest{JobId: startResp.JobId}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}()
+
+	// Allow the job to write its output.
+	time.Sleep(200 * time.Millisecond)
+
+	start := time.Now()
+	stream, err := suite.client.Output(ctx, &pb.OutputRequest{JobId: startResp.JobId, Follow: proto.Bool(false)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var b []byte
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b = append(b, resp.Output...)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("snapshot did not return promptly; elapsed: %v", elapsed)
+	}
+	if string(b) != "hello world\n" {
+		t.Fatalf("unexpected output; actual: %q, expected: %q", b, "hello world\n")
+	}
+
+	resp, err := suite.client.Status(ctx, &pb.StatusRequest{JobId: startResp.JobId})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Status.Status != pb.Status_STATUS_RUNNING {
+		t.Fatalf("unexpected status; actual: %v, expected: %v", resp.Status.Status, pb.Status_STATUS_RUNNING)
+	}
+}
+
+func TestOutputTruncated(t *testing.T) {
+	const notice = "\n[jobworker: output truncated]\n"
+
+	type expected struct {
+		output    string
+		truncated bool
+	}
+	tests := map[string]struct {
+		cmd *pb.Command
+		exp expected
+	}{
+		"truncated": {
+			cmd: &pb.Command{Name: "bash", Args: []string{"-c", "yes hello | head -c 3000"}},
+			exp: expected{output: strings.Repeat("hello\n", 170) + "hell" + notice, truncated: true},
+		},
+		"within limit": {
+			cmd: &pb.Command{Name: "bash", Args: []string{"-c", "echo hello world"}},
+			exp: expected{output: "hello world\n"},
+		},
+		"background process holds output": {
+			cmd: &pb.Command{Name: "bash", Args: []string{"-c", "sleep 30 & echo hello world"}},
+			exp: expected{output: "hello world\n"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			suite := setup(t)
+			defer suite.close(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			startResp, err := suite.client.Start(ctx, &pb.StartRequest{
+				Command: test.cmd,
+				Limits:  &pb.Limits{MaxOutputBytes: 1024},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			waitResp, err := suite.client.Wait(ctx, &pb.WaitRequest{JobId: startResp.JobId})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if waitResp.Status.OutputTruncated != test.exp.truncated {
+				t.Fatalf("unexpected output truncated; actual: %v, expected: %v", waitResp.Status.OutputTruncated, test.exp.truncated)
+			}
+
+			stream, err := suite.client.Output(ctx, &pb.OutputRequest{JobId: startResp.JobId})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var b []byte
+			for {
+				resp, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				b = append(b, resp.Output...)
+			}
+
+			if string(b) != test.exp.output {
+				t.Fatalf("unexpected output; actual: %q, expected: %q", b, test.exp.output)
+			}
+			truncated := strings.Join(stream.Trailer().Get("output-truncated"), "") == "true"
+			if truncated != test.exp.truncated {
+				t.Fatalf("unexpected output-truncated trailer; actual: %v, expected: %v", truncated, test.exp.truncated)
+			}
+		})
+	}
+}
+
+func TestHealth(t *testing.T) {
+	tests := map[string]struct {
+		service string
+	}{
+		"server":    {service: ""},
+		"jobworker": {service: "jobworker.v1.JobWorkerService"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			suite := setup(t)
+			defer suite.close(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			resp, err := healthpb.NewHealthClient(suite.conn).Check(ctx, &healthpb.HealthCheckRequest{Service: test.service})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Status != healthpb.HealthCheckResponse_SERVING {
+				t.Fatalf("unexpected status; actual: %v, expected: %v", resp.Status, healthpb.HealthCheckResponse_SERVING)
+			}
+		})
+	}
+}
+
 func setup(t *testing.T) *suite {
 	clientCert := "../../certs/alpha_user.crt"
 	clientKey := "../../certs/alpha_user.key"
 	caCert := "../../certs/ca.crt"
 
-	config, err := encrypt.NewClientTLSConfig(clientCert, clientKey, caCert)
+	config, err := encrypt.NewClientTLSConfig(
+		clientCert,
+		clientKey,
+		caCert,
+		encrypt.WithServerName(*serverName),
+	)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -297,3 +1151,13 @@ func (s suite) close(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+// containsStatus checks if statuses contains target.
+func containsStatus(statuses []pb.Status, target pb.Status) bool {
+	for _, s := range statuses {
+		if s == target {
+			return true
+		}
+	}
+	return false
+}